@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/matthewmolinar/tldr/pkg/breaker"
+	"github.com/matthewmolinar/tldr/pkg/extract"
+	"github.com/matthewmolinar/tldr/pkg/llm"
+	"github.com/matthewmolinar/tldr/pkg/metrics"
+	"github.com/matthewmolinar/tldr/pkg/middleware"
+)
+
+// Circuit breaker metrics. Providers get a state series each; origins are
+// only counted, since there can be any number of them.
+var (
+	breakerState       = metrics.Default.Gauge("circuit_breaker_state", "Provider circuit breaker state: 0 closed, 1 half-open, 2 open")
+	breakerTransitions = metrics.Default.Counter("circuit_breaker_transitions_total", "Circuit breaker state changes by upstream kind and new state")
+	openOrigins        = metrics.Default.Gauge("circuit_breaker_open_origins", "Origin hosts whose circuit breaker is open")
+)
+
+// providerBreakers guards each LLM provider by name
+var providerBreakers = breaker.NewSet(breaker.Config{
+	FailureThreshold: breaker.DefaultConfig.FailureThreshold,
+	OpenTimeout:      breaker.DefaultConfig.OpenTimeout,
+	IsFailure:        providerFailure,
+	OnStateChange: func(name string, from, to breaker.State) {
+		log.Printf("Provider %s circuit breaker %s -> %s", name, from, to)
+		breakerState.Set(float64(to), metrics.Labels{"provider": name})
+		breakerTransitions.Inc(metrics.Labels{"kind": "provider", "state": to.String()})
+	},
+})
+
+// providerFailure counts outages, rate limits and timeouts against a provider,
+// but not bad output or callers hanging up
+func providerFailure(err error) bool {
+	return !errors.Is(err, context.Canceled) && llm.IsRetryable(context.Background(), err)
+}
+
+// watchOriginBreakers reports the fetcher's per-host breakers in metrics.
+// The open-origin count follows the transitions rather than rescanning every
+// breaker on each one.
+func watchOriginBreakers() {
+	extract.OriginBreakers.OnStateChange(func(host string, from, to breaker.State) {
+		log.Printf("Origin %s circuit breaker %s -> %s", host, from, to)
+		breakerTransitions.Inc(metrics.Labels{"kind": "origin", "state": to.String()})
+		switch {
+		case to == breaker.Open:
+			openOrigins.Add(1, nil)
+		case from == breaker.Open:
+			openOrigins.Add(-1, nil)
+		}
+	})
+}
+
+// guardedSummarizer fails fast while its provider's breaker is open
+type guardedSummarizer struct {
+	llm.Summarizer
+	breaker *breaker.Breaker
+}
+
+func (g *guardedSummarizer) Summarize(ctx context.Context, req llm.Request) (*llm.Summary, error) {
+	if err := g.breaker.Allow(); err != nil {
+		return nil, err
+	}
+	summary, err := g.Summarizer.Summarize(ctx, req)
+	g.breaker.Record(err)
+	return summary, err
+}
+
+// guardProvider wraps a provider's summarizer in its circuit breaker
+func guardProvider(name string, s llm.Summarizer) llm.Summarizer {
+	return &guardedSummarizer{Summarizer: s, breaker: providerBreakers.Get(name)}
+}
+
+// serviceUnavailable converts an open-breaker error into a 503 that tells the
+// client when the upstream will be tried again
+func serviceUnavailable(c *fiber.Ctx, err error, message string) error {
+	var open *breaker.OpenError
+	if errors.As(err, &open) {
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(open.RetryIn.Seconds()))))
+	}
+	return middleware.NewAPIError(fiber.StatusServiceUnavailable, "service_unavailable", message)
+}
+
+// HealthResp is the /healthz?details body
+type HealthResp struct {
+	Status   string        `json:"status"` // "ok", "degraded" while a provider breaker is open, or "draining" during shutdown
+	Breakers BreakerHealth `json:"breakers"`
+}
+
+// BreakerHealth lists every provider's breaker and the origins that aren't closed
+type BreakerHealth struct {
+	Providers map[string]breaker.State `json:"providers"`
+	Origins   map[string]breaker.State `json:"origins"`
+}
+
+// handleHealth answers 200 for health checks, or 503 once shutdown has begun,
+// adding breaker states when called as /healthz?details
+func handleHealth(c *fiber.Ctx) error {
+	status := fiber.StatusOK
+	if draining.Load() {
+		status = fiber.StatusServiceUnavailable
+	}
+	if !c.Context().QueryArgs().Has("details") {
+		return c.SendStatus(status)
+	}
+
+	resp := HealthResp{
+		Status: "ok",
+		Breakers: BreakerHealth{
+			Providers: map[string]breaker.State{},
+			Origins:   extract.OriginBreakers.States(),
+		},
+	}
+	names := []string{defaultProvider}
+	for name := range providers {
+		names = append(names, name)
+	}
+	for _, name := range names {
+		state := providerBreakers.Get(name).State()
+		resp.Breakers.Providers[name] = state
+		if state == breaker.Open {
+			resp.Status = "degraded"
+		}
+	}
+	if draining.Load() {
+		resp.Status = "draining"
+	}
+	return c.Status(status).JSON(resp)
+}
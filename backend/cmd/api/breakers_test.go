@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/matthewmolinar/tldr/pkg/breaker"
+	"github.com/matthewmolinar/tldr/pkg/extract"
+	"github.com/matthewmolinar/tldr/pkg/llm"
+	"github.com/sashabaranov/go-openai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// withProviderBreakers installs a fresh provider breaker set for the test
+func withProviderBreakers(t *testing.T, threshold int) {
+	saved := providerBreakers
+	providerBreakers = breaker.NewSet(breaker.Config{FailureThreshold: threshold, OpenTimeout: time.Minute, IsFailure: providerFailure})
+	t.Cleanup(func() { providerBreakers = saved })
+}
+
+// failingLLMClient always fails with err and counts its calls
+type failingLLMClient struct {
+	err   error
+	calls int
+}
+
+func (f *failingLLMClient) Summarize(ctx context.Context, req llm.Request) (*llm.Summary, error) {
+	f.calls++
+	return nil, f.err
+}
+
+func TestGuardedSummarizer(t *testing.T) {
+	withProviderBreakers(t, 2)
+
+	t.Run("opens after consecutive provider failures", func(t *testing.T) {
+		client := &failingLLMClient{err: &openai.APIError{HTTPStatusCode: 503}}
+		guarded := guardProvider("down", client)
+
+		for i := 0; i < 2; i++ {
+			_, err := guarded.Summarize(context.Background(), llm.Request{})
+			assert.NotErrorIs(t, err, breaker.ErrOpen)
+		}
+		_, err := guarded.Summarize(context.Background(), llm.Request{})
+		assert.ErrorIs(t, err, breaker.ErrOpen)
+		assert.Equal(t, 2, client.calls)
+	})
+
+	t.Run("ignores bad output and client errors", func(t *testing.T) {
+		client := &failingLLMClient{err: llm.ErrInvalidSummary}
+		guarded := guardProvider("flaky-output", client)
+		for i := 0; i < 3; i++ {
+			guarded.Summarize(context.Background(), llm.Request{})
+		}
+		client.err = context.Canceled
+		guarded.Summarize(context.Background(), llm.Request{})
+		assert.Equal(t, breaker.Closed, providerBreakers.Get("flaky-output").State())
+	})
+}
+
+func TestWatchOriginBreakers(t *testing.T) {
+	watchOriginBreakers()
+	t.Cleanup(func() { extract.OriginBreakers.OnStateChange(nil) })
+	before := openOrigins.Value(nil)
+
+	b := extract.OriginBreakers.Get("down.example")
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < breaker.DefaultConfig.FailureThreshold; i++ {
+			require.NoError(t, b.Allow())
+			b.Record(errors.New("connection refused"))
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("opening an origin breaker deadlocked")
+	}
+
+	assert.Equal(t, breaker.Open, b.State())
+	assert.Equal(t, before+1, openOrigins.Value(nil))
+	assert.Equal(t, []string{"down.example"}, extract.OriginBreakers.Open())
+}
+
+func TestHealthDetails(t *testing.T) {
+	withProviderBreakers(t, 1)
+	providerBreakers.Get(defaultProvider).Record(&openai.APIError{HTTPStatusCode: 500})
+
+	app := fiber.New()
+	app.Get("/healthz", handleHealth)
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/healthz?details", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	var health struct {
+		Status   string `json:"status"`
+		Breakers struct {
+			Providers map[string]string `json:"providers"`
+		} `json:"breakers"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&health))
+	assert.Equal(t, "degraded", health.Status)
+	assert.Equal(t, "open", health.Breakers.Providers[defaultProvider])
+}
+
+func TestServiceUnavailable(t *testing.T) {
+	app := setupTestApp(&mockLLMClient{})
+	app.Get("/down", func(c *fiber.Ctx) error {
+		return serviceUnavailable(c, &breaker.OpenError{Name: "x", RetryIn: 1500 * time.Millisecond}, "down")
+	})
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/down", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusServiceUnavailable, resp.StatusCode)
+	assert.Equal(t, "2", resp.Header.Get(fiber.HeaderRetryAfter))
+
+	var body map[string]string
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	assert.Equal(t, "service_unavailable", body["code"])
+}
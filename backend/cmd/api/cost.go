@@ -0,0 +1,217 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/matthewmolinar/tldr/pkg/breaker"
+	"github.com/matthewmolinar/tldr/pkg/llm"
+	"github.com/matthewmolinar/tldr/pkg/metrics"
+	"github.com/matthewmolinar/tldr/pkg/middleware"
+	"github.com/sashabaranov/go-openai"
+)
+
+// LLM usage metrics, labeled by provider and model
+var (
+	llmCalls  = metrics.Default.Counter("llm_calls_total", "LLM calls that returned a summary")
+	llmTokens = metrics.Default.Counter("llm_tokens_total", "Tokens consumed by LLM calls, by kind (prompt or completion)")
+	llmCost   = metrics.Default.Counter("llm_cost_usd_total", "LLM spend in USD at list prices")
+	llmSpend  = metrics.Default.Gauge("llm_daily_spend_usd", "LLM spend so far in the current UTC day")
+
+	llmErrors   = metrics.Default.Counter("llm_errors_total", "Failed LLM calls by provider and error type")
+	llmInFlight = metrics.Default.Gauge("llm_requests_in_flight", "LLM calls awaiting an answer, by provider")
+)
+
+// dailySpend tracks LLM spend against the daily spend cap
+var dailySpend = newSpendTracker(0)
+
+// spendTracker accumulates spend per UTC day and reports when the cap is hit
+type spendTracker struct {
+	mu    sync.Mutex
+	cap   float64 // USD per UTC day; zero disables the cap
+	day   string
+	spent float64
+	now   func() time.Time
+}
+
+func newSpendTracker(cap float64) *spendTracker {
+	return &spendTracker{cap: cap, now: time.Now}
+}
+
+// rollover starts a new day's tally when the UTC date changes; s.mu must be held
+func (s *spendTracker) rollover() {
+	if day := s.now().UTC().Format(time.DateOnly); day != s.day {
+		s.day, s.spent = day, 0
+	}
+}
+
+// Exceeded reports whether today's spend has reached the cap
+func (s *spendTracker) Exceeded() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rollover()
+	return s.cap > 0 && s.spent >= s.cap
+}
+
+// Add records spend and returns today's total
+func (s *spendTracker) Add(usd float64) float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rollover()
+	s.spent += usd
+	return s.spent
+}
+
+// Status returns the current day, its spend so far and the cap
+func (s *spendTracker) Status() (day string, spent, cap float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rollover()
+	return s.day, s.spent, s.cap
+}
+
+// resetIn is the time left until the next UTC midnight, when the cap resets
+func (s *spendTracker) resetIn() time.Duration {
+	now := s.now().UTC()
+	return now.Truncate(24 * time.Hour).Add(24 * time.Hour).Sub(now)
+}
+
+// meteredSummarizer records the usage of every call it makes, so requests that
+// call the LLM more than once, like strict regenerations, are billed in full.
+// Calls that failed after the provider billed them count too.
+type meteredSummarizer struct {
+	llm.Summarizer
+	provider string
+	usage    llm.Usage
+}
+
+func (m *meteredSummarizer) Summarize(ctx context.Context, req llm.Request) (*llm.Summary, error) {
+	providerLabel := metrics.Labels{"provider": m.provider}
+	llmInFlight.Add(1, providerLabel)
+	summary, err := m.Summarizer.Summarize(ctx, req)
+	llmInFlight.Add(-1, providerLabel)
+	if err != nil {
+		llmErrors.Inc(metrics.Labels{"provider": m.provider, "type": llmErrorType(err)})
+		var billed *llm.BilledError
+		if errors.As(err, &billed) {
+			m.record(billed.Model, billed.Usage)
+		}
+		return nil, err
+	}
+	m.record(summary.Model, summary.Usage)
+	return summary, nil
+}
+
+// record adds a billed call's usage to the request total, the usage metrics
+// and today's spend
+func (m *meteredSummarizer) record(model string, u llm.Usage) {
+	m.usage = m.usage.Add(u)
+	labels := metrics.Labels{"provider": m.provider, "model": model}
+	llmCalls.Inc(labels)
+	llmTokens.Add(float64(u.PromptTokens), metrics.Labels{"provider": m.provider, "model": model, "kind": "prompt"})
+	llmTokens.Add(float64(u.CompletionTokens), metrics.Labels{"provider": m.provider, "model": model, "kind": "completion"})
+	llmCost.Add(u.CostUSD, labels)
+	if total := dailySpend.Add(u.CostUSD); total > 0 {
+		llmSpend.Set(total, nil)
+	}
+}
+
+// llmErrorType classifies a failed LLM call for the error metrics
+func llmErrorType(err error) string {
+	var apiErr *openai.APIError
+	var reqErr *openai.RequestError
+	status := 0
+	switch {
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, breaker.ErrOpen):
+		return "circuit_open"
+	case errors.Is(err, llm.ErrContentFiltered):
+		return "content_filtered"
+	case errors.Is(err, llm.ErrInvalidSummary):
+		return "invalid_summary"
+	case errors.Is(err, llm.ErrInvalidParams):
+		return "invalid_params"
+	case errors.As(err, &apiErr):
+		status = apiErr.HTTPStatusCode
+	case errors.As(err, &reqErr):
+		status = reqErr.HTTPStatusCode
+	}
+
+	switch {
+	case status == http.StatusTooManyRequests:
+		return "rate_limited"
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return "auth"
+	case status >= http.StatusInternalServerError:
+		return "server_error"
+	case status >= http.StatusBadRequest:
+		return "bad_request"
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return "network"
+	}
+	return "other"
+}
+
+// setUsageHeaders reports a request's token usage and cost
+func setUsageHeaders(c *fiber.Ctx, u llm.Usage) {
+	c.Set("X-LLM-Prompt-Tokens", strconv.Itoa(u.PromptTokens))
+	c.Set("X-LLM-Completion-Tokens", strconv.Itoa(u.CompletionTokens))
+	c.Set("X-LLM-Cost-USD", strconv.FormatFloat(u.CostUSD, 'f', 6, 64))
+}
+
+// spendCapError rejects a request made after today's spend reached the cap
+func spendCapError(c *fiber.Ctx) error {
+	log.Printf("Rejecting request: daily LLM spend cap reached")
+	c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(dailySpend.resetIn().Seconds())+1))
+	return middleware.NewAPIError(fiber.StatusTooManyRequests, "spend_cap_exceeded", "daily LLM spend cap reached, try again after midnight UTC")
+}
+
+// UsageResp reports today's spend and the usage metrics
+type UsageResp struct {
+	Date        string           `json:"date"`
+	SpendUSD    float64          `json:"spend_usd"`
+	DailyCapUSD float64          `json:"daily_cap_usd,omitempty"`
+	Metrics     []metrics.Family `json:"metrics"`
+}
+
+// handleUsage returns today's LLM spend alongside the aggregated usage metrics
+func handleUsage(c *fiber.Ctx) error {
+	day, spent, capUSD := dailySpend.Status()
+	return c.JSON(UsageResp{
+		Date:        day,
+		SpendUSD:    spent,
+		DailyCapUSD: capUSD,
+		Metrics:     metrics.Default.Snapshot(),
+	})
+}
+
+// logMetrics writes the final usage metrics to the log at shutdown, since
+// they are kept only in memory
+func logMetrics() error {
+	day, spent, _ := dailySpend.Status()
+	snapshot, err := json.Marshal(metrics.Default.Snapshot())
+	if err != nil {
+		return err
+	}
+	log.Printf("Spend on %s: $%.4f; final metrics: %s", day, spent, snapshot)
+	return nil
+}
+
+// handleMetrics serves every metric in the Prometheus text format
+func handleMetrics(c *fiber.Ctx) error {
+	c.Set(fiber.HeaderContentType, metrics.PrometheusContentType)
+	return metrics.Default.WritePrometheus(c)
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/matthewmolinar/tldr/pkg/breaker"
+	"github.com/matthewmolinar/tldr/pkg/llm"
+	"github.com/matthewmolinar/tldr/pkg/metrics"
+	"github.com/sashabaranov/go-openai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// withSpendTracker installs a spend tracker with a fixed clock for the test
+func withSpendTracker(t *testing.T, capUSD float64, now *time.Time) *spendTracker {
+	tracker := newSpendTracker(capUSD)
+	tracker.now = func() time.Time { return *now }
+	dailySpend = tracker
+	t.Cleanup(func() { dailySpend = newSpendTracker(0) })
+	return tracker
+}
+
+// pricedLLMClient returns a summary with fixed usage
+type pricedLLMClient struct{ usage llm.Usage }
+
+func (p *pricedLLMClient) Summarize(ctx context.Context, req llm.Request) (*llm.Summary, error) {
+	return &llm.Summary{Headline: "H", Model: "gpt-4o-mini", Usage: p.usage}, nil
+}
+
+func TestSpendTracker(t *testing.T) {
+	now := time.Date(2026, 10, 19, 23, 0, 0, 0, time.UTC)
+	tracker := withSpendTracker(t, 1.0, &now)
+
+	assert.False(t, tracker.Exceeded())
+	tracker.Add(0.6)
+	assert.False(t, tracker.Exceeded())
+	assert.InDelta(t, 1.1, tracker.Add(0.5), 1e-9)
+	assert.True(t, tracker.Exceeded())
+	assert.Equal(t, time.Hour, tracker.resetIn())
+
+	// A new UTC day starts from zero
+	now = now.Add(2 * time.Hour)
+	assert.False(t, tracker.Exceeded())
+	day, spent, _ := tracker.Status()
+	assert.Equal(t, "2026-10-20", day)
+	assert.Zero(t, spent)
+
+	uncapped := newSpendTracker(0)
+	uncapped.Add(1000)
+	assert.False(t, uncapped.Exceeded())
+}
+
+func TestMeteredSummarizer(t *testing.T) {
+	now := time.Now()
+	tracker := withSpendTracker(t, 0, &now)
+
+	usage := llm.Usage{PromptTokens: 1000, CompletionTokens: 50, CostUSD: 0.00018}
+	m := &meteredSummarizer{Summarizer: &pricedLLMClient{usage: usage}, provider: "test-metered"}
+	_, err := m.Summarize(context.Background(), llm.Request{})
+	require.NoError(t, err)
+	_, err = m.Summarize(context.Background(), llm.Request{Strict: true})
+	require.NoError(t, err)
+
+	assert.Equal(t, 2000, m.usage.PromptTokens)
+	assert.InDelta(t, 0.00036, m.usage.CostUSD, 1e-12)
+	_, spent, _ := tracker.Status()
+	assert.InDelta(t, 0.00036, spent, 1e-12)
+
+	labels := metrics.Labels{"provider": "test-metered", "model": "gpt-4o-mini"}
+	assert.Equal(t, 2.0, llmCalls.Value(labels))
+	assert.Equal(t, 100.0, llmTokens.Value(metrics.Labels{"provider": "test-metered", "model": "gpt-4o-mini", "kind": "completion"}))
+	assert.InDelta(t, 0.00036, llmCost.Value(labels), 1e-12)
+}
+
+func TestMeteredSummarizer_Errors(t *testing.T) {
+	m := &meteredSummarizer{Summarizer: &failingLLMClient{err: &openai.APIError{HTTPStatusCode: 429}}, provider: "test-errors"}
+	_, err := m.Summarize(context.Background(), llm.Request{})
+	require.Error(t, err)
+
+	assert.Equal(t, 1.0, llmErrors.Value(metrics.Labels{"provider": "test-errors", "type": "rate_limited"}))
+	assert.Zero(t, llmInFlight.Value(metrics.Labels{"provider": "test-errors"}))
+}
+
+func TestMeteredSummarizer_BilledErrors(t *testing.T) {
+	now := time.Now()
+	tracker := withSpendTracker(t, 0, &now)
+
+	usage := llm.Usage{PromptTokens: 1000, CompletionTokens: 50, CostUSD: 0.00018}
+	billed := &llm.BilledError{Err: fmt.Errorf("%w: link not in article", llm.ErrInvalidSummary), Model: "gpt-4o-mini", Usage: usage}
+	m := &meteredSummarizer{Summarizer: &failingLLMClient{err: billed}, provider: "test-billed"}
+	for range 3 {
+		_, err := m.Summarize(context.Background(), llm.Request{})
+		require.ErrorIs(t, err, llm.ErrInvalidSummary)
+	}
+
+	// Retrying a failing article still counts toward the cap
+	assert.Equal(t, 3000, m.usage.PromptTokens)
+	_, spent, _ := tracker.Status()
+	assert.InDelta(t, 0.00054, spent, 1e-12)
+	labels := metrics.Labels{"provider": "test-billed", "model": "gpt-4o-mini"}
+	assert.Equal(t, 3.0, llmCalls.Value(labels))
+	assert.InDelta(t, 0.00054, llmCost.Value(labels), 1e-12)
+	assert.Equal(t, 3.0, llmErrors.Value(metrics.Labels{"provider": "test-billed", "type": "invalid_summary"}))
+}
+
+func TestLLMErrorType(t *testing.T) {
+	tests := map[string]error{
+		"canceled":         context.Canceled,
+		"timeout":          fmt.Errorf("summarize: %w", context.DeadlineExceeded),
+		"circuit_open":     breaker.ErrOpen,
+		"content_filtered": llm.ErrContentFiltered,
+		"invalid_summary":  llm.ErrInvalidSummary,
+		"rate_limited":     &openai.APIError{HTTPStatusCode: 429},
+		"auth":             &openai.APIError{HTTPStatusCode: 401},
+		"server_error":     &openai.RequestError{HTTPStatusCode: 502},
+		"bad_request":      &openai.APIError{HTTPStatusCode: 404},
+		"network":          &net.OpError{Op: "dial", Err: errors.New("connection refused")},
+		"other":            errors.New("boom"),
+	}
+	for want, err := range tests {
+		assert.Equal(t, want, llmErrorType(err), err.Error())
+	}
+}
+
+func TestMetricsEndpoint(t *testing.T) {
+	llmCalls.Inc(metrics.Labels{"provider": "test-scrape", "model": "gpt-4o-mini"})
+
+	app := fiber.New()
+	app.Get("/metrics", handleMetrics)
+	resp, err := app.Test(httptest.NewRequest("GET", "/metrics", nil))
+	require.NoError(t, err)
+	assert.Equal(t, metrics.PrometheusContentType, resp.Header.Get("Content-Type"))
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), "# TYPE llm_calls_total counter\n")
+	assert.Contains(t, string(body), `llm_calls_total{model="gpt-4o-mini",provider="test-scrape"} 1`)
+	assert.Contains(t, string(body), "# TYPE summarize_stage_duration_seconds histogram\n")
+}
+
+func TestSpendCap(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	tracker := withSpendTracker(t, 0.01, &now)
+	tracker.Add(0.02)
+
+	app := setupTestApp(&mockLLMClient{})
+	app.Get("/api/usage", handleUsage)
+
+	t.Run("rejects summaries once the cap is reached", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/api/summarize", strings.NewReader(`{"url":"https://example.com"}`))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		assert.Equal(t, fiber.StatusTooManyRequests, resp.StatusCode)
+		assert.Equal(t, "43201", resp.Header.Get(fiber.HeaderRetryAfter))
+
+		body, _ := io.ReadAll(resp.Body)
+		var result map[string]string
+		require.NoError(t, json.Unmarshal(body, &result))
+		assert.Equal(t, "spend_cap_exceeded", result["code"])
+	})
+
+	t.Run("rejects even if the day rolled over since the check", func(t *testing.T) {
+		app.Get("/capped", spendCapError)
+		t.Cleanup(func() { now = time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC) })
+		now = time.Date(2026, 10, 20, 0, 0, 1, 0, time.UTC)
+
+		resp, err := app.Test(httptest.NewRequest("GET", "/capped", nil))
+		require.NoError(t, err)
+		assert.Equal(t, fiber.StatusTooManyRequests, resp.StatusCode, "a caller that saw the cap gets the error, not an empty 200")
+	})
+
+	t.Run("reports spend against the cap", func(t *testing.T) {
+		resp, err := app.Test(httptest.NewRequest("GET", "/api/usage", nil))
+		require.NoError(t, err)
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+		var usage UsageResp
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&usage))
+		assert.Equal(t, "2026-10-19", usage.Date)
+		assert.InDelta(t, 0.02, usage.SpendUSD, 1e-9)
+		assert.Equal(t, 0.01, usage.DailyCapUSD)
+	})
+}
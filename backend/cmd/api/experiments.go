@@ -0,0 +1,198 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/matthewmolinar/tldr/pkg/config"
+	"github.com/matthewmolinar/tldr/pkg/experiment"
+	"github.com/matthewmolinar/tldr/pkg/llm"
+	"github.com/matthewmolinar/tldr/pkg/middleware"
+)
+
+// Active experiment and its outcome log; both nil when no experiment is configured
+var (
+	activeExperiment *experiment.Experiment
+	experimentLog    *experiment.Recorder
+)
+
+// defaultProvider names the global client in providers, metrics and health;
+// the llm.provider setting selects it
+var defaultProvider = "openai"
+
+// providers maps the provider names experiment variants refer to onto summarizers
+var providers = map[string]llm.Summarizer{}
+
+// ExperimentTag tells the client which variant served its summary
+type ExperimentTag struct {
+	Name    string `json:"name"`
+	Variant string `json:"variant"`
+}
+
+// FeedbackReq represents a user rating of an earlier summary
+type FeedbackReq struct {
+	RequestID string `json:"request_id"`
+	Rating    int    `json:"rating"` // +1 helpful, -1 not helpful
+	Comment   string `json:"comment,omitempty"`
+}
+
+const maxFeedbackComment = 1000
+
+// setupExperiment loads the experiment file, if one is configured, and opens
+// its outcome log. A variant's prompt version applies to every style, so each
+// style must have it in prompts.
+func setupExperiment(cfg config.Experiment, prompts *llm.PromptSet) error {
+	path := cfg.File
+	if path == "" {
+		return nil
+	}
+
+	exp, err := experiment.Load(path)
+	if err != nil {
+		return err
+	}
+	for _, v := range exp.Variants {
+		if _, ok := providers[v.Provider]; v.Provider != "" && !ok {
+			return fmt.Errorf("variant %q uses unknown provider %q", v.Name, v.Provider)
+		}
+		if v.PromptVersion == "" {
+			continue
+		}
+		for _, style := range llm.StyleNames() {
+			if _, err := prompts.Get(style, v.PromptVersion); err != nil {
+				return fmt.Errorf("variant %q uses prompt version %q: %w", v.Name, v.PromptVersion, err)
+			}
+		}
+	}
+
+	logPath := cfg.Log
+	if logPath == "" {
+		logPath = config.Default().Experiment.Log
+	}
+	rec, err := experiment.NewRecorder(logPath)
+	if err != nil {
+		return err
+	}
+
+	activeExperiment, experimentLog = exp, rec
+	log.Printf("Running experiment %q with %d variants, logging to %s", exp.Name, len(exp.Variants), logPath)
+	return nil
+}
+
+// closeExperimentLog flushes the experiment log at shutdown
+func closeExperimentLog() error {
+	if experimentLog == nil {
+		return nil
+	}
+	return experimentLog.Close()
+}
+
+// assignVariant buckets the request into a variant of the active experiment
+func assignVariant(c *fiber.Ctx) (experiment.Variant, bool) {
+	if activeExperiment == nil {
+		return experiment.Variant{}, false
+	}
+	return activeExperiment.Assign(experimentUnit(c)), true
+}
+
+// experimentUnit identifies the caller: an explicit X-Client-ID, else an
+// anonymous hash of IP and User-Agent so repeat visitors stay in one variant
+func experimentUnit(c *fiber.Ctx) string {
+	if id := c.Get("X-Client-ID"); id != "" {
+		return id
+	}
+	return fmt.Sprintf("anon-%08x", experiment.Bucket(c.IP()+"|"+c.Get(fiber.HeaderUserAgent)))
+}
+
+// summarizerFor returns the summarizer for a variant's provider, defaulting
+// to the global client
+func summarizerFor(v experiment.Variant) llm.Summarizer {
+	if s, ok := providers[v.Provider]; ok {
+		return s
+	}
+	return llmClient
+}
+
+// providerName names a variant's provider, defaulting to the global client's
+func providerName(v experiment.Variant) string {
+	if _, ok := providers[v.Provider]; ok {
+		return v.Provider
+	}
+	return defaultProvider
+}
+
+// recordExperimentEvent appends to the experiment log, logging rather than
+// failing the request on write errors
+func recordExperimentEvent(e experiment.Event) {
+	if experimentLog == nil {
+		return
+	}
+	if err := experimentLog.Record(e); err != nil {
+		log.Printf("Failed to record experiment event: %v", err)
+	}
+}
+
+// requestID returns the ID assigned by the requestid middleware
+func requestID(c *fiber.Ctx) string {
+	id, _ := c.Locals("requestid").(string)
+	return id
+}
+
+// handleFeedback records a user rating for an earlier summary
+func handleFeedback(c *fiber.Ctx) error {
+	if experimentLog == nil {
+		return middleware.NewAPIError(fiber.StatusNotFound, "experiments_disabled", "no experiment is running")
+	}
+
+	var req FeedbackReq
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid request body")
+	}
+	if req.RequestID == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "request_id is required")
+	}
+	if req.Rating != 1 && req.Rating != -1 {
+		return fiber.NewError(fiber.StatusBadRequest, "rating must be 1 or -1")
+	}
+	if len(req.Comment) > maxFeedbackComment {
+		return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("comment must be at most %d bytes", maxFeedbackComment))
+	}
+
+	recordExperimentEvent(experiment.Event{
+		Type:      experiment.EventFeedback,
+		RequestID: req.RequestID,
+		Rating:    req.Rating,
+		Comment:   req.Comment,
+	})
+	return c.SendStatus(fiber.StatusAccepted)
+}
+
+// handleExperimentExport streams the raw experiment log as JSON lines
+func handleExperimentExport(c *fiber.Ctx) error {
+	if experimentLog == nil {
+		return middleware.NewAPIError(fiber.StatusNotFound, "experiments_disabled", "no experiment is running")
+	}
+	c.Set(fiber.HeaderContentType, "application/x-ndjson")
+	return c.SendFile(experimentLog.Path())
+}
+
+// handleExperimentReport aggregates the experiment log per variant
+func handleExperimentReport(c *fiber.Ctx) error {
+	if experimentLog == nil {
+		return middleware.NewAPIError(fiber.StatusNotFound, "experiments_disabled", "no experiment is running")
+	}
+
+	f, err := os.Open(experimentLog.Path())
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	stats, err := experiment.Report(f)
+	if err != nil {
+		return err
+	}
+	return c.JSON(stats)
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/matthewmolinar/tldr/pkg/config"
+	"github.com/matthewmolinar/tldr/pkg/experiment"
+	"github.com/matthewmolinar/tldr/pkg/llm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// withExperiment installs an experiment and a temporary outcome log for the
+// duration of a test
+func withExperiment(t *testing.T, exp *experiment.Experiment) *experiment.Recorder {
+	rec, err := experiment.NewRecorder(filepath.Join(t.TempDir(), "experiments.jsonl"))
+	require.NoError(t, err)
+
+	activeExperiment, experimentLog = exp, rec
+	t.Cleanup(func() {
+		rec.Close()
+		activeExperiment, experimentLog = nil, nil
+	})
+	return rec
+}
+
+func setupExperimentApp() *fiber.App {
+	app := setupTestApp(&mockLLMClient{})
+	api := app.Group("/api")
+	api.Post("/feedback", handleFeedback)
+	api.Get("/experiments/report", handleExperimentReport)
+	api.Get("/experiments/export", handleExperimentExport)
+	return app
+}
+
+func TestFeedbackHandler(t *testing.T) {
+	t.Run("returns 404 when no experiment is running", func(t *testing.T) {
+		app := setupExperimentApp()
+		req := httptest.NewRequest("POST", "/api/feedback", strings.NewReader(`{"request_id":"r1","rating":1}`))
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+	})
+
+	t.Run("records feedback against the serving variant", func(t *testing.T) {
+		rec := withExperiment(t, &experiment.Experiment{
+			Name:     "prompt-v2",
+			Variants: []experiment.Variant{{Name: "control", Weight: 1}},
+		})
+		require.NoError(t, rec.Record(experiment.Event{
+			Type: experiment.EventOutcome, RequestID: "r1", Experiment: "prompt-v2", Variant: "control", LatencyMS: 120,
+		}))
+		app := setupExperimentApp()
+
+		for _, body := range []string{`{"request_id":"r1","rating":1}`, `{"request_id":"r1","rating":-1,"comment":"too vague"}`} {
+			req := httptest.NewRequest("POST", "/api/feedback", strings.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			resp, err := app.Test(req)
+			require.NoError(t, err)
+			assert.Equal(t, fiber.StatusAccepted, resp.StatusCode)
+		}
+
+		resp, err := app.Test(httptest.NewRequest("GET", "/api/experiments/report", nil))
+		require.NoError(t, err)
+		require.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+		var stats []experiment.VariantStats
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&stats))
+		require.Len(t, stats, 1)
+		assert.Equal(t, "control", stats[0].Variant)
+		assert.Equal(t, 2, stats[0].Ratings)
+		assert.Equal(t, 0.0, stats[0].MeanRating)
+
+		resp, err = app.Test(httptest.NewRequest("GET", "/api/experiments/export", nil))
+		require.NoError(t, err)
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Equal(t, 3, strings.Count(string(body), "\n"))
+	})
+
+	t.Run("rejects invalid ratings", func(t *testing.T) {
+		withExperiment(t, &experiment.Experiment{
+			Name:     "prompt-v2",
+			Variants: []experiment.Variant{{Name: "control", Weight: 1}},
+		})
+		app := setupExperimentApp()
+
+		for _, body := range []string{`{"request_id":"r1","rating":5}`, `{"rating":1}`} {
+			req := httptest.NewRequest("POST", "/api/feedback", strings.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			resp, err := app.Test(req)
+			require.NoError(t, err)
+			assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode, body)
+		}
+	})
+}
+
+func TestSetupExperiment(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "headline.v3.tmpl"), []byte("Write a headline."), 0o644))
+	prompts, err := llm.LoadPrompts(dir)
+	require.NoError(t, err)
+
+	setup := func(t *testing.T, variant string) error {
+		file := filepath.Join(t.TempDir(), "experiment.json")
+		require.NoError(t, os.WriteFile(file, []byte(`{"name":"prompts","variants":[`+variant+`]}`), 0o644))
+		err := setupExperiment(config.Experiment{File: file, Log: filepath.Join(t.TempDir(), "log.jsonl")}, prompts)
+		t.Cleanup(func() {
+			closeExperimentLog()
+			activeExperiment, experimentLog = nil, nil
+		})
+		return err
+	}
+
+	t.Run("accepts versions every style has", func(t *testing.T) {
+		assert.NoError(t, setup(t, `{"name":"control","weight":1,"prompt_version":"v1"}`))
+		assert.NotNil(t, activeExperiment)
+	})
+
+	t.Run("rejects versions some styles lack", func(t *testing.T) {
+		err := setup(t, `{"name":"treatment","weight":1,"prompt_version":"v3"}`)
+		assert.ErrorContains(t, err, `variant "treatment" uses prompt version "v3"`)
+		assert.Nil(t, activeExperiment)
+	})
+}
+
+func TestExperimentUnit(t *testing.T) {
+	app := fiber.New()
+	app.Get("/unit", func(c *fiber.Ctx) error {
+		return c.SendString(experimentUnit(c))
+	})
+
+	get := func(clientID, userAgent string) string {
+		req := httptest.NewRequest("GET", "/unit", nil)
+		if clientID != "" {
+			req.Header.Set("X-Client-ID", clientID)
+		}
+		req.Header.Set("User-Agent", userAgent)
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return string(body)
+	}
+
+	assert.Equal(t, "client-42", get("client-42", "ua"))
+	assert.Equal(t, get("", "ua-1"), get("", "ua-1"), "anonymous units must be stable")
+	assert.NotEqual(t, get("", "ua-1"), get("", "ua-2"))
+}
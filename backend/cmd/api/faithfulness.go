@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	"github.com/matthewmolinar/tldr/pkg/lang"
+	"github.com/matthewmolinar/tldr/pkg/llm"
+	"github.com/matthewmolinar/tldr/pkg/nlp"
+	"github.com/matthewmolinar/tldr/pkg/verify"
+)
+
+// regenerateUnsupported enables one strict retry when a bullet fails
+// verification (VERIFY_REGENERATE=true)
+var regenerateUnsupported bool
+
+// verifySummary checks each bullet against the article text. When retries are
+// enabled and a bullet is unsupported, it asks for a strict regeneration and
+// keeps whichever attempt is better supported overall. Summaries it can't
+// check, like translations, are returned with no support scores.
+func verifySummary(ctx context.Context, summarizer llm.Summarizer, req llm.Request, summary *llm.Summary) (*llm.Summary, []verify.BulletSupport, bool) {
+	if !verifiable(summary, req.Text) {
+		return summary, nil, false
+	}
+	checker := verify.NewChecker(req.Text)
+	checks := checker.Check(summary.Bullets)
+	if !regenerateUnsupported || verify.AllSupported(checks) {
+		return summary, checks, false
+	}
+
+	log.Printf("Summary has unsupported bullets (mean support %.2f), regenerating in strict mode", verify.MeanScore(checks))
+	req.Strict = true
+	retry, err := summarizer.Summarize(ctx, req)
+	if err != nil {
+		log.Printf("Strict regeneration failed, keeping first summary: %v", err)
+		return summary, checks, false
+	}
+
+	retryChecks := checker.Check(retry.Bullets)
+	if verify.MeanScore(retryChecks) <= verify.MeanScore(checks) {
+		return summary, checks, false
+	}
+	return retry, retryChecks, true
+}
+
+// verifiable reports whether the word-overlap checks apply: the bullets must
+// be in the article's language, and one whose stopwords are known. A
+// translation shares no words with its source and would score unsupported.
+func verifiable(summary *llm.Summary, text string) bool {
+	language := summary.Language
+	if language == "" {
+		language = lang.Default
+	}
+	return language == lang.Detect(text) && nlp.HasStopwords(language)
+}
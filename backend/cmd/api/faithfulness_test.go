@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/matthewmolinar/tldr/pkg/llm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const marketArticle = `The central bank kept its benchmark interest rate unchanged at 4.25 percent on Thursday.
+Consumer prices rose 3.1 percent in the year to September, down from a peak of 6.8 percent last winter.`
+
+// scriptedLLMClient returns one summary for normal requests and another for strict ones
+type scriptedLLMClient struct {
+	normal, strict *llm.Summary
+	strictErr      error
+	strictCalls    int
+}
+
+func (s *scriptedLLMClient) Summarize(ctx context.Context, req llm.Request) (*llm.Summary, error) {
+	if req.Strict {
+		s.strictCalls++
+		return s.strict, s.strictErr
+	}
+	return s.normal, nil
+}
+
+func TestVerifySummary(t *testing.T) {
+	supported := &llm.Summary{Headline: "Rates on hold", Bullets: []string{"Rate unchanged at 4.25 percent", "Consumer prices rose 3.1 percent"}}
+	invented := &llm.Summary{Headline: "Rates on hold", Bullets: []string{"Rate unchanged at 4.25 percent", "Governor Smith predicts 9 percent growth"}}
+
+	withRegenerate := func(t *testing.T, enabled bool) {
+		regenerateUnsupported = enabled
+		t.Cleanup(func() { regenerateUnsupported = false })
+	}
+
+	t.Run("reports per-bullet support without retrying by default", func(t *testing.T) {
+		withRegenerate(t, false)
+		client := &scriptedLLMClient{normal: invented, strict: supported}
+
+		summary, checks, regenerated := verifySummary(context.Background(), client, llm.Request{Text: marketArticle}, invented)
+		assert.Same(t, invented, summary)
+		assert.False(t, regenerated)
+		assert.Zero(t, client.strictCalls)
+		require.Len(t, checks, 2)
+		assert.True(t, checks[0].Supported)
+		assert.False(t, checks[1].Supported)
+		assert.Contains(t, checks[1].Missing, "Smith")
+	})
+
+	t.Run("keeps a better-supported strict regeneration", func(t *testing.T) {
+		withRegenerate(t, true)
+		client := &scriptedLLMClient{strict: supported}
+
+		summary, checks, regenerated := verifySummary(context.Background(), client, llm.Request{Text: marketArticle}, invented)
+		assert.Same(t, supported, summary)
+		assert.True(t, regenerated)
+		assert.Equal(t, 1, client.strictCalls)
+		assert.True(t, checks[1].Supported)
+	})
+
+	t.Run("keeps the first summary when regeneration fails", func(t *testing.T) {
+		withRegenerate(t, true)
+		client := &scriptedLLMClient{strictErr: errors.New("upstream timeout")}
+
+		summary, _, regenerated := verifySummary(context.Background(), client, llm.Request{Text: marketArticle}, invented)
+		assert.Same(t, invented, summary)
+		assert.False(t, regenerated)
+	})
+
+	t.Run("does not retry fully supported summaries", func(t *testing.T) {
+		withRegenerate(t, true)
+		client := &scriptedLLMClient{}
+
+		_, _, regenerated := verifySummary(context.Background(), client, llm.Request{Text: marketArticle}, supported)
+		assert.False(t, regenerated)
+		assert.Zero(t, client.strictCalls)
+	})
+}
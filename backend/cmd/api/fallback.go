@@ -0,0 +1,36 @@
+package main
+
+import (
+	"github.com/matthewmolinar/tldr/pkg/llm"
+	"github.com/matthewmolinar/tldr/pkg/metrics"
+)
+
+// extractiveFallback answers with an extractive summary instead of an error
+// when the LLM fails or the spend cap is reached (EXTRACTIVE_FALLBACK=true)
+var extractiveFallback bool
+
+var extractiveSummaries = metrics.Default.Counter("extractive_summaries_total", "Summaries served by the extractive fallback, by reason (spend_cap or llm_error)")
+
+// extractiveWarning tells the reader why the summary is made of quotes
+const extractiveWarning = "the summary service is unavailable, so this summary quotes the article's key sentences"
+
+// withFallback puts the extractive summarizer behind s when the fallback is
+// enabled, or in its place when the spend cap has been reached
+func withFallback(s llm.Summarizer, overCap bool) llm.Summarizer {
+	switch {
+	case !extractiveFallback:
+		return s
+	case overCap:
+		return llm.Extractive{}
+	default:
+		return llm.Chain{s, llm.Extractive{}}
+	}
+}
+
+// fallbackReason labels an extractive summary for metrics
+func fallbackReason(overCap bool) string {
+	if overCap {
+		return "spend_cap"
+	}
+	return "llm_error"
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/matthewmolinar/tldr/pkg/breaker"
+	"github.com/matthewmolinar/tldr/pkg/llm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithFallback(t *testing.T) {
+	defer func() { extractiveFallback = false }()
+	req := llm.Request{Text: "The harbor board approved the dredging plan on Tuesday. The plan deepens the shipping channel to sixteen meters. Critics of the dredging plan cited the cost."}
+
+	t.Run("disabled", func(t *testing.T) {
+		extractiveFallback = false
+		client := &failingLLMClient{err: breaker.ErrOpen}
+		_, err := withFallback(client, false).Summarize(context.Background(), req)
+		assert.ErrorIs(t, err, breaker.ErrOpen)
+	})
+
+	t.Run("after an LLM failure", func(t *testing.T) {
+		extractiveFallback = true
+		client := &failingLLMClient{err: breaker.ErrOpen}
+		summary, err := withFallback(client, false).Summarize(context.Background(), req)
+		require.NoError(t, err)
+		assert.True(t, summary.Extractive)
+		assert.Equal(t, 1, client.calls)
+	})
+
+	t.Run("over the spend cap", func(t *testing.T) {
+		extractiveFallback = true
+		client := &failingLLMClient{}
+		summary, err := withFallback(client, true).Summarize(context.Background(), req)
+		require.NoError(t, err)
+		assert.True(t, summary.Extractive)
+		assert.Zero(t, client.calls, "the LLM is skipped once the cap is reached")
+	})
+}
@@ -1,53 +1,304 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log"
+	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/matthewmolinar/tldr/pkg/breaker"
+	"github.com/matthewmolinar/tldr/pkg/cite"
+	"github.com/matthewmolinar/tldr/pkg/config"
+	"github.com/matthewmolinar/tldr/pkg/experiment"
 	"github.com/matthewmolinar/tldr/pkg/extract"
-	"github.com/matthewmolinar/tldr/pkg/validate"
+	"github.com/matthewmolinar/tldr/pkg/lang"
+	"github.com/matthewmolinar/tldr/pkg/llm"
+	"github.com/matthewmolinar/tldr/pkg/metrics"
+	"github.com/matthewmolinar/tldr/pkg/middleware"
+	"github.com/matthewmolinar/tldr/pkg/redact"
+	"github.com/matthewmolinar/tldr/pkg/verify"
 )
 
+// blockedMessages are the user-facing messages for pages Extract refuses to summarize
+var blockedMessages = map[extract.BlockKind]string{
+	extract.BlockConsentWall: "page shows a cookie consent wall instead of the article",
+	extract.BlockPaywall:     "article is behind a paywall",
+	extract.BlockJSRequired:  "page requires JavaScript to render its content",
+	extract.BlockBotCheck:    "page is protected by a bot check",
+}
+
+// summarizeTimeout bounds the LLM calls of one request, retries included
+var summarizeTimeout = config.Default().LLM.Timeout
+
+// redactByDefault masks PII in every request; requests can't opt out
+var redactByDefault bool
+
 // SummarizeReq represents the request payload for the summarize endpoint
 type SummarizeReq struct {
-	URL string `json:"url"`
+	URL      string `json:"url"`
+	Language string `json:"language,omitempty"` // ISO 639-1 summary language; defaults to the article's
+	Style    string `json:"style,omitempty"`    // summary style preset; defaults to "headline"
+	Bullets  *int   `json:"bullets,omitempty"`  // bullet count within the style's range
+	Redact   *bool  `json:"redact,omitempty"`   // mask PII and secrets before summarizing; always on with REDACT_PII
+
+	// Model and sampling overrides; the model must be on the allow-list
+	Model       string   `json:"model,omitempty"`
+	Temperature *float32 `json:"temperature,omitempty"`
+	TopP        *float32 `json:"top_p,omitempty"`
+	MaxTokens   *int     `json:"max_tokens,omitempty"`
 }
 
 // SummarizeResp represents the response from the summarize endpoint
 type SummarizeResp struct {
-	Headline string   `json:"headline"`
-	Bullets  []string `json:"bullets"`
+	Headline       string   `json:"headline"`
+	Bullets        []string `json:"bullets"`
+	Language       string   `json:"language"`
+	SourceLanguage string   `json:"source_language,omitempty"`
+	Style          string   `json:"style"`
+	PromptVersion  string   `json:"prompt_version,omitempty"`
+	RequestID      string   `json:"request_id,omitempty"`
+
+	// Support scores each bullet against the article, in bullet order
+	Support     []verify.BulletSupport `json:"support,omitempty"`
+	Regenerated bool                   `json:"regenerated,omitempty"`
+
+	// Citations link each bullet to its supporting sentence, in bullet order
+	Citations []cite.Citation `json:"citations,omitempty"`
+
+	// Extractive marks a summary quoted from the article because no LLM was available
+	Extractive bool `json:"extractive,omitempty"`
+
+	Warnings []string `json:"warnings,omitempty"`
+
+	// Redactions counts masked PII and secrets; omitted when redaction is off
+	Redactions *int `json:"redactions,omitempty"`
+
+	Experiment *ExperimentTag `json:"experiment,omitempty"`
 }
 
 // handleSummarize handles article summarization requests
 func handleSummarize(c *fiber.Ctx) error {
+	start := time.Now()
+
 	// Parse and validate request
 	var req SummarizeReq
 	if err := c.BodyParser(&req); err != nil {
 		return fiber.NewError(fiber.StatusBadRequest, "invalid request body")
 	}
 
-	if err := validate.ValidateURL(req.URL, nil); err != nil {
+	req.Language = strings.ToLower(req.Language)
+	if req.Language != "" && !lang.Supported(req.Language) {
+		return middleware.NewAPIError(fiber.StatusBadRequest, "unsupported_language", "unsupported summary language: "+req.Language)
+	}
+
+	style, err := llm.LookupStyle(req.Style)
+	if err != nil {
+		return middleware.NewAPIError(fiber.StatusBadRequest, "invalid_style",
+			fmt.Sprintf("%v (available: %s)", err, strings.Join(llm.StyleNames(), ", ")))
+	}
+	wantBullets, err := style.BulletCount(req.Bullets)
+	if err != nil {
+		return middleware.NewAPIError(fiber.StatusBadRequest, "invalid_bullet_count", err.Error())
+	}
+
+	if req.Model != "" && !modelConfig.Allows(req.Model) {
+		return middleware.NewAPIError(fiber.StatusBadRequest, "model_not_allowed",
+			fmt.Sprintf("model %q is not allowed (available: %s)", req.Model, strings.Join(modelConfig.AllowedModels(), ", ")))
+	}
+	overrides := llm.Request{Temperature: req.Temperature, TopP: req.TopP, MaxTokens: req.MaxTokens}
+	if err := llm.CheckOverrides(overrides); err != nil {
+		return middleware.NewAPIError(fiber.StatusBadRequest, "invalid_model_params", err.Error())
+	}
+
+	// Past the spend cap, answer extractively if allowed, else refuse
+	overCap := dailySpend.Exceeded()
+	if overCap && !extractiveFallback {
+		return spendCapError(c)
+	}
+
+	// Time and trace the pipeline stages, reporting them in Server-Timing
+	stages := &stageTimer{}
+	defer func() {
+		stages.observe()
+		c.Set("Server-Timing", stages.serverTiming())
+	}()
+	reqCtx := withStageTimer(c.UserContext(), stages)
+	host := urlHost(req.URL)
+
+	ctx, span, end := stages.begin(reqCtx, "validate")
+	span.SetAttr("server.address", host)
+	err = urlValidator.ValidateURL(ctx, req.URL)
+	end(err)
+	if err != nil {
 		return fiber.NewError(fiber.StatusUnprocessableEntity, "invalid URL format")
 	}
 
-	// Extract article text
-	text, err := extract.Extract(req.URL)
+	// Extract article text, keeping more of it for a long-context model; the
+	// fetch stage times the downloads, extract the rest
+	ex := extractor
+	if n := modelConfig.ArticleBytes(req.Model, ex.MaxBytes()); n > ex.MaxBytes() {
+		ex = ex.WithMaxBytes(n)
+	}
+	ctx, span, end = stages.begin(reqCtx, "extract")
+	span.SetAttr("server.address", host)
+	article, err := ex.ExtractArticle(ctx, req.URL)
+	if err == nil {
+		span.SetAttr("article.bytes", len(article.Text))
+	}
+	end(err)
 	if err != nil {
+		var blocked *extract.BlockedError
+		if errors.As(err, &blocked) {
+			return middleware.NewAPIError(fiber.StatusUnprocessableEntity, string(blocked.Kind), blockedMessages[blocked.Kind])
+		}
+		if errors.Is(err, breaker.ErrOpen) {
+			return serviceUnavailable(c, err, "the article's site is failing, try again later")
+		}
 		return fiber.NewError(fiber.StatusUnprocessableEntity, "failed to extract article content")
 	}
+	text := article.Text
+	articleBytes.Observe(float64(len(text)), nil)
+
+	// Mask PII and secrets before anything leaves for the LLM provider
+	var redactions *int
+	if shouldRedact(req) {
+		res := redact.Text(text)
+		text = res.Text
+		total := res.Total()
+		redactions = &total
+		if total > 0 {
+			log.Printf("Redacted %d items (%s) from %s", total, strings.Join(res.Kinds(), ", "), req.URL)
+		}
+	}
+
+	// Summarize in the requested language, else the article's own, else English
+	sourceLanguage := lang.Detect(text)
+	target := req.Language
+	if target == "" {
+		target = sourceLanguage
+	}
+	if !lang.Supported(target) {
+		target = lang.Default
+	}
+
+	// Experiment variants may swap the provider, model and prompt version
+	variant, inExperiment := assignVariant(c)
+	outcome := experiment.Event{Type: experiment.EventOutcome, RequestID: requestID(c)}
+	if inExperiment {
+		outcome.Experiment, outcome.Variant = activeExperiment.Name, variant.Name
+	}
 
 	// Generate summary using LLM
-	headline, bullets, err := llmClient.Summarize(text)
+	provider := providerName(variant)
+	metered := &meteredSummarizer{Summarizer: guardProvider(provider, summarizerFor(variant)), provider: provider}
+	summarizer := withFallback(metered, overCap)
+	model := req.Model
+	if model == "" {
+		model = variant.Model
+	}
+	llmReq := llm.Request{
+		Text:          text,
+		Language:      target,
+		Style:         style.Name,
+		Bullets:       req.Bullets,
+		Model:         model,
+		PromptVersion: variant.PromptVersion,
+		Temperature:   req.Temperature,
+		TopP:          req.TopP,
+		MaxTokens:     req.MaxTokens,
+	}
+	llmCtx, cancel := context.WithTimeout(reqCtx, summarizeTimeout)
+	defer cancel()
+	ctx, span, end = stages.begin(llmCtx, "llm")
+	span.SetAttr("llm.provider", provider)
+	if model != "" {
+		span.SetAttr("gen_ai.request.model", model)
+	}
+	summary, err := summarizer.Summarize(ctx, llmReq)
+	if err == nil {
+		span.SetAttr("gen_ai.response.model", summary.Model)
+	}
+	span.SetAttr("gen_ai.usage.input_tokens", metered.usage.PromptTokens)
+	span.SetAttr("gen_ai.usage.output_tokens", metered.usage.CompletionTokens)
+	end(err)
 	if err != nil {
 		// Log the actual error
 		log.Printf("LLM error: %v", err)
+		if inExperiment {
+			outcome.LatencyMS = time.Since(start).Milliseconds()
+			outcome.Error = err.Error()
+			recordExperimentEvent(outcome)
+		}
+		if errors.Is(err, breaker.ErrOpen) {
+			return serviceUnavailable(c, err, "the summary provider is unavailable, try again later")
+		}
+		if errors.Is(err, context.DeadlineExceeded) {
+			return middleware.NewAPIError(fiber.StatusGatewayTimeout, "llm_timeout", "summary took too long to generate")
+		}
+		if errors.Is(err, llm.ErrInvalidSummary) {
+			return middleware.NewAPIError(fiber.StatusBadGateway, "invalid_summary", "model output did not look like a summary of the article")
+		}
+		if errors.Is(err, llm.ErrInvalidParams) {
+			return middleware.NewAPIError(fiber.StatusBadRequest, "invalid_model_params", err.Error())
+		}
+		if errors.Is(err, llm.ErrContentFiltered) {
+			return middleware.NewAPIError(fiber.StatusUnprocessableEntity, "content_filtered", "the model provider refused to summarize this article")
+		}
 		return fiber.NewError(fiber.StatusInternalServerError, "failed to generate summary: " + err.Error())
 	}
+	log.Printf("Summarized %s with model %s and prompt %s", req.URL, summary.Model, summary.PromptVersion)
+	if summary.Extractive {
+		extractiveSummaries.Inc(metrics.Labels{"reason": fallbackReason(overCap)})
+		summary.Warnings = append(summary.Warnings, extractiveWarning)
+	}
+
+	// Flag bullets with names, numbers or claims the article doesn't back up
+	ctx, span, end = stages.begin(llmCtx, "verify")
+	summary, support, regenerated := verifySummary(ctx, summarizer, llmReq, summary)
+	span.SetAttr("summary.regenerated", regenerated)
+	end(nil)
+	setUsageHeaders(c, metered.usage)
+
+	resp := SummarizeResp{
+		Headline:       summary.Headline,
+		Bullets:        summary.Bullets,
+		Language:       summary.Language,
+		SourceLanguage: sourceLanguage,
+		Style:          summary.Style,
+		PromptVersion:  summary.PromptVersion,
+		RequestID:      outcome.RequestID,
+		Support:        support,
+		Regenerated:    regenerated,
+		Citations:      cite.Bullets(article.Pages, support),
+		Extractive:     summary.Extractive,
+		Warnings:       summary.Warnings,
+		Redactions:     redactions,
+	}
+
+	if inExperiment {
+		width := lang.Width(summary.Headline)
+		for _, b := range summary.Bullets {
+			width += lang.Width(b)
+		}
+		outcome.LatencyMS = time.Since(start).Milliseconds()
+		outcome.Width = width
+		outcome.LengthCompliant = width <= style.MaxWidth
+		outcome.BulletCompliant = len(summary.Bullets) == wantBullets
+		recordExperimentEvent(outcome)
+
+		resp.Experiment = &ExperimentTag{Name: outcome.Experiment, Variant: outcome.Variant}
+		c.Set("X-Experiment-Variant", outcome.Variant)
+	}
 
 	// Return response
-	return c.Status(fiber.StatusCreated).JSON(SummarizeResp{
-		Headline: headline,
-		Bullets:  bullets,
-	})
+	return c.Status(fiber.StatusCreated).JSON(resp)
+}
+
+// shouldRedact reports whether to mask the article. A request can turn
+// redaction on, but not off when the deployment enforces it.
+func shouldRedact(req SummarizeReq) bool {
+	return redactByDefault || (req.Redact != nil && *req.Redact)
 }
@@ -1,22 +1,37 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"io"
+	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/matthewmolinar/tldr/pkg/config"
+	"github.com/matthewmolinar/tldr/pkg/extract"
 	"github.com/matthewmolinar/tldr/pkg/llm"
+	"github.com/matthewmolinar/tldr/pkg/middleware"
+	"github.com/matthewmolinar/tldr/pkg/validate"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // mockLLMClient is a test double that returns canned responses
 type mockLLMClient struct{}
 
-func (m *mockLLMClient) Summarize(text string) (string, []string, error) {
-	return "Test Headline", []string{"Point 1", "Point 2", "Point 3"}, nil
+func (m *mockLLMClient) Summarize(ctx context.Context, req llm.Request) (*llm.Summary, error) {
+	return &llm.Summary{
+		Headline: "Test Headline",
+		Bullets:  []string{"Point 1", "Point 2", "Point 3"},
+		Language: req.Language,
+		Style:    req.Style,
+
+		PromptVersion: "headline.v1@test",
+	}, nil
 }
 
 func setupTestApp(client llm.Summarizer) *fiber.App {
@@ -27,12 +42,30 @@ func setupTestApp(client llm.Summarizer) *fiber.App {
 	
 	// Override global client for testing
 	llmClient = client
-	
+
+	app.Use(middleware.ErrorMiddleware())
 	api := app.Group("/api")
 	api.Post("/summarize", handleSummarize)
 	return app
 }
 
+// withArticle serves page from a local HTTPS origin that the validator and
+// extractor trust for the duration of the test, and returns its URL
+func withArticle(t *testing.T, page string) string {
+	t.Helper()
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		io.WriteString(w, page)
+	}))
+	t.Cleanup(ts.Close)
+
+	oldExtractor, oldValidator := extractor, urlValidator
+	extractor = extract.New(config.Default().Fetch, ts.Client())
+	urlValidator = validate.New(config.Default().Fetch, ts.Client())
+	t.Cleanup(func() { extractor, urlValidator = oldExtractor, oldValidator })
+	return ts.URL + "/article"
+}
+
 func TestServerStartup(t *testing.T) {
 	app := setupTestApp(&mockLLMClient{})
 	assert.NotNil(t, app, "Server should initialize")
@@ -58,7 +91,7 @@ func TestSummarizeHandler(t *testing.T) {
 
 		body, err := io.ReadAll(resp.Body)
 		assert.NoError(t, err)
-		expected := `{"headline":"Test Headline","bullets":["Point 1","Point 2","Point 3"]}`
+		expected := `{"headline":"Test Headline","bullets":["Point 1","Point 2","Point 3"],"language":"en","source_language":"en","style":"headline","prompt_version":"headline.v1@test"}`
 		assert.JSONEq(t, expected, string(body))
 	})
 
@@ -72,6 +105,46 @@ func TestSummarizeHandler(t *testing.T) {
 		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
 	})
 
+	t.Run("returns 400 for unsupported language", func(t *testing.T) {
+		reqBody := `{"url":"https://example.com","language":"klingon"}`
+		req := httptest.NewRequest("POST", "/api/summarize", strings.NewReader(reqBody))
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("returns 400 for unknown style or bullet count", func(t *testing.T) {
+		for _, reqBody := range []string{
+			`{"url":"https://example.com","style":"sonnet"}`,
+			`{"url":"https://example.com","style":"one_liner","bullets":3}`,
+		} {
+			req := httptest.NewRequest("POST", "/api/summarize", strings.NewReader(reqBody))
+			req.Header.Set("Content-Type", "application/json")
+
+			resp, err := app.Test(req)
+			assert.NoError(t, err)
+			assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode, reqBody)
+		}
+	})
+
+	t.Run("returns 400 for disallowed models and bad sampling overrides", func(t *testing.T) {
+		for _, reqBody := range []string{
+			`{"url":"https://example.com","model":"gpt-4"}`,
+			`{"url":"https://example.com","temperature":3}`,
+			`{"url":"https://example.com","top_p":1.5}`,
+			`{"url":"https://example.com","max_tokens":100000}`,
+		} {
+			req := httptest.NewRequest("POST", "/api/summarize", strings.NewReader(reqBody))
+			req.Header.Set("Content-Type", "application/json")
+
+			resp, err := app.Test(req)
+			assert.NoError(t, err)
+			assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode, reqBody)
+		}
+	})
+
 	t.Run("returns 422 for invalid URL", func(t *testing.T) {
 		reqBody := `{"url":"not-a-url"}`
 		req := httptest.NewRequest("POST", "/api/summarize", strings.NewReader(reqBody))
@@ -80,5 +153,107 @@ func TestSummarizeHandler(t *testing.T) {
 		resp, err := app.Test(req)
 		assert.NoError(t, err)
 		assert.Equal(t, fiber.StatusUnprocessableEntity, resp.StatusCode)
+		assert.Regexp(t, `^validate;dur=\d+\.\d$`, resp.Header.Get("Server-Timing"), "failed requests still report their stages")
 	})
 }
+
+func TestShouldRedact(t *testing.T) {
+	on, off := true, false
+	t.Cleanup(func() { redactByDefault = false })
+
+	redactByDefault = false
+	assert.False(t, shouldRedact(SummarizeReq{}))
+	assert.False(t, shouldRedact(SummarizeReq{Redact: &off}))
+	assert.True(t, shouldRedact(SummarizeReq{Redact: &on}))
+
+	redactByDefault = true
+	assert.True(t, shouldRedact(SummarizeReq{}))
+	assert.True(t, shouldRedact(SummarizeReq{Redact: &on}))
+	assert.True(t, shouldRedact(SummarizeReq{Redact: &off}), "requests can't disable enforced redaction")
+}
+
+func TestSummarizeHandler_Translation(t *testing.T) {
+	pageURL := withArticle(t, "<html><head><title>Rates on hold</title></head><body><article><h1>Rates on hold</h1>"+
+		"<p>"+strings.ReplaceAll(marketArticle, "\n", "</p><p>")+"</p></article></body></html>")
+	regenerateUnsupported = true
+	t.Cleanup(func() { regenerateUnsupported = false })
+
+	summarize := func(t *testing.T, client *scriptedLLMClient, body string) SummarizeResp {
+		app := setupTestApp(client)
+		req := httptest.NewRequest("POST", "/api/summarize", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req, -1)
+		require.NoError(t, err)
+		require.Equal(t, fiber.StatusCreated, resp.StatusCode)
+		var out SummarizeResp
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&out))
+		return out
+	}
+
+	t.Run("verifies summaries in the article's language", func(t *testing.T) {
+		client := &scriptedLLMClient{normal: &llm.Summary{Headline: "Rates on hold", Language: "en",
+			Bullets: []string{"Rate unchanged at 4.25 percent", "Consumer prices rose 3.1 percent"}}}
+		out := summarize(t, client, `{"url":"`+pageURL+`"}`)
+		assert.Equal(t, "en", out.SourceLanguage)
+		require.Len(t, out.Support, 2)
+		assert.NotEmpty(t, out.Citations)
+	})
+
+	t.Run("skips verification of translations", func(t *testing.T) {
+		client := &scriptedLLMClient{normal: &llm.Summary{Headline: "Tipos sin cambios", Language: "es",
+			Bullets: []string{"El tipo se mantiene en el 4,25 por ciento", "Los precios subieron un 3,1 por ciento"}}}
+		out := summarize(t, client, `{"url":"`+pageURL+`","language":"es"}`)
+		assert.Equal(t, "en", out.SourceLanguage)
+		assert.Empty(t, out.Support, "a translation shares no words with its source")
+		assert.Empty(t, out.Citations)
+		assert.False(t, out.Regenerated)
+		assert.Zero(t, client.strictCalls, "no strict retry is spent on a translation")
+	})
+}
+
+// textLenLLMClient records how much article text each request carried
+type textLenLLMClient struct {
+	mockLLMClient
+	textLen int
+}
+
+func (m *textLenLLMClient) Summarize(ctx context.Context, req llm.Request) (*llm.Summary, error) {
+	m.textLen = len(req.Text)
+	return m.mockLLMClient.Summarize(ctx, req)
+}
+
+func TestSummarizeHandler_LongContextArticleBytes(t *testing.T) {
+	para := "<p>" + strings.Repeat("The harbor authority approved a new dredging plan for the outer channel. ", 20) + "</p>"
+	pageURL := withArticle(t, "<html><head><title>Harbor</title></head><body><article><h1>Harbor</h1>"+
+		strings.Repeat(para, 20)+"</article></body></html>")
+	oldModels := modelConfig
+	modelConfig = llm.ModelConfig{Model: "gpt-3.5-turbo", Temperature: 0.3, Allowed: []string{"gpt-4o-mini"}, LongContext: []string{"gpt-4o"}}
+	t.Cleanup(func() { modelConfig = oldModels })
+
+	for _, tc := range []struct {
+		name, model string
+		long        bool
+	}{
+		{"default model keeps the default cap", "", false},
+		{"allowed model keeps the default cap", "gpt-4o-mini", false},
+		{"long-context model gets the whole article", "gpt-4o", true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			client := &textLenLLMClient{}
+			app := setupTestApp(client)
+			req := httptest.NewRequest("POST", "/api/summarize", strings.NewReader(`{"url":"`+pageURL+`","model":"`+tc.model+`"}`))
+			req.Header.Set("Content-Type", "application/json")
+			resp, err := app.Test(req, -1)
+			require.NoError(t, err)
+			require.Equal(t, fiber.StatusCreated, resp.StatusCode)
+
+			max := config.Default().Fetch.MaxArticleBytes
+			if tc.long {
+				assert.Greater(t, client.textLen, max)
+			} else {
+				assert.LessOrEqual(t, client.textLen, max)
+			}
+		})
+	}
+	assert.Equal(t, config.Default().Fetch.MaxArticleBytes, extractor.MaxBytes(), "the shared extractor keeps its cap")
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/matthewmolinar/tldr/pkg/breaker"
+	"github.com/matthewmolinar/tldr/pkg/config"
+	"github.com/matthewmolinar/tldr/pkg/health"
+)
+
+// readiness holds the checks behind /readyz
+var readiness = health.NewRegistry()
+
+// pinger is a provider that can cheaply check it is reachable
+type pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// setupHealthChecks registers the readiness checks. Call it after
+// setupProviders and before anything wraps the providers.
+func setupHealthChecks(cfg *config.Config) {
+	readiness.Register(health.Check{
+		Name:     "shutdown",
+		Critical: true,
+		Run: func(context.Context) error {
+			if draining.Load() {
+				return errors.New("draining for shutdown")
+			}
+			return nil
+		},
+	})
+	readiness.Register(health.Check{
+		Name:     "config",
+		Critical: true,
+		Run:      func(context.Context) error { return cfg.Validate() },
+	})
+
+	// Probes hit the provider's API, so results are reused for a while. A
+	// failing provider fails every instance alike, so like an open breaker
+	// it only degrades readiness rather than emptying the load balancer.
+	for name, p := range providers {
+		probe, ok := p.(pinger)
+		if !ok {
+			continue
+		}
+		readiness.Register(health.Check{
+			Name:     "provider:" + name,
+			Interval: cfg.Health.ProbeInterval,
+			Timeout:  cfg.Health.ProbeTimeout,
+			Run:      probe.Ping,
+		})
+	}
+
+	// An open breaker means the upstream is failing for everyone, which
+	// taking this instance out of rotation wouldn't fix
+	readiness.Register(health.Check{
+		Name: "breakers",
+		Run: func(context.Context) error {
+			var open []string
+			for name := range providers {
+				if providerBreakers.Get(name).State() == breaker.Open {
+					open = append(open, name)
+				}
+			}
+			if len(open) > 0 {
+				sort.Strings(open)
+				return fmt.Errorf("circuit open for %s", strings.Join(open, ", "))
+			}
+			return nil
+		},
+	})
+}
+
+// handleLive answers 200 while the process can serve requests at all; it
+// checks nothing, so a slow dependency never gets the instance restarted
+func handleLive(c *fiber.Ctx) error {
+	return c.SendStatus(fiber.StatusOK)
+}
+
+// handleReady runs the readiness checks and answers 503 with the breakdown
+// when a critical one fails
+func handleReady(c *fiber.Ctx) error {
+	report := readiness.Run(c.UserContext())
+	status := fiber.StatusOK
+	if !report.Ready() {
+		status = fiber.StatusServiceUnavailable
+	}
+	return c.Status(status).JSON(report)
+}
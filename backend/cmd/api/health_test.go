@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/matthewmolinar/tldr/pkg/config"
+	"github.com/matthewmolinar/tldr/pkg/health"
+	"github.com/matthewmolinar/tldr/pkg/llm"
+	"github.com/matthewmolinar/tldr/pkg/llm/llmtest"
+	"github.com/sashabaranov/go-openai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// withProviders installs the given providers and a fresh readiness registry
+// for the test
+func withProviders(t *testing.T, ps map[string]llm.Summarizer) {
+	savedProviders, savedReadiness := providers, readiness
+	providers, readiness = ps, health.NewRegistry()
+	t.Cleanup(func() { providers, readiness = savedProviders, savedReadiness })
+}
+
+func readyReport(t *testing.T) (int, health.Report) {
+	t.Helper()
+	app := fiber.New()
+	app.Get("/readyz", handleReady)
+	resp, err := app.Test(httptest.NewRequest("GET", "/readyz", nil))
+	require.NoError(t, err)
+
+	var report health.Report
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&report))
+	return resp.StatusCode, report
+}
+
+func TestReadyz(t *testing.T) {
+	withProviderBreakers(t, 1)
+	fake := llmtest.NewServer()
+	defer fake.Close()
+	fake.Models = []string{"llama3"}
+	client, err := llm.NewProvider(llm.ProviderConfig{BaseURL: fake.BaseURL(), Model: "llama3"})
+	require.NoError(t, err)
+	withProviders(t, map[string]llm.Summarizer{defaultProvider: client, "mock": &mockLLMClient{}})
+
+	cfg := config.Default()
+	cfg.LLM.APIKey = "test-key"
+	setupHealthChecks(cfg)
+
+	status, report := readyReport(t)
+	assert.Equal(t, fiber.StatusOK, status)
+	assert.Equal(t, health.StatusOK, report.Status)
+	assert.ElementsMatch(t, []string{"shutdown", "config", "provider:" + defaultProvider, "breakers"}, keys(report.Checks),
+		"providers that can't be probed are skipped")
+	assert.False(t, report.Checks["provider:"+defaultProvider].Critical)
+
+	t.Run("draining fails", func(t *testing.T) {
+		draining.Store(true)
+		t.Cleanup(func() { draining.Store(false) })
+		status, report := readyReport(t)
+		assert.Equal(t, fiber.StatusServiceUnavailable, status)
+		assert.Equal(t, health.StatusFail, report.Checks["shutdown"].Status)
+	})
+
+	t.Run("open breaker degrades", func(t *testing.T) {
+		providerBreakers.Get("mock").Record(&openai.APIError{HTTPStatusCode: 500})
+		status, report := readyReport(t)
+		assert.Equal(t, fiber.StatusOK, status)
+		assert.Equal(t, health.StatusDegraded, report.Status)
+		assert.Equal(t, "circuit open for mock", report.Checks["breakers"].Error)
+	})
+}
+
+func TestReadyz_ProviderRejectsKey(t *testing.T) {
+	withProviderBreakers(t, 1)
+	fake := llmtest.NewServer()
+	defer fake.Close()
+	fake.AuthHeader, fake.AuthValue = "Authorization", "Bearer right-key"
+	client, err := llm.NewProvider(llm.ProviderConfig{BaseURL: fake.BaseURL(), Model: "llama3", APIKey: "wrong-key"})
+	require.NoError(t, err)
+	withProviders(t, map[string]llm.Summarizer{defaultProvider: client})
+
+	cfg := config.Default()
+	cfg.LLM.APIKey = "test-key"
+	setupHealthChecks(cfg)
+
+	// A bad key breaks every instance, so the instance stays in rotation
+	status, report := readyReport(t)
+	assert.Equal(t, fiber.StatusOK, status)
+	assert.Equal(t, health.StatusDegraded, report.Status)
+	assert.Contains(t, report.Checks["provider:"+defaultProvider].Error, "invalid api key")
+}
+
+func TestLivez(t *testing.T) {
+	draining.Store(true)
+	t.Cleanup(func() { draining.Store(false) })
+
+	app := fiber.New()
+	app.Get("/livez", handleLive)
+	resp, err := app.Test(httptest.NewRequest("GET", "/livez", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode, "liveness ignores shutdown and dependencies")
+}
+
+func keys[V any](m map[string]V) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	return out
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/matthewmolinar/tldr/pkg/config"
+	"github.com/matthewmolinar/tldr/pkg/llm"
+	"github.com/matthewmolinar/tldr/pkg/metrics"
+)
+
+// hedgeEvents counts hedged calls by event: request, hedged and won. The
+// hedge rate is hedged/request and the win rate won/hedged.
+var hedgeEvents = metrics.Default.Counter("llm_hedge_events_total", "Hedged LLM calls by event (request, hedged, won)")
+
+// setupHedging wraps the default provider in a hedger when hedging is
+// enabled. Hedges go to cfg.Provider, defaulting to the primary itself.
+func setupHedging(hc config.Hedge) error {
+	if !hc.Enabled {
+		return nil
+	}
+
+	cfg := llm.DefaultHedgeConfig
+	if hc.Percentile != 0 {
+		cfg.Percentile = hc.Percentile
+	}
+	cfg.OnEvent = func(e llm.HedgeEvent) {
+		hedgeEvents.Inc(metrics.Labels{"event": string(e)})
+	}
+
+	primary := providers[defaultProvider]
+	var secondary llm.Summarizer
+	if name := hc.Provider; name != "" {
+		s, ok := providers[name]
+		if !ok {
+			return fmt.Errorf("hedge provider %q is not configured", name)
+		}
+		secondary = s
+	}
+
+	hedger := llm.NewHedger(primary, secondary, cfg)
+	providers[defaultProvider] = hedger
+	llmClient = hedger
+	log.Printf("Hedging %s requests at the p%.0f latency", defaultProvider, cfg.Percentile*100)
+	return nil
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/matthewmolinar/tldr/pkg/config"
+	"github.com/matthewmolinar/tldr/pkg/llm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetupHedging(t *testing.T) {
+	saved, savedClient := providers[defaultProvider], llmClient
+	t.Cleanup(func() { providers[defaultProvider], llmClient = saved, savedClient })
+	reset := func() { providers[defaultProvider], llmClient = &mockLLMClient{}, &mockLLMClient{} }
+
+	t.Run("disabled by default", func(t *testing.T) {
+		reset()
+		require.NoError(t, setupHedging(config.Default().Hedge))
+		assert.IsType(t, &mockLLMClient{}, providers[defaultProvider])
+	})
+
+	t.Run("wraps the default provider", func(t *testing.T) {
+		reset()
+		require.NoError(t, setupHedging(config.Hedge{Enabled: true, Percentile: 0.9}))
+		assert.IsType(t, &llm.Hedger{}, providers[defaultProvider])
+		assert.Same(t, providers[defaultProvider], llmClient)
+	})
+
+	t.Run("rejects an unknown hedge provider", func(t *testing.T) {
+		reset()
+		assert.Error(t, setupHedging(config.Hedge{Enabled: true, Provider: "nope"}))
+	})
+}
@@ -1,67 +1,152 @@
 package main
 
 import (
+	"context"
 	"log"
+	"net"
 	"os"
-	"time"
+	"os/signal"
+	"syscall"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
 	"github.com/gofiber/fiber/v2/middleware/logger"
-	"github.com/joho/godotenv"
+	"github.com/gofiber/fiber/v2/middleware/requestid"
+	"github.com/matthewmolinar/tldr/pkg/config"
+	"github.com/matthewmolinar/tldr/pkg/extract"
 	"github.com/matthewmolinar/tldr/pkg/llm"
+	"github.com/matthewmolinar/tldr/pkg/metrics"
+	"github.com/matthewmolinar/tldr/pkg/middleware"
+	"github.com/matthewmolinar/tldr/pkg/trace"
+	"github.com/matthewmolinar/tldr/pkg/validate"
 )
 
 // Global LLM client for reuse
 var llmClient llm.Summarizer
 
+// Article fetching, configured in main
+var (
+	extractor    = extract.New(config.Default().Fetch, nil)
+	urlValidator = validate.New(config.Default().Fetch, nil)
+)
+
 func main() {
-	// Load .env file
-	if err := godotenv.Load(); err != nil {
-		log.Printf("Warning: Error loading .env file: %v", err)
+	// Load defaults, CONFIG_FILE, .env and the environment
+	cfg, err := config.Load("")
+	if err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
 	}
+	log.Printf("Configuration:\n%s", cfg)
 
-	// Initialize LLM client
-	// Debug: Print first 10 chars of API key
-	apiKey := os.Getenv("OPENAI_API_KEY")
-	if len(apiKey) > 10 {
-		log.Printf("Using API key starting with: %s...", apiKey[:10])
-	} else {
-		log.Printf("API key is too short or empty")
+	hc := cfg.HTTPClient()
+	if cfg.InsecureSkipVerify {
+		log.Printf("TLS certificate verification is disabled for outbound requests")
 	}
 
-	var err error
-	llmClient, err = llm.NewClient()
-	if err != nil {
+	// Export spans to the collector, if one is configured; outbound calls
+	// get client spans in the trace of the request that made them
+	setupTracing(cfg.Tracing, hc)
+	traced := trace.Client(hc)
+
+	urlValidator = validate.New(cfg.Fetch, traced)
+
+	// Initialize LLM clients
+	if err := setupProviders(cfg.LLM, traced); err != nil {
 		log.Fatalf("Failed to initialize LLM client: %v", err)
 	}
+	extractor = extract.New(cfg.Fetch, timedClient(traced))
+	summarizeTimeout = cfg.LLM.Timeout
+
+	// Mask PII and secrets in every article, or only when a request asks
+	redactByDefault = cfg.Summaries.RedactPII
+
+	// Stop calling the LLM once the day's spend reaches the cap
+	dailySpend = newSpendTracker(cfg.Summaries.DailySpendCapUSD)
+
+	// Quote the article instead of failing when the LLM can't be used
+	extractiveFallback = cfg.Summaries.ExtractiveFallback
+
+	// Retry summaries with unsupported bullets in strict mode
+	regenerateUnsupported = cfg.Summaries.VerifyRegenerate
+
+	// Readiness checks probe the providers before hedging wraps them
+	setupHealthChecks(cfg)
+
+	// Send a second request when the primary provider is slow
+	if err := setupHedging(cfg.Hedge); err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+
+	// Report the fetcher's per-origin circuit breakers
+	watchOriginBreakers()
+
+	// Load the A/B experiment, if one is configured, checking its prompt
+	// versions against the templates the providers use
+	prompts, err := llm.LoadPrompts(cfg.LLM.PromptDir)
+	if err != nil {
+		log.Fatalf("Failed to load prompts: %v", err)
+	}
+	if err := setupExperiment(cfg.Experiment, prompts); err != nil {
+		log.Fatalf("Failed to set up experiment: %v", err)
+	}
+
+	allowOrigin, err := middleware.NewOriginMatcher(cfg.Server.CORSOrigins)
+	if err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
 
 	app := fiber.New(fiber.Config{
-		ReadTimeout:  5 * time.Second,
-		WriteTimeout: 5 * time.Second,
+		ReadTimeout:  cfg.Server.ReadTimeout,
+		WriteTimeout: cfg.Server.WriteTimeout,
 	})
 
 	// Add middleware
+	app.Use(requestid.New())
+	app.Use(middleware.Tracing(trace.Default))
 	app.Use(logger.New())
+	app.Use(middleware.Metrics(metrics.Default))
+	app.Use(middleware.SecurityHeaders(cfg.Security))
+	app.Use(middleware.ErrorMiddleware())
 	app.Use(cors.New(cors.Config{
-		AllowOrigins: "https://web-tldr.vercel.app",
-		AllowMethods: "GET,POST,OPTIONS",
-		AllowHeaders: "Content-Type",
+		AllowOriginsFunc: allowOrigin,
+		AllowMethods:     "GET,POST,OPTIONS",
+		AllowHeaders:     "Content-Type,X-Client-ID,traceparent,tracestate",
+		ExposeHeaders:    "X-Request-ID,X-Experiment-Variant,X-LLM-Prompt-Tokens,X-LLM-Completion-Tokens,X-LLM-Cost-USD,X-Trace-ID,Server-Timing",
 	}))
 
-	// Health check endpoint
-	app.Get("/healthz", func(c *fiber.Ctx) error {
-		return c.SendStatus(fiber.StatusOK)
-	})
+	setupRoutes(app, cfg.Server.AdminToken)
+
+	// Drain in-flight summaries on SIGINT (Fly's kill signal) or SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	ln, err := net.Listen("tcp", cfg.Server.Addr())
+	if err != nil {
+		log.Fatalf("Failed to listen: %v", err)
+	}
+	if err := serve(ctx, app, ln, cfg.Server, closeExperimentLog, logMetrics, flushTraces); err != nil {
+		log.Fatalf("Shutdown: %v", err)
+	}
+	log.Printf("Shutdown complete")
+}
+
+// setupRoutes registers the endpoints. Usage, metrics and experiment data
+// are for operators and need the admin token.
+func setupRoutes(app *fiber.App, adminToken string) {
+	admin := middleware.RequireToken(adminToken)
+
+	// Health check endpoint; /healthz?details adds circuit breaker states
+	app.Get("/healthz", handleHealth)
+	app.Get("/livez", handleLive)
+	app.Get("/readyz", handleReady)
+
+	// Prometheus scrape endpoint
+	app.Get("/metrics", admin, handleMetrics)
 
 	// API routes
 	api := app.Group("/api")
 	api.Post("/summarize", handleSummarize)
-
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
-	}
-
-	log.Fatal(app.Listen(":" + port))
+	api.Post("/feedback", handleFeedback)
+	api.Get("/experiments/report", admin, handleExperimentReport)
+	api.Get("/experiments/export", admin, handleExperimentExport)
+	api.Get("/usage", admin, handleUsage)
 }
@@ -6,14 +6,14 @@ import (
 	"testing"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/matthewmolinar/tldr/pkg/middleware"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestHealthzEndpoint(t *testing.T) {
 	app := fiber.New()
-	app.Get("/healthz", func(c *fiber.Ctx) error {
-		return c.SendStatus(fiber.StatusOK)
-	})
+	app.Get("/healthz", handleHealth)
 
 	req := httptest.NewRequest("GET", "/healthz", nil)
 	resp, err := app.Test(req)
@@ -24,3 +24,25 @@ func TestHealthzEndpoint(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, "OK", string(body))
 }
+
+func TestAdminRoutes(t *testing.T) {
+	app := fiber.New()
+	app.Use(middleware.ErrorMiddleware())
+	setupRoutes(app, "s3cret")
+
+	for _, path := range []string{"/metrics", "/api/usage", "/api/experiments/report", "/api/experiments/export"} {
+		resp, err := app.Test(httptest.NewRequest("GET", path, nil))
+		require.NoError(t, err)
+		assert.Equal(t, fiber.StatusUnauthorized, resp.StatusCode, path)
+
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set("Authorization", "Bearer s3cret")
+		resp, err = app.Test(req)
+		require.NoError(t, err)
+		assert.NotEqual(t, fiber.StatusUnauthorized, resp.StatusCode, path)
+	}
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/healthz", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode, "health checks stay public")
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/matthewmolinar/tldr/pkg/config"
+	"github.com/matthewmolinar/tldr/pkg/llm"
+)
+
+// modelConfig holds the deployment's model settings; its allow-list governs
+// the models requests may ask for
+var modelConfig = llm.DefaultModelConfig
+
+// setupProviders registers the OpenAI client when an API key is configured
+// and the self-hosted one when a base URL is, then makes the configured
+// provider the global client
+func setupProviders(cfg config.LLM, hc *http.Client) error {
+	defaultProvider = cfg.Provider
+
+	var err error
+	modelConfig, err = llm.NewModelConfig(cfg)
+	if err != nil {
+		return err
+	}
+
+	if cfg.APIKey != "" {
+		client, err := llm.NewClient(cfg, hc)
+		if err != nil {
+			return err
+		}
+		providers["openai"] = client
+	}
+
+	if cfg.Local.BaseURL != "" {
+		client, err := llm.NewLocalClient(cfg, hc)
+		if err != nil {
+			return err
+		}
+		providers["local"] = client
+		log.Printf("Registered local provider at %s with model %s", cfg.Local.BaseURL, client.Config.Model)
+	}
+
+	primary, ok := providers[defaultProvider]
+	if !ok {
+		return fmt.Errorf("provider %q is not configured", defaultProvider)
+	}
+	llmClient = primary
+	return nil
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/matthewmolinar/tldr/pkg/config"
+)
+
+// draining is set once shutdown begins, so health checks fail and load
+// balancers stop routing new requests here while in-flight ones finish
+var draining atomic.Bool
+
+// serve runs app on ln until ctx is done, then shuts down gracefully: it marks
+// the instance as draining, keeps serving for cfg.DrainDelay while load
+// balancers notice, stops accepting connections, and waits up to
+// cfg.DrainTimeout for in-flight requests before running cleanup. It returns
+// an error if the server failed, requests were cut off, or cleanup failed.
+func serve(ctx context.Context, app *fiber.App, ln net.Listener, cfg config.Server, cleanup ...func() error) error {
+	served := make(chan error, 1)
+	go func() { served <- app.Listener(ln) }()
+
+	select {
+	case err := <-served:
+		return err
+	case <-ctx.Done():
+	}
+
+	draining.Store(true)
+	log.Printf("Shutting down: draining requests for up to %s", cfg.DrainDelay+cfg.DrainTimeout)
+	time.Sleep(cfg.DrainDelay)
+
+	drainCtx, cancel := context.WithTimeout(context.Background(), cfg.DrainTimeout)
+	defer cancel()
+	var errs []error
+	if err := app.ShutdownWithContext(drainCtx); err != nil {
+		errs = append(errs, fmt.Errorf("draining requests: %w", err))
+	}
+	if err := <-served; err != nil {
+		errs = append(errs, err)
+	}
+	for _, fn := range cleanup {
+		if err := fn(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/matthewmolinar/tldr/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// startServer serves app on a free port until the returned cancel is called;
+// done receives serve's result
+func startServer(t *testing.T, app *fiber.App, cfg config.Server, cleanup ...func() error) (url string, cancel context.CancelFunc, done <-chan error) {
+	t.Helper()
+	t.Cleanup(func() { draining.Store(false) })
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	go func() { errc <- serve(ctx, app, ln, cfg, cleanup...) }()
+	return "http://" + ln.Addr().String(), cancel, errc
+}
+
+func TestServe_DrainsInFlightRequests(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	app := fiber.New(fiber.Config{DisableStartupMessage: true})
+	app.Get("/healthz", handleHealth)
+	app.Get("/slow", func(c *fiber.Ctx) error {
+		close(started)
+		<-release
+		return c.SendString("summary")
+	})
+
+	cleanedUp := false
+	cfg := config.Server{DrainDelay: 100 * time.Millisecond, DrainTimeout: 5 * time.Second}
+	url, shutdown, done := startServer(t, app, cfg, func() error {
+		cleanedUp = true
+		return nil
+	})
+
+	type result struct {
+		status int
+		body   string
+		err    error
+	}
+	inFlight := make(chan result, 1)
+	go func() {
+		resp, err := http.Get(url + "/slow")
+		if err != nil {
+			inFlight <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		inFlight <- result{resp.StatusCode, string(body), err}
+	}()
+	<-started
+	shutdown()
+
+	// Health checks fail during the drain delay so new traffic goes elsewhere
+	require.Eventually(t, draining.Load, time.Second, 5*time.Millisecond)
+	resp, err := http.Get(url + "/healthz")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, fiber.StatusServiceUnavailable, resp.StatusCode)
+
+	// The in-flight request finishes before the server exits
+	select {
+	case err := <-done:
+		t.Fatalf("server exited with a request in flight: %v", err)
+	case <-time.After(200 * time.Millisecond):
+	}
+	close(release)
+
+	r := <-inFlight
+	require.NoError(t, r.err)
+	assert.Equal(t, fiber.StatusOK, r.status)
+	assert.Equal(t, "summary", r.body)
+
+	require.NoError(t, <-done)
+	assert.True(t, cleanedUp)
+
+	_, err = http.Get(url + "/healthz")
+	assert.Error(t, err, "no new connections after shutdown")
+}
+
+func TestServe_DrainTimeout(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	started := make(chan struct{})
+	app := fiber.New(fiber.Config{DisableStartupMessage: true})
+	app.Get("/stuck", func(c *fiber.Ctx) error {
+		close(started)
+		<-release
+		return nil
+	})
+
+	url, shutdown, done := startServer(t, app, config.Server{DrainTimeout: 50 * time.Millisecond})
+	go http.Get(url + "/stuck")
+	<-started
+	shutdown()
+
+	select {
+	case err := <-done:
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	case <-time.After(2 * time.Second):
+		t.Fatal("shutdown did not give up after the drain timeout")
+	}
+}
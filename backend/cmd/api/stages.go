@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/matthewmolinar/tldr/pkg/metrics"
+	"github.com/matthewmolinar/tldr/pkg/trace"
+)
+
+// Summarize pipeline metrics
+var (
+	stageDuration = metrics.Default.Histogram("summarize_stage_duration_seconds",
+		"Time a summarize request spent in each pipeline stage (validate, fetch, extract, llm, verify)", metrics.DurationBuckets)
+	articleBytes = metrics.Default.Histogram("article_content_bytes",
+		"Size of the article text extracted for summarizing", metrics.ExponentialBuckets(256, 2, 8))
+	fetchedBytes = metrics.Default.Histogram("fetch_response_bytes",
+		"Size of the pages downloaded from origins", metrics.ExponentialBuckets(4096, 4, 8))
+)
+
+// stageTimer accumulates the time one request spends in each pipeline
+// stage, in the order the stages first ran. It is safe for concurrent use.
+type stageTimer struct {
+	mu     sync.Mutex
+	stages []stageTiming
+}
+
+type stageTiming struct {
+	name string
+	dur  time.Duration
+}
+
+type stageTimerKey struct{}
+
+// withStageTimer returns ctx carrying t, for stages timed deeper in the call
+// stack, like origin downloads
+func withStageTimer(ctx context.Context, t *stageTimer) context.Context {
+	return context.WithValue(ctx, stageTimerKey{}, t)
+}
+
+// stageTimerFrom returns the timer ctx carries, or nil
+func stageTimerFrom(ctx context.Context) *stageTimer {
+	t, _ := ctx.Value(stageTimerKey{}).(*stageTimer)
+	return t
+}
+
+// add records time spent in a stage; stages that run repeatedly add up
+func (t *stageTimer) add(name string, d time.Duration) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for i := range t.stages {
+		if t.stages[i].name == name {
+			t.stages[i].dur += d
+			return
+		}
+	}
+	t.stages = append(t.stages, stageTiming{name, d})
+}
+
+// begin starts a stage and a span for it, returning ctx carrying the span.
+// Call end with the stage's error when it finishes. The stage is charged its
+// own time only: stages recorded while it ran, like the downloads inside
+// extract, are subtracted.
+func (t *stageTimer) begin(ctx context.Context, name string) (context.Context, *trace.Span, func(error)) {
+	ctx, span := trace.Start(ctx, name, trace.KindInternal)
+	start, nested := time.Now(), t.sum()
+	return ctx, span, func(err error) {
+		t.add(name, time.Since(start)-(t.sum()-nested))
+		span.RecordError(err)
+		span.End()
+	}
+}
+
+// sum is the time recorded across all stages so far
+func (t *stageTimer) sum() time.Duration {
+	if t == nil {
+		return 0
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var d time.Duration
+	for _, s := range t.stages {
+		d += s.dur
+	}
+	return d
+}
+
+// total returns the time recorded for a stage so far
+func (t *stageTimer) total(name string) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, s := range t.stages {
+		if s.name == name {
+			return s.dur
+		}
+	}
+	return 0
+}
+
+// timings returns the stages in the order they first ran
+func (t *stageTimer) timings() []stageTiming {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]stageTiming(nil), t.stages...)
+}
+
+// serverTiming formats the stages as a Server-Timing header value, in
+// milliseconds, so browser dev tools show where a request's time went
+func (t *stageTimer) serverTiming() string {
+	var b strings.Builder
+	for i, s := range t.timings() {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		fmt.Fprintf(&b, "%s;dur=%.1f", s.name, float64(s.dur.Microseconds())/1000)
+	}
+	return b.String()
+}
+
+// observe records the request's stage totals in the stage histogram
+func (t *stageTimer) observe() {
+	for _, s := range t.timings() {
+		stageDuration.Observe(s.dur.Seconds(), metrics.Labels{"stage": s.name})
+	}
+}
+
+// urlHost returns the host an article URL points at, for span attributes
+func urlHost(rawURL string) string {
+	if u, err := url.Parse(rawURL); err == nil {
+		return u.Hostname()
+	}
+	return ""
+}
+
+// fetchTiming times origin downloads, from sending the request to closing
+// the body, as the "fetch" stage of the request the context carries
+type fetchTiming struct {
+	base http.RoundTripper
+}
+
+// timedClient returns a copy of hc whose requests are timed as fetches
+func timedClient(hc *http.Client) *http.Client {
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	base := hc.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	timed := *hc
+	timed.Transport = fetchTiming{base}
+	return &timed
+}
+
+func (f fetchTiming) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	timer := stageTimerFrom(req.Context())
+	resp, err := f.base.RoundTrip(req)
+	if err != nil {
+		timer.add("fetch", time.Since(start))
+		return nil, err
+	}
+	resp.Body = &timedBody{ReadCloser: resp.Body, done: func(n int64) {
+		timer.add("fetch", time.Since(start))
+		fetchedBytes.Observe(float64(n), nil)
+	}}
+	return resp, nil
+}
+
+// timedBody calls done with the bytes read once, when closed
+type timedBody struct {
+	io.ReadCloser
+	n    int64
+	once sync.Once
+	done func(n int64)
+}
+
+func (b *timedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.n += int64(n)
+	return n, err
+}
+
+func (b *timedBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(func() { b.done(b.n) })
+	return err
+}
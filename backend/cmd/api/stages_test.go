@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/matthewmolinar/tldr/pkg/metrics"
+	"github.com/matthewmolinar/tldr/pkg/trace"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStageTimer(t *testing.T) {
+	timer := &stageTimer{}
+	timer.add("validate", 10*time.Millisecond)
+	timer.add("fetch", 100*time.Millisecond)
+	timer.add("fetch", 50*time.Millisecond)
+	_, _, end := timer.begin(context.Background(), "llm")
+	end(nil)
+
+	timings := timer.timings()
+	require.Len(t, timings, 3)
+	assert.Equal(t, []string{"validate", "fetch", "llm"}, []string{timings[0].name, timings[1].name, timings[2].name})
+	assert.Equal(t, 150*time.Millisecond, timer.total("fetch"), "repeated stages add up")
+	assert.Zero(t, timer.total("verify"))
+
+	before := stageDuration.Count(metrics.Labels{"stage": "fetch"})
+	timer.observe()
+	assert.Equal(t, before+1, stageDuration.Count(metrics.Labels{"stage": "fetch"}))
+
+	// Timing without a timer in the context is a no-op
+	var none *stageTimer
+	none.add("fetch", time.Second)
+}
+
+func TestStageTimer_Begin(t *testing.T) {
+	rec := &spanRecorder{}
+	defer func(old *trace.Tracer) { trace.Default = old }(trace.Default)
+	trace.Default = trace.NewTracer(rec, 1)
+
+	timer := &stageTimer{}
+	ctx, root := trace.Start(context.Background(), "POST /api/summarize", trace.KindServer)
+	ctx, span, end := timer.begin(ctx, "extract")
+	span.SetAttr("server.address", "example.com")
+	time.Sleep(10 * time.Millisecond)
+	timer.add("fetch", time.Hour) // a download inside extract
+	end(errors.New("paywall"))
+	root.End()
+
+	assert.Less(t, timer.total("extract"), time.Duration(0), "nested stage time is not charged to extract")
+	require.Len(t, rec.spans, 2)
+	assert.Equal(t, "extract", rec.spans[0].Name)
+	assert.Equal(t, root.Context().SpanID, rec.spans[0].Parent)
+	assert.True(t, rec.spans[0].Failed)
+	assert.Contains(t, rec.spans[0].Attrs, trace.Attr{Key: "server.address", Value: "example.com"})
+	assert.Equal(t, trace.SpanFromContext(ctx).Context(), rec.spans[0].Context)
+}
+
+func TestStageTimer_ServerTiming(t *testing.T) {
+	timer := &stageTimer{}
+	assert.Empty(t, timer.serverTiming())
+
+	timer.add("validate", 12345*time.Microsecond)
+	timer.add("fetch", 250*time.Millisecond)
+	timer.add("llm", 1500*time.Millisecond)
+	assert.Equal(t, "validate;dur=12.3, fetch;dur=250.0, llm;dur=1500.0", timer.serverTiming())
+}
+
+// spanRecorder collects the spans the tracer exports
+type spanRecorder struct {
+	mu    sync.Mutex
+	spans []trace.SpanData
+}
+
+func (r *spanRecorder) Export(s trace.SpanData) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.spans = append(r.spans, s)
+}
+
+func TestTimedClient(t *testing.T) {
+	page := strings.Repeat("x", 5000)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(20 * time.Millisecond)
+		io.WriteString(w, page)
+	}))
+	defer ts.Close()
+
+	timer := &stageTimer{}
+	req, err := http.NewRequestWithContext(withStageTimer(context.Background(), timer), http.MethodGet, ts.URL, nil)
+	require.NoError(t, err)
+	before := fetchedBytes.Count(nil)
+
+	resp, err := timedClient(nil).Do(req)
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+
+	assert.Len(t, body, len(page))
+	assert.GreaterOrEqual(t, timer.total("fetch"), 20*time.Millisecond)
+	assert.Equal(t, before+1, fetchedBytes.Count(nil))
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/matthewmolinar/tldr/pkg/config"
+	"github.com/matthewmolinar/tldr/pkg/trace"
+)
+
+// traceFlushTimeout bounds the final span export at shutdown
+const traceFlushTimeout = 5 * time.Second
+
+// traceExporter sends spans to the collector, when one is configured
+var traceExporter *trace.OTLPExporter
+
+// setupTracing points the default tracer at the configured collector. hc
+// sends the spans and must not itself be traced.
+func setupTracing(cfg config.Tracing, hc *http.Client) {
+	if cfg.Endpoint == "" {
+		return
+	}
+	traceExporter = trace.NewOTLPExporter(cfg.Endpoint, cfg.ServiceName, hc)
+	trace.Default = trace.NewTracer(traceExporter, cfg.SampleRatio)
+	log.Printf("Exporting traces to %s as %s, sampling %g of new traces", cfg.Endpoint, cfg.ServiceName, cfg.SampleRatio)
+}
+
+// flushTraces sends the spans still queued at shutdown
+func flushTraces() error {
+	if traceExporter == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), traceFlushTimeout)
+	defer cancel()
+	if n := traceExporter.Dropped(); n > 0 {
+		log.Printf("Dropped %d spans the collector could not keep up with", n)
+	}
+	return traceExporter.Shutdown(ctx)
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/matthewmolinar/tldr/pkg/eval"
+	"github.com/matthewmolinar/tldr/pkg/extract"
+	"github.com/matthewmolinar/tldr/pkg/lang"
+	"github.com/matthewmolinar/tldr/pkg/llm"
+)
+
+// evalCase is one saved article with its reference summary
+type evalCase struct {
+	Name      string
+	HTML      []byte
+	Reference eval.Reference
+}
+
+// runOptions selects the configuration under evaluation
+type runOptions struct {
+	Provider      string
+	Model         string
+	Style         string
+	PromptVersion string
+}
+
+// loadCorpus reads every <dir>/<case>/article.html and reference.json pair
+func loadCorpus(dir string) ([]evalCase, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var cases []evalCase
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		caseDir := filepath.Join(dir, e.Name())
+
+		html, err := os.ReadFile(filepath.Join(caseDir, "article.html"))
+		if err != nil {
+			return nil, fmt.Errorf("case %s: %w", e.Name(), err)
+		}
+		refData, err := os.ReadFile(filepath.Join(caseDir, "reference.json"))
+		if err != nil {
+			return nil, fmt.Errorf("case %s: %w", e.Name(), err)
+		}
+		var ref eval.Reference
+		if err := json.Unmarshal(refData, &ref); err != nil {
+			return nil, fmt.Errorf("case %s: invalid reference.json: %w", e.Name(), err)
+		}
+
+		cases = append(cases, evalCase{Name: e.Name(), HTML: html, Reference: ref})
+	}
+	if len(cases) == 0 {
+		return nil, fmt.Errorf("no cases found in %s", dir)
+	}
+
+	sort.Slice(cases, func(i, j int) bool { return cases[i].Name < cases[j].Name })
+	return cases, nil
+}
+
+// run extracts and summarizes each case the way the API does and scores the result
+func run(summarizer llm.Summarizer, cases []evalCase, opts runOptions) (*eval.Report, error) {
+	style, err := llm.LookupStyle(opts.Style)
+	if err != nil {
+		return nil, err
+	}
+	bullets, err := style.BulletCount(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &eval.Report{
+		Provider:    opts.Provider,
+		Model:       opts.Model,
+		Style:       style.Name,
+		GeneratedAt: time.Now().UTC(),
+	}
+
+	for _, c := range cases {
+		log.Printf("Evaluating %s", c.Name)
+		text, err := extract.FromHTML(c.HTML, c.Reference.URL)
+		if err != nil {
+			report.Results = append(report.Results, eval.Result{Case: c.Name, Error: err.Error()})
+			continue
+		}
+
+		target := lang.Detect(text)
+		if !lang.Supported(target) {
+			target = lang.Default
+		}
+
+		start := time.Now()
+		summary, err := summarizer.Summarize(context.Background(), llm.Request{
+			Text:          text,
+			Language:      target,
+			Style:         style.Name,
+			Model:         opts.Model,
+			PromptVersion: opts.PromptVersion,
+		})
+		latency := time.Since(start)
+		if err != nil {
+			report.Results = append(report.Results, eval.Result{Case: c.Name, Error: err.Error(), LatencyMS: latency.Milliseconds()})
+			continue
+		}
+		if report.PromptVersion == "" {
+			report.PromptVersion = summary.PromptVersion
+		}
+		if report.Model == "" {
+			report.Model = summary.Model
+		}
+
+		res := eval.Evaluate(c.Name, summary.Headline, summary.Bullets, c.Reference, text,
+			eval.Limits{MaxWidth: style.MaxWidth, Bullets: bullets})
+		res.LatencyMS = latency.Milliseconds()
+		report.Results = append(report.Results, res)
+	}
+
+	report.Summarize()
+	return report, nil
+}
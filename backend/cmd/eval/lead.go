@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+
+	"github.com/matthewmolinar/tldr/pkg/lang"
+	"github.com/matthewmolinar/tldr/pkg/llm"
+	"github.com/matthewmolinar/tldr/pkg/nlp"
+)
+
+// leadSummarizer is the classic lead-N baseline: the first sentence becomes
+// the headline and the following sentences the bullets. It needs no API key,
+// and any provider worth shipping should beat it.
+type leadSummarizer struct{}
+
+func (leadSummarizer) Summarize(ctx context.Context, req llm.Request) (*llm.Summary, error) {
+	style, err := llm.LookupStyle(req.Style)
+	if err != nil {
+		return nil, err
+	}
+	n, err := style.BulletCount(req.Bullets)
+	if err != nil {
+		return nil, err
+	}
+
+	sentences := nlp.Sentences(req.Text)
+	summary := &llm.Summary{Language: req.Language, Style: style.Name, Model: "lead"}
+	if len(sentences) == 0 {
+		return summary, nil
+	}
+
+	// Spread the budget evenly so every bullet survives
+	share := style.MaxWidth / (n + 1)
+	summary.Headline = lang.Truncate(sentences[0], share)
+	for _, s := range sentences[1:min(len(sentences), n+1)] {
+		summary.Bullets = append(summary.Bullets, lang.Truncate(s, share))
+	}
+	return summary, nil
+}
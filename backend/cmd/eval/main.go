@@ -0,0 +1,110 @@
+// Command eval runs a corpus of saved articles through the summarization
+// pipeline and writes a JSON and Markdown quality report, so prompt and model
+// changes can be compared offline.
+//
+// Usage (from backend/):
+//
+//	go run ./cmd/eval -provider openai -style headline -out report.json -md report.md
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/matthewmolinar/tldr/pkg/config"
+	"github.com/matthewmolinar/tldr/pkg/eval"
+	"github.com/matthewmolinar/tldr/pkg/llm"
+)
+
+func main() {
+	corpusDir := flag.String("corpus", "cmd/eval/testdata/corpus", "directory of <case>/article.html + reference.json")
+	provider := flag.String("provider", "openai", "summarizer to evaluate: openai, local (LOCAL_LLM_* server), extractive (TextRank) or lead (first-sentences baseline)")
+	model := flag.String("model", "", "chat model override")
+	style := flag.String("style", llm.DefaultStyle, "summary style preset")
+	promptVersion := flag.String("prompt-version", "", "prompt template version (default: latest)")
+	jsonOut := flag.String("out", "eval-report.json", "JSON report path")
+	mdOut := flag.String("md", "eval-report.md", "Markdown report path; empty to skip")
+	flag.Parse()
+
+	cfg, err := config.Read("")
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	summarizer, err := newProvider(*provider, cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize provider: %v", err)
+	}
+
+	cases, err := loadCorpus(*corpusDir)
+	if err != nil {
+		log.Fatalf("Failed to load corpus: %v", err)
+	}
+
+	report, err := run(summarizer, cases, runOptions{
+		Provider:      *provider,
+		Model:         *model,
+		Style:         *style,
+		PromptVersion: *promptVersion,
+	})
+	if err != nil {
+		log.Fatalf("Evaluation failed: %v", err)
+	}
+
+	if err := writeReports(report, *jsonOut, *mdOut); err != nil {
+		log.Fatalf("Failed to write report: %v", err)
+	}
+
+	a := report.Aggregate
+	fmt.Printf("%d cases, %d errors, length OK %.0f%%, ROUGE-L F1 %.3f, faithfulness %.3f\n",
+		a.Cases, a.Errors, a.LengthComplianceRate*100, a.MeanRougeLF1, a.MeanFaithfulness)
+}
+
+// newProvider creates the summarizer named on the command line; only the LLM
+// providers need a valid configuration
+func newProvider(name string, cfg *config.Config) (llm.Summarizer, error) {
+	switch name {
+	case "openai", "local":
+		cfg.LLM.Provider = name
+		if err := cfg.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	switch name {
+	case "openai":
+		return llm.NewClient(cfg.LLM, cfg.HTTPClient())
+	case "local":
+		return llm.NewLocalClient(cfg.LLM, cfg.HTTPClient())
+	case "extractive":
+		return llm.Extractive{}, nil
+	case "lead":
+		return leadSummarizer{}, nil
+	default:
+		return nil, fmt.Errorf("unknown provider %q", name)
+	}
+}
+
+// writeReports writes the JSON report and, if mdPath is set, the Markdown one
+func writeReports(report *eval.Report, jsonPath, mdPath string) error {
+	f, err := os.Create(jsonPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := report.WriteJSON(f); err != nil {
+		return err
+	}
+
+	if mdPath == "" {
+		return nil
+	}
+	md, err := os.Create(mdPath)
+	if err != nil {
+		return err
+	}
+	defer md.Close()
+	return report.WriteMarkdown(md)
+}
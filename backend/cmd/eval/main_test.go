@@ -0,0 +1,43 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEvalRun(t *testing.T) {
+	cases, err := loadCorpus("testdata/corpus")
+	require.NoError(t, err)
+	require.Len(t, cases, 3)
+	assert.NotEmpty(t, cases[0].Reference.Headline)
+
+	report, err := run(leadSummarizer{}, cases, runOptions{Provider: "lead", Style: "headline"})
+	require.NoError(t, err)
+
+	assert.Equal(t, 3, report.Aggregate.Cases)
+	assert.Zero(t, report.Aggregate.Errors)
+	assert.Equal(t, 1.0, report.Aggregate.LengthComplianceRate)
+	assert.Equal(t, 1.0, report.Aggregate.BulletComplianceRate)
+	// Lead sentences are copied from the article; only words cut by
+	// truncation can count as unsupported
+	assert.Greater(t, report.Aggregate.MeanFaithfulness, 0.8)
+	assert.Greater(t, report.Aggregate.MeanRouge1F1, 0.0)
+
+	dir := t.TempDir()
+	jsonPath, mdPath := filepath.Join(dir, "report.json"), filepath.Join(dir, "report.md")
+	require.NoError(t, writeReports(report, jsonPath, mdPath))
+
+	md, err := os.ReadFile(mdPath)
+	require.NoError(t, err)
+	assert.Contains(t, string(md), "| harbor-expansion |")
+	assert.FileExists(t, jsonPath)
+}
+
+func TestEvalRun_UnknownStyle(t *testing.T) {
+	_, err := run(leadSummarizer{}, nil, runOptions{Style: "sonnet"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,211 @@
+// Package breaker implements circuit breakers that fail fast while an
+// upstream dependency is down, instead of letting every request wait out
+// its timeout
+package breaker
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// State is the position of a circuit breaker
+type State int
+
+const (
+	// Closed lets calls through and counts consecutive failures
+	Closed State = iota
+	// HalfOpen lets a single probe call through to test recovery
+	HalfOpen
+	// Open rejects calls until the open timeout passes
+	Open
+)
+
+func (s State) String() string {
+	switch s {
+	case Closed:
+		return "closed"
+	case HalfOpen:
+		return "half_open"
+	case Open:
+		return "open"
+	}
+	return fmt.Sprintf("State(%d)", int(s))
+}
+
+// MarshalText renders the state by name in JSON
+func (s State) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// ErrOpen is matched by errors.Is for every call rejected by an open breaker
+var ErrOpen = errors.New("circuit breaker is open")
+
+// OpenError is returned when a breaker rejects a call
+type OpenError struct {
+	Name    string
+	RetryIn time.Duration // until the breaker lets a probe through
+}
+
+func (e *OpenError) Error() string {
+	return fmt.Sprintf("%s: circuit breaker is open, retry in %s", e.Name, e.RetryIn.Round(time.Second))
+}
+
+func (e *OpenError) Is(target error) bool {
+	return target == ErrOpen
+}
+
+// Config tunes a breaker
+type Config struct {
+	FailureThreshold int           // consecutive failures that open the breaker
+	OpenTimeout      time.Duration // how long to stay open before probing
+
+	// IsFailure decides which errors count against the upstream; nil counts
+	// every non-nil error. Caller mistakes like bad input shouldn't trip it.
+	IsFailure func(error) bool
+
+	// OnStateChange, when set, is called after every transition, with no
+	// lock held so it may call back into the breaker
+	OnStateChange func(name string, from, to State)
+}
+
+// DefaultConfig opens after 5 consecutive failures and probes after 30s
+var DefaultConfig = Config{FailureThreshold: 5, OpenTimeout: 30 * time.Second}
+
+// Breaker guards calls to one upstream
+type Breaker struct {
+	name string
+	cfg  Config
+	now  func() time.Time
+
+	mu       sync.Mutex
+	state    State
+	failures int
+	openedAt time.Time
+	probing  bool
+	changes  []transition // made under mu, reported by unlock
+}
+
+// transition is a state change waiting to be reported
+type transition struct{ from, to State }
+
+// New creates a closed breaker
+func New(name string, cfg Config) *Breaker {
+	if cfg.FailureThreshold <= 0 {
+		cfg.FailureThreshold = DefaultConfig.FailureThreshold
+	}
+	if cfg.OpenTimeout <= 0 {
+		cfg.OpenTimeout = DefaultConfig.OpenTimeout
+	}
+	return &Breaker{name: name, cfg: cfg, now: time.Now}
+}
+
+// Name identifies the guarded upstream
+func (b *Breaker) Name() string {
+	return b.name
+}
+
+// State returns the current state, moving an open breaker whose timeout
+// has passed to half-open
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.unlock()
+	return b.currentState()
+}
+
+// currentState applies the open timeout; b.mu must be held
+func (b *Breaker) currentState() State {
+	if b.state == Open && b.now().Sub(b.openedAt) >= b.cfg.OpenTimeout {
+		b.setState(HalfOpen)
+	}
+	return b.state
+}
+
+// unlock releases b.mu and then reports the transitions made while it was
+// held, so OnStateChange can't deadlock by reading the breaker's state
+func (b *Breaker) unlock() {
+	changes := b.changes
+	b.changes = nil
+	b.mu.Unlock()
+	if b.cfg.OnStateChange != nil {
+		for _, c := range changes {
+			b.cfg.OnStateChange(b.name, c.from, c.to)
+		}
+	}
+}
+
+// setState makes a transition, queueing it for unlock to report; b.mu must
+// be held
+func (b *Breaker) setState(to State) {
+	from := b.state
+	if from == to {
+		return
+	}
+	b.state = to
+	switch to {
+	case Open:
+		b.openedAt = b.now()
+	case Closed:
+		b.failures = 0
+	}
+	b.probing = false
+	b.changes = append(b.changes, transition{from, to})
+}
+
+// Allow reports whether a call may proceed. Every allowed call must be
+// followed by exactly one Record.
+func (b *Breaker) Allow() error {
+	b.mu.Lock()
+	defer b.unlock()
+
+	switch b.currentState() {
+	case Open:
+		return &OpenError{Name: b.name, RetryIn: b.cfg.OpenTimeout - b.now().Sub(b.openedAt)}
+	case HalfOpen:
+		if b.probing {
+			return &OpenError{Name: b.name, RetryIn: 0}
+		}
+		b.probing = true
+	}
+	return nil
+}
+
+// Record reports the outcome of an allowed call
+func (b *Breaker) Record(err error) {
+	failed := err != nil
+	if failed && b.cfg.IsFailure != nil {
+		failed = b.cfg.IsFailure(err)
+	}
+
+	b.mu.Lock()
+	defer b.unlock()
+
+	switch b.state {
+	case HalfOpen:
+		if failed {
+			b.setState(Open)
+		} else {
+			b.setState(Closed)
+		}
+	case Closed:
+		if !failed {
+			b.failures = 0
+			return
+		}
+		b.failures++
+		if b.failures >= b.cfg.FailureThreshold {
+			b.setState(Open)
+		}
+	}
+}
+
+// Do runs fn if the breaker allows it and records the result
+func (b *Breaker) Do(fn func() error) error {
+	if err := b.Allow(); err != nil {
+		return err
+	}
+	err := fn()
+	b.Record(err)
+	return err
+}
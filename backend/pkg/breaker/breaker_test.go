@@ -0,0 +1,129 @@
+package breaker
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errUpstream = errors.New("upstream down")
+
+// newTestBreaker returns a breaker on a controllable clock
+func newTestBreaker(cfg Config) (*Breaker, *time.Time) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	b := New("test", cfg)
+	b.now = func() time.Time { return now }
+	return b, &now
+}
+
+func TestBreaker_Lifecycle(t *testing.T) {
+	var transitions []string
+	b, now := newTestBreaker(Config{
+		FailureThreshold: 3,
+		OpenTimeout:      10 * time.Second,
+		OnStateChange: func(name string, from, to State) {
+			transitions = append(transitions, fmt.Sprintf("%s->%s", from, to))
+		},
+	})
+
+	// Failures below the threshold, or interrupted by a success, keep it closed
+	b.Do(func() error { return errUpstream })
+	b.Do(func() error { return errUpstream })
+	b.Do(func() error { return nil })
+	b.Do(func() error { return errUpstream })
+	b.Do(func() error { return errUpstream })
+	assert.Equal(t, Closed, b.State())
+
+	// The third consecutive failure opens it and calls fail fast
+	b.Do(func() error { return errUpstream })
+	assert.Equal(t, Open, b.State())
+	called := false
+	err := b.Do(func() error { called = true; return nil })
+	assert.False(t, called)
+	assert.ErrorIs(t, err, ErrOpen)
+	var openErr *OpenError
+	require.ErrorAs(t, err, &openErr)
+	assert.Equal(t, 10*time.Second, openErr.RetryIn)
+
+	// After the timeout one probe goes through; a failed probe reopens it
+	*now = now.Add(10 * time.Second)
+	assert.Equal(t, HalfOpen, b.State())
+	require.NoError(t, b.Allow())
+	assert.ErrorIs(t, b.Allow(), ErrOpen, "only one probe at a time")
+	b.Record(errUpstream)
+	assert.Equal(t, Open, b.State())
+
+	// A successful probe closes it
+	*now = now.Add(10 * time.Second)
+	require.NoError(t, b.Do(func() error { return nil }))
+	assert.Equal(t, Closed, b.State())
+
+	assert.Equal(t, []string{
+		"closed->open", "open->half_open", "half_open->open", "open->half_open", "half_open->closed",
+	}, transitions)
+}
+
+func TestBreaker_IsFailure(t *testing.T) {
+	errBadInput := errors.New("bad input")
+	b, _ := newTestBreaker(Config{
+		FailureThreshold: 1,
+		IsFailure:        func(err error) bool { return !errors.Is(err, errBadInput) },
+	})
+
+	b.Do(func() error { return errBadInput })
+	assert.Equal(t, Closed, b.State())
+	b.Do(func() error { return errUpstream })
+	assert.Equal(t, Open, b.State())
+}
+
+func TestBreaker_CallbackReadsState(t *testing.T) {
+	var b *Breaker
+	var seen []State
+	b = New("api", Config{FailureThreshold: 1, OpenTimeout: time.Minute, OnStateChange: func(name string, from, to State) {
+		seen = append(seen, b.State())
+	}})
+
+	done := make(chan struct{})
+	go func() {
+		b.Record(errUpstream)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Record deadlocked in OnStateChange")
+	}
+	assert.Equal(t, []State{Open}, seen)
+}
+
+func TestSet(t *testing.T) {
+	var opened []string
+	s := NewSet(Config{FailureThreshold: 1, OpenTimeout: time.Minute})
+	s.OnStateChange(func(name string, from, to State) {
+		if to == Open {
+			opened = append(opened, name)
+		}
+	})
+
+	assert.Same(t, s.Get("a.example"), s.Get("a.example"))
+	s.Get("b.example").Record(errUpstream)
+	s.Get("a.example").Record(nil)
+
+	assert.Equal(t, map[string]State{"b.example": Open}, s.States())
+	assert.Equal(t, []string{"b.example"}, s.Open())
+	assert.Equal(t, []string{"b.example"}, opened)
+}
+
+func TestSet_PrunesIdleBreakers(t *testing.T) {
+	s := NewSet(Config{FailureThreshold: 1})
+	s.Get("down.example").Record(errUpstream)
+	for i := 0; i < maxIdleBreakers+10; i++ {
+		s.Get(fmt.Sprintf("host%d.example", i))
+	}
+	assert.LessOrEqual(t, len(s.breakers), maxIdleBreakers)
+	assert.Equal(t, Open, s.Get("down.example").State(), "breakers with failures are kept")
+}
@@ -0,0 +1,104 @@
+package breaker
+
+import (
+	"sort"
+	"sync"
+)
+
+// maxIdleBreakers is how many breakers a Set keeps before forgetting healthy
+// ones, so a stream of one-off hosts can't grow it without bound
+const maxIdleBreakers = 1000
+
+// Set holds one breaker per key, such as an origin host, sharing a config
+type Set struct {
+	cfg Config
+
+	mu       sync.Mutex
+	breakers map[string]*Breaker
+
+	// hookMu guards onChange apart from mu, so notify never waits on Get
+	// or pruneLocked, which take breaker locks under mu
+	hookMu   sync.Mutex
+	onChange func(name string, from, to State)
+}
+
+// NewSet creates an empty set whose breakers use cfg
+func NewSet(cfg Config) *Set {
+	s := &Set{cfg: cfg, breakers: make(map[string]*Breaker), onChange: cfg.OnStateChange}
+	s.cfg.OnStateChange = s.notify
+	return s
+}
+
+// OnStateChange replaces the transition callback of every breaker in the
+// set, for packages that create their set before the server wires up metrics
+func (s *Set) OnStateChange(fn func(name string, from, to State)) {
+	s.hookMu.Lock()
+	defer s.hookMu.Unlock()
+	s.onChange = fn
+}
+
+func (s *Set) notify(name string, from, to State) {
+	s.hookMu.Lock()
+	fn := s.onChange
+	s.hookMu.Unlock()
+	if fn != nil {
+		fn(name, from, to)
+	}
+}
+
+// Get returns the breaker for key, creating it on first use
+func (s *Set) Get(key string) *Breaker {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if b, ok := s.breakers[key]; ok {
+		return b
+	}
+	if len(s.breakers) >= maxIdleBreakers {
+		s.pruneLocked()
+	}
+	b := New(key, s.cfg)
+	s.breakers[key] = b
+	return b
+}
+
+// pruneLocked drops closed breakers with no recent failures; s.mu must be held
+func (s *Set) pruneLocked() {
+	for key, b := range s.breakers {
+		b.mu.Lock()
+		idle := b.state == Closed && b.failures == 0
+		b.mu.Unlock()
+		if idle {
+			delete(s.breakers, key)
+		}
+	}
+}
+
+// States returns the state of every breaker that isn't closed, keyed by name
+func (s *Set) States() map[string]State {
+	s.mu.Lock()
+	breakers := make([]*Breaker, 0, len(s.breakers))
+	for _, b := range s.breakers {
+		breakers = append(breakers, b)
+	}
+	s.mu.Unlock()
+
+	out := make(map[string]State)
+	for _, b := range breakers {
+		if st := b.State(); st != Closed {
+			out[b.Name()] = st
+		}
+	}
+	return out
+}
+
+// Open lists the keys whose breakers are open, sorted
+func (s *Set) Open() []string {
+	var out []string
+	for name, st := range s.States() {
+		if st == Open {
+			out = append(out, name)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,76 @@
+// Package cite links summary bullets back to the sentences they came from
+// using Text Fragment URLs (#:~:text=)
+package cite
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/matthewmolinar/tldr/pkg/extract"
+	"github.com/matthewmolinar/tldr/pkg/redact"
+	"github.com/matthewmolinar/tldr/pkg/verify"
+)
+
+// fragmentWords is how many words of a long quote go into each of the
+// fragment's start and end terms. Quotes up to twice this are linked whole.
+const fragmentWords = 4
+
+// Citation points a bullet at its supporting sentence in the original article
+type Citation struct {
+	Quote string `json:"quote,omitempty"`
+	URL   string `json:"url,omitempty"`
+}
+
+// Bullets builds one citation per bullet from its verification result, in
+// bullet order, linking each quote to the article page it appears on.
+// Unsupported bullets get an empty citation, as do quotes that no page shows
+// as written: those with redaction masks, or cut across a page boundary.
+func Bullets(pages []extract.Page, checks []verify.BulletSupport) []Citation {
+	texts := make([]string, len(pages))
+	for i, p := range pages {
+		texts[i] = strings.Join(strings.Fields(p.Text), " ")
+	}
+
+	out := make([]Citation, len(checks))
+	for i, c := range checks {
+		if !c.Supported || c.Source == "" || redact.Masked(c.Source) {
+			continue
+		}
+		for j, text := range texts {
+			if strings.Contains(text, c.Source) {
+				out[i] = Citation{Quote: c.Source, URL: Link(pages[j].URL, c.Source)}
+				break
+			}
+		}
+	}
+	return out
+}
+
+// Link returns pageURL with a Text Fragment that highlights quote. Long quotes
+// use a textStart,textEnd range so small extraction differences in the middle
+// of the sentence don't break the match. Any existing fragment is replaced.
+func Link(pageURL, quote string) string {
+	words := strings.Fields(quote)
+	if len(words) == 0 {
+		return pageURL
+	}
+
+	directive := encode(strings.Join(words, " "))
+	if len(words) > 2*fragmentWords {
+		start := strings.Join(words[:fragmentWords], " ")
+		end := strings.Join(words[len(words)-fragmentWords:], " ")
+		directive = encode(start) + "," + encode(end)
+	}
+
+	base, _, _ := strings.Cut(pageURL, "#")
+	return base + "#:~:text=" + directive
+}
+
+// encode percent-encodes a fragment term. Besides the usual reserved
+// characters, the spec requires '-', ',' and '&' to be escaped because they
+// delimit prefix, suffix and range terms and separate directives.
+func encode(term string) string {
+	s := url.QueryEscape(term)
+	s = strings.ReplaceAll(s, "+", "%20")
+	return strings.ReplaceAll(s, "-", "%2D")
+}
@@ -0,0 +1,86 @@
+package cite
+
+import (
+	"testing"
+
+	"github.com/matthewmolinar/tldr/pkg/extract"
+	"github.com/matthewmolinar/tldr/pkg/verify"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLink(t *testing.T) {
+	tests := []struct {
+		name  string
+		url   string
+		quote string
+		want  string
+	}{
+		{
+			name:  "short quote is linked whole",
+			url:   "https://example.com/news/rates",
+			quote: "Rates held at 4.25 percent.",
+			want:  "https://example.com/news/rates#:~:text=Rates%20held%20at%204.25%20percent.",
+		},
+		{
+			name:  "long quote uses a start and end range",
+			url:   "https://example.com/news/rates",
+			quote: "The central bank kept its benchmark interest rate unchanged at 4.25 percent on Thursday.",
+			want:  "https://example.com/news/rates#:~:text=The%20central%20bank%20kept,4.25%20percent%20on%20Thursday.",
+		},
+		{
+			name:  "delimiters are escaped",
+			url:   "https://example.com/a",
+			quote: "Year-on-year, costs & fees rose",
+			want:  "https://example.com/a#:~:text=Year%2Don%2Dyear%2C%20costs%20%26%20fees%20rose",
+		},
+		{
+			name:  "existing fragment is replaced",
+			url:   "https://example.com/a?page=2#comments",
+			quote: "Hello world",
+			want:  "https://example.com/a?page=2#:~:text=Hello%20world",
+		},
+		{
+			name:  "non-ASCII text is percent-encoded",
+			url:   "https://example.jp/a",
+			quote: "東京で会議",
+			want:  "https://example.jp/a#:~:text=%E6%9D%B1%E4%BA%AC%E3%81%A7%E4%BC%9A%E8%AD%B0",
+		},
+		{
+			name:  "empty quote leaves URL alone",
+			url:   "https://example.com/a",
+			quote: "  ",
+			want:  "https://example.com/a",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Link(tt.url, tt.quote))
+		})
+	}
+}
+
+func TestBullets(t *testing.T) {
+	pages := []extract.Page{
+		{URL: "https://example.com/rates", Text: "Rates held at 4.25 percent.\nInflation  eased to 2.4 percent.\nWrite to desk@example.com with tips."},
+		{URL: "https://example.com/rates?page=2", Text: "Markets rallied after the decision."},
+	}
+	checks := []verify.BulletSupport{
+		{Score: 0.9, Source: "Rates held at 4.25 percent.", Supported: true},
+		{Score: 0, Supported: false},
+		{Score: 0.3, Source: "Inflation eased to 2.4 percent.", Supported: false},
+		{Score: 0.8, Source: "Markets rallied after the decision.", Supported: true},
+		{Score: 0.7, Source: "Write to [EMAIL] with tips.", Supported: true},
+		{Score: 0.7, Source: "Rates held at 4.25 percent. Markets rallied after the decision.", Supported: true},
+	}
+
+	got := Bullets(pages, checks)
+	assert.Equal(t, []Citation{
+		{Quote: "Rates held at 4.25 percent.", URL: "https://example.com/rates#:~:text=Rates%20held%20at%204.25%20percent."},
+		{},
+		{}, // below the support threshold
+		{Quote: "Markets rallied after the decision.", URL: "https://example.com/rates?page=2#:~:text=Markets%20rallied%20after%20the%20decision."},
+		{}, // the page shows the address, not the mask
+		{}, // spans two pages
+	}, got)
+}
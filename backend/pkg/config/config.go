@@ -0,0 +1,287 @@
+// Package config loads the service's settings. Defaults are overridden by an
+// optional YAML file named by CONFIG_FILE, which is overridden by the
+// environment, including variables from a .env file. Every package receives
+// its section explicitly; nothing else reads the environment.
+package config
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the complete service configuration
+type Config struct {
+	Server    Server    `yaml:"server"`
+	Security  Security  `yaml:"security"`
+	LLM       LLM       `yaml:"llm"`
+	Fetch     Fetch     `yaml:"fetch"`
+	Summaries Summaries `yaml:"summaries"`
+	Hedge     Hedge     `yaml:"hedge"`
+
+	Experiment Experiment `yaml:"experiment"`
+	Health     Health     `yaml:"health"`
+	Tracing    Tracing    `yaml:"tracing"`
+
+	// InsecureSkipVerify disables TLS certificate verification for outbound
+	// calls. It defaults to on when running on Fly (FLY_APP_NAME is set),
+	// whose containers lack a CA bundle.
+	InsecureSkipVerify bool `yaml:"insecure_skip_verify" env:"INSECURE_SKIP_VERIFY"`
+}
+
+// Server configures the HTTP listener
+type Server struct {
+	Port         int           `yaml:"port" env:"PORT"`
+	ReadTimeout  time.Duration `yaml:"read_timeout" env:"READ_TIMEOUT"`
+	WriteTimeout time.Duration `yaml:"write_timeout" env:"WRITE_TIMEOUT"`
+	CORSOrigins  []string      `yaml:"cors_origins" env:"CORS_ORIGINS"`
+
+	// On SIGINT or SIGTERM the server fails health checks and keeps serving
+	// for DrainDelay, then stops accepting connections and waits up to
+	// DrainTimeout for in-flight requests
+	DrainDelay   time.Duration `yaml:"drain_delay" env:"DRAIN_DELAY"`
+	DrainTimeout time.Duration `yaml:"drain_timeout" env:"DRAIN_TIMEOUT"`
+
+	// AdminToken is the bearer token required by /metrics, /api/usage and
+	// the experiment endpoints; without one they are disabled
+	AdminToken string `yaml:"admin_token" env:"ADMIN_TOKEN" secret:"true"`
+}
+
+// Security configures the browser security headers of every response
+type Security struct {
+	HSTSMaxAge                time.Duration `yaml:"hsts_max_age" env:"HSTS_MAX_AGE"`                                 // zero omits the header
+	ContentSecurityPolicy     string        `yaml:"content_security_policy" env:"CONTENT_SECURITY_POLICY"`           // API responses
+	PageContentSecurityPolicy string        `yaml:"page_content_security_policy" env:"PAGE_CONTENT_SECURITY_POLICY"` // HTML pages
+	ReferrerPolicy            string        `yaml:"referrer_policy" env:"REFERRER_POLICY"`
+}
+
+// LLM configures the summary providers
+type LLM struct {
+	Provider  string        `yaml:"provider" env:"LLM_PROVIDER"` // openai or local
+	APIKey    string        `yaml:"api_key" env:"OPENAI_API_KEY" secret:"true"`
+	PromptDir string        `yaml:"prompt_dir" env:"PROMPT_DIR"`
+	Timeout   time.Duration `yaml:"timeout" env:"LLM_TIMEOUT"` // per request, retries included
+
+	Model             string   `yaml:"model" env:"LLM_MODEL"`
+	Temperature       float32  `yaml:"temperature" env:"LLM_TEMPERATURE"`
+	TopP              float32  `yaml:"top_p" env:"LLM_TOP_P"`
+	MaxTokens         int      `yaml:"max_tokens" env:"LLM_MAX_TOKENS"`
+	AllowedModels     []string `yaml:"allowed_models" env:"LLM_ALLOWED_MODELS"`
+	LongContextModels []string `yaml:"long_context_models" env:"LLM_LONG_CONTEXT_MODELS"`
+
+	Local LocalLLM `yaml:"local"`
+}
+
+// LocalLLM configures a self-hosted OpenAI-compatible model server
+type LocalLLM struct {
+	BaseURL       string `yaml:"base_url" env:"LOCAL_LLM_BASE_URL"`
+	Model         string `yaml:"model" env:"LOCAL_LLM_MODEL"`
+	APIKey        string `yaml:"api_key" env:"LOCAL_LLM_API_KEY" secret:"true"`
+	Auth          string `yaml:"auth" env:"LOCAL_LLM_AUTH"` // bearer, none or header:<name>
+	ContextTokens int    `yaml:"context_tokens" env:"LOCAL_LLM_CONTEXT_TOKENS"`
+}
+
+// Fetch configures article download and extraction
+type Fetch struct {
+	MaxArticleBytes  int   `yaml:"max_article_bytes" env:"MAX_ARTICLE_BYTES"`   // extracted text sent to the LLM; more for long-context models
+	MaxContentLength int64 `yaml:"max_content_length" env:"MAX_CONTENT_LENGTH"` // largest page checked by HEAD or downloaded
+}
+
+// Summaries toggles the optional summary pipeline stages
+type Summaries struct {
+	RedactPII          bool    `yaml:"redact_pii" env:"REDACT_PII"`
+	VerifyRegenerate   bool    `yaml:"verify_regenerate" env:"VERIFY_REGENERATE"`
+	ExtractiveFallback bool    `yaml:"extractive_fallback" env:"EXTRACTIVE_FALLBACK"`
+	DailySpendCapUSD   float64 `yaml:"daily_spend_cap_usd" env:"DAILY_SPEND_CAP_USD"` // zero means no cap
+}
+
+// Hedge configures hedged LLM requests
+type Hedge struct {
+	Enabled    bool    `yaml:"enabled" env:"HEDGE_REQUESTS"`
+	Percentile float64 `yaml:"percentile" env:"HEDGE_PERCENTILE"`
+	Provider   string  `yaml:"provider" env:"HEDGE_PROVIDER"` // empty hedges on the primary
+}
+
+// Experiment configures the A/B experiment
+type Experiment struct {
+	File string `yaml:"file" env:"EXPERIMENT_FILE"`
+	Log  string `yaml:"log" env:"EXPERIMENT_LOG"`
+}
+
+// Health configures the readiness checks
+type Health struct {
+	ProbeInterval time.Duration `yaml:"probe_interval" env:"HEALTH_PROBE_INTERVAL"` // how long a provider probe result is reused
+	ProbeTimeout  time.Duration `yaml:"probe_timeout" env:"HEALTH_PROBE_TIMEOUT"`
+}
+
+// Tracing configures span export to an OpenTelemetry collector
+type Tracing struct {
+	Endpoint    string  `yaml:"endpoint" env:"OTEL_EXPORTER_OTLP_ENDPOINT"` // OTLP/HTTP base URL; empty disables export
+	ServiceName string  `yaml:"service_name" env:"OTEL_SERVICE_NAME"`
+	SampleRatio float64 `yaml:"sample_ratio" env:"TRACE_SAMPLE_RATIO"` // share of new traces kept
+}
+
+// Default returns the configuration used when nothing is set
+func Default() *Config {
+	return &Config{
+		Server: Server{
+			Port:         8080,
+			ReadTimeout:  5 * time.Second,
+			WriteTimeout: 5 * time.Second,
+			CORSOrigins:  []string{"https://web-tldr.vercel.app"},
+			DrainTimeout: 35 * time.Second, // outlasts a summary's LLM timeout
+		},
+		Security: Security{
+			HSTSMaxAge:            180 * 24 * time.Hour,
+			ContentSecurityPolicy: "default-src 'none'; frame-ancestors 'none'",
+			PageContentSecurityPolicy: "default-src 'self'; img-src 'self' https: data:; style-src 'self' 'unsafe-inline'; " +
+				"frame-ancestors 'none'; base-uri 'none'; form-action 'self'",
+			ReferrerPolicy: "strict-origin-when-cross-origin",
+		},
+		LLM: LLM{
+			Provider:    "openai",
+			Timeout:     30 * time.Second,
+			Model:       "gpt-3.5-turbo",
+			Temperature: 0.5,
+		},
+		Fetch: Fetch{
+			MaxArticleBytes:  8192,             // 8 KB limit as per PRD
+			MaxContentLength: 10 * 1024 * 1024, // 10 MB
+		},
+		Hedge: Hedge{
+			Percentile: 0.95,
+		},
+		Experiment: Experiment{
+			Log: "experiments.jsonl",
+		},
+		Health: Health{
+			ProbeInterval: time.Minute,
+			ProbeTimeout:  5 * time.Second,
+		},
+		Tracing: Tracing{
+			ServiceName: "tldr-api",
+			SampleRatio: 1,
+		},
+	}
+}
+
+// Load reads the configuration like Read and validates it
+func Load(path string) (*Config, error) {
+	cfg, err := Read(path)
+	if err != nil {
+		return nil, err
+	}
+	return cfg, cfg.Validate()
+}
+
+// Read loads .env into the environment, then builds the configuration from
+// the defaults, the YAML file at path (CONFIG_FILE when path is empty), and
+// the environment, without validating it
+func Read(path string) (*Config, error) {
+	if err := godotenv.Load(); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("loading .env: %w", err)
+	}
+	if path == "" {
+		path = os.Getenv("CONFIG_FILE")
+	}
+
+	cfg := Default()
+	_, cfg.InsecureSkipVerify = os.LookupEnv("FLY_APP_NAME")
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("reading config file: %w", err)
+		}
+		if err := yaml.Unmarshal(data, cfg); err != nil {
+			return nil, fmt.Errorf("parsing config file %s: %w", path, err)
+		}
+	}
+	if err := applyEnv(cfg, os.LookupEnv); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Validate reports every invalid setting at once
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.Server.Port > 0 && c.Server.Port < 65536, "server.port must be between 1 and 65535, got %d", c.Server.Port)
+	check(c.Server.ReadTimeout > 0, "server.read_timeout must be positive")
+	check(c.Server.WriteTimeout > 0, "server.write_timeout must be positive")
+	check(c.Server.DrainDelay >= 0, "server.drain_delay must not be negative")
+	check(c.Server.DrainTimeout > 0, "server.drain_timeout must be positive")
+	check(len(c.Server.CORSOrigins) > 0, "server.cors_origins must list at least one origin")
+	check(c.Security.HSTSMaxAge >= 0, "security.hsts_max_age must not be negative")
+
+	switch c.LLM.Provider {
+	case "openai":
+		check(c.LLM.APIKey != "", "llm.api_key (OPENAI_API_KEY) is required for provider openai")
+	case "local":
+		check(c.LLM.Local.BaseURL != "", "llm.local.base_url (LOCAL_LLM_BASE_URL) is required for provider local")
+	default:
+		errs = append(errs, fmt.Errorf("llm.provider must be openai or local, got %q", c.LLM.Provider))
+	}
+	check(c.LLM.Timeout > 0, "llm.timeout must be positive")
+	check(c.LLM.Model != "", "llm.model must not be empty")
+	check(c.LLM.Temperature >= 0 && c.LLM.Temperature <= 2, "llm.temperature must be between 0 and 2, got %g", c.LLM.Temperature)
+	check(c.LLM.TopP >= 0 && c.LLM.TopP <= 1, "llm.top_p must be between 0 and 1, got %g", c.LLM.TopP)
+	check(c.LLM.MaxTokens >= 0, "llm.max_tokens must not be negative, got %d", c.LLM.MaxTokens)
+
+	if local := c.LLM.Local; local.BaseURL != "" {
+		check(strings.HasPrefix(local.BaseURL, "http://") || strings.HasPrefix(local.BaseURL, "https://"),
+			"llm.local.base_url must be an http(s) URL, got %q", local.BaseURL)
+		check(local.Model != "", "llm.local.model (LOCAL_LLM_MODEL) is required with a base URL")
+		check(local.ContextTokens >= 0, "llm.local.context_tokens must not be negative, got %d", local.ContextTokens)
+		auth := strings.ToLower(local.Auth)
+		check(auth == "" || auth == "bearer" || auth == "none" || strings.HasPrefix(auth, "header:"),
+			"llm.local.auth must be bearer, none or header:<name>, got %q", local.Auth)
+	}
+
+	check(c.Fetch.MaxArticleBytes > 0, "fetch.max_article_bytes must be positive, got %d", c.Fetch.MaxArticleBytes)
+	check(c.Fetch.MaxContentLength > 0, "fetch.max_content_length must be positive, got %d", c.Fetch.MaxContentLength)
+	check(c.Summaries.DailySpendCapUSD >= 0, "summaries.daily_spend_cap_usd must not be negative, got %g", c.Summaries.DailySpendCapUSD)
+	check(c.Hedge.Percentile > 0 && c.Hedge.Percentile < 1, "hedge.percentile must be between 0 and 1 exclusive, got %g", c.Hedge.Percentile)
+
+	check(c.Health.ProbeInterval >= 0, "health.probe_interval must not be negative")
+	check(c.Health.ProbeTimeout > 0, "health.probe_timeout must be positive")
+
+	if c.Tracing.Endpoint != "" {
+		check(strings.HasPrefix(c.Tracing.Endpoint, "http://") || strings.HasPrefix(c.Tracing.Endpoint, "https://"),
+			"tracing.endpoint must be an http(s) URL, got %q", c.Tracing.Endpoint)
+		check(c.Tracing.ServiceName != "", "tracing.service_name must not be empty")
+	}
+	check(c.Tracing.SampleRatio >= 0 && c.Tracing.SampleRatio <= 1, "tracing.sample_ratio must be between 0 and 1, got %g", c.Tracing.SampleRatio)
+
+	return errors.Join(errs...)
+}
+
+// Addr is the address the server listens on
+func (s Server) Addr() string {
+	return ":" + strconv.Itoa(s.Port)
+}
+
+// HTTPClient returns the client for outbound calls, honoring InsecureSkipVerify
+func (c *Config) HTTPClient() *http.Client {
+	if !c.InsecureSkipVerify {
+		return http.DefaultClient
+	}
+	return &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		},
+	}
+}
@@ -0,0 +1,172 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/joho/godotenv"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+// mapEnv looks variables up in a map instead of the process environment
+func mapEnv(vars map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		v, ok := vars[name]
+		return v, ok
+	}
+}
+
+func writeFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestDefault(t *testing.T) {
+	cfg := Default()
+	assert.ErrorContains(t, cfg.Validate(), "OPENAI_API_KEY")
+
+	cfg.LLM.APIKey = "sk-test"
+	require.NoError(t, cfg.Validate())
+	assert.Equal(t, ":8080", cfg.Server.Addr())
+	assert.Equal(t, 30*time.Second, cfg.LLM.Timeout)
+}
+
+func TestApplyEnv(t *testing.T) {
+	cfg := Default()
+	err := applyEnv(cfg, mapEnv(map[string]string{
+		"PORT":                "9090",
+		"READ_TIMEOUT":        "2s",
+		"CORS_ORIGINS":        "https://a.example, https://b.example,",
+		"LLM_TEMPERATURE":     "0.2",
+		"REDACT_PII":          "true",
+		"DAILY_SPEND_CAP_USD": "12.5",
+		"MAX_CONTENT_LENGTH":  "1024",
+		"LOCAL_LLM_MODEL":     "llama3",
+		"EXPERIMENT_LOG":      "",
+	}))
+	require.NoError(t, err)
+
+	assert.Equal(t, 9090, cfg.Server.Port)
+	assert.Equal(t, 2*time.Second, cfg.Server.ReadTimeout)
+	assert.Equal(t, 5*time.Second, cfg.Server.WriteTimeout, "unset variables keep the default")
+	assert.Equal(t, []string{"https://a.example", "https://b.example"}, cfg.Server.CORSOrigins)
+	assert.Equal(t, float32(0.2), cfg.LLM.Temperature)
+	assert.True(t, cfg.Summaries.RedactPII)
+	assert.Equal(t, 12.5, cfg.Summaries.DailySpendCapUSD)
+	assert.Equal(t, int64(1024), cfg.Fetch.MaxContentLength)
+	assert.Equal(t, "llama3", cfg.LLM.Local.Model)
+	assert.Equal(t, "experiments.jsonl", cfg.Experiment.Log, "an empty variable keeps the default")
+
+	for name, value := range map[string]string{
+		"PORT":            "eighty",
+		"LLM_TIMEOUT":     "30",
+		"REDACT_PII":      "maybe",
+		"LLM_TEMPERATURE": "warm",
+	} {
+		err := applyEnv(Default(), mapEnv(map[string]string{name: value}))
+		assert.ErrorContains(t, err, name)
+	}
+}
+
+func TestEnvSample(t *testing.T) {
+	// The documented setup copies .env.sample to .env unchanged
+	vars, err := godotenv.Read("../../.env.sample")
+	require.NoError(t, err)
+	cfg := Default()
+	require.NoError(t, applyEnv(cfg, mapEnv(vars)))
+	assert.NoError(t, cfg.Validate())
+	assert.Equal(t, Default().Server.ReadTimeout, cfg.Server.ReadTimeout)
+
+	// Every variable set blank, as in older copies of the sample, keeps the
+	// defaults too
+	blank := map[string]string{}
+	walk(reflect.ValueOf(Default()).Elem(), func(f reflect.StructField, _ reflect.Value) error {
+		if name := f.Tag.Get("env"); name != "" {
+			blank[name] = ""
+		}
+		return nil
+	})
+	blank["OPENAI_API_KEY"] = "sk-test"
+	cfg = Default()
+	require.NoError(t, applyEnv(cfg, mapEnv(blank)))
+	assert.NoError(t, cfg.Validate())
+	assert.Equal(t, Default().Server.CORSOrigins, cfg.Server.CORSOrigins)
+}
+
+func TestLoad(t *testing.T) {
+	path := writeFile(t, `
+server:
+  port: 3000
+  read_timeout: 10s
+llm:
+  api_key: sk-file
+  model: gpt-4o-mini
+  allowed_models: [gpt-4o]
+summaries:
+  extractive_fallback: true
+`)
+	t.Setenv("CONFIG_FILE", path)
+	t.Setenv("OPENAI_API_KEY", "sk-env")
+	t.Setenv("PORT", "4000")
+
+	cfg, err := Load("")
+	require.NoError(t, err)
+	assert.Equal(t, 4000, cfg.Server.Port, "environment beats the file")
+	assert.Equal(t, "sk-env", cfg.LLM.APIKey)
+	assert.Equal(t, 10*time.Second, cfg.Server.ReadTimeout)
+	assert.Equal(t, "gpt-4o-mini", cfg.LLM.Model)
+	assert.Equal(t, []string{"gpt-4o"}, cfg.LLM.AllowedModels)
+	assert.True(t, cfg.Summaries.ExtractiveFallback)
+	assert.Equal(t, 5*time.Second, cfg.Server.WriteTimeout, "the file keeps unset defaults")
+
+	_, err = Load(writeFile(t, "server: [unclosed"))
+	assert.Error(t, err)
+	_, err = Load(filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.Error(t, err)
+}
+
+func TestValidate(t *testing.T) {
+	cfg := Default()
+	cfg.Server.Port = 0
+	cfg.LLM.Provider = "local"
+	cfg.LLM.Local.BaseURL = "localhost:11434"
+	cfg.LLM.Local.Auth = "basic"
+	cfg.Hedge.Percentile = 1
+	cfg.Tracing.Endpoint = "localhost:4318"
+	cfg.Tracing.SampleRatio = 2
+
+	err := cfg.Validate()
+	require.Error(t, err)
+	for _, want := range []string{"server.port", "llm.local.base_url must be an http(s) URL", "llm.local.model", "llm.local.auth", "hedge.percentile",
+		"tracing.endpoint", "tracing.sample_ratio"} {
+		assert.ErrorContains(t, err, want)
+	}
+	assert.NotContains(t, err.Error(), "OPENAI_API_KEY", "the local provider needs no OpenAI key")
+}
+
+func TestString_RedactsSecrets(t *testing.T) {
+	cfg := Default()
+	cfg.LLM.APIKey = "sk-live-secret"
+	cfg.LLM.Local.APIKey = "local-secret"
+	cfg.Server.AdminToken = "admin-secret"
+
+	out := cfg.String()
+	assert.NotContains(t, out, "secret")
+	assert.Contains(t, out, redacted)
+	assert.Equal(t, "sk-live-secret", cfg.LLM.APIKey, "redaction must not touch the original")
+
+	// The rendering round-trips, durations included
+	var back Config
+	require.NoError(t, yaml.Unmarshal([]byte(out), &back))
+	assert.Equal(t, redacted, back.Server.AdminToken)
+	back.Server.AdminToken = cfg.Server.AdminToken
+	assert.Equal(t, cfg.Server, back.Server)
+	assert.Equal(t, cfg.LLM.Timeout, back.LLM.Timeout)
+}
@@ -0,0 +1,116 @@
+// Package eval scores generated summaries against reference summaries and
+// their source articles for offline prompt and model comparisons
+package eval
+
+import (
+	"github.com/matthewmolinar/tldr/pkg/nlp"
+)
+
+// Score is the precision, recall and F1 of an overlap metric
+type Score struct {
+	Precision float64 `json:"precision"`
+	Recall    float64 `json:"recall"`
+	F1        float64 `json:"f1"`
+}
+
+func newScore(overlap, candidateTotal, referenceTotal int) Score {
+	var s Score
+	if candidateTotal > 0 {
+		s.Precision = float64(overlap) / float64(candidateTotal)
+	}
+	if referenceTotal > 0 {
+		s.Recall = float64(overlap) / float64(referenceTotal)
+	}
+	if s.Precision+s.Recall > 0 {
+		s.F1 = 2 * s.Precision * s.Recall / (s.Precision + s.Recall)
+	}
+	return s
+}
+
+// RougeN computes ROUGE-N: clipped n-gram overlap between candidate and reference tokens
+func RougeN(candidate, reference string, n int) Score {
+	cand := ngrams(nlp.Tokens(candidate), n)
+	ref := ngrams(nlp.Tokens(reference), n)
+
+	overlap, candTotal, refTotal := 0, 0, 0
+	for g, c := range cand {
+		candTotal += c
+		overlap += min(c, ref[g])
+	}
+	for _, c := range ref {
+		refTotal += c
+	}
+	return newScore(overlap, candTotal, refTotal)
+}
+
+// RougeL computes ROUGE-L: the longest common token subsequence of candidate and reference
+func RougeL(candidate, reference string) Score {
+	cand := nlp.Tokens(candidate)
+	ref := nlp.Tokens(reference)
+	return newScore(lcs(cand, ref), len(cand), len(ref))
+}
+
+// Faithfulness is the share of the summary's content tokens that occur in
+// the source text. Low values point at invented names, facts or figures.
+func Faithfulness(summary, source string) float64 {
+	tokens := nlp.ContentTokens(summary)
+	if len(tokens) == 0 {
+		return 1
+	}
+	inSource := make(map[string]bool)
+	for _, t := range nlp.Tokens(source) {
+		inSource[t] = true
+	}
+
+	supported := 0
+	for _, t := range tokens {
+		if inSource[t] {
+			supported++
+		}
+	}
+	return float64(supported) / float64(len(tokens))
+}
+
+// UnsupportedNumbers returns the numbers in summary that never appear in source
+func UnsupportedNumbers(summary, source string) []string {
+	inSource := make(map[string]bool)
+	for _, n := range nlp.Numbers(source) {
+		inSource[n] = true
+	}
+
+	var out []string
+	for _, n := range nlp.Numbers(summary) {
+		if !inSource[n] {
+			out = append(out, n)
+		}
+	}
+	return out
+}
+
+func ngrams(tokens []string, n int) map[string]int {
+	out := make(map[string]int)
+	for i := 0; i+n <= len(tokens); i++ {
+		key := tokens[i]
+		for _, t := range tokens[i+1 : i+n] {
+			key += " " + t
+		}
+		out[key]++
+	}
+	return out
+}
+
+func lcs(a, b []string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			if a[i-1] == b[j-1] {
+				cur[j] = prev[j-1] + 1
+			} else {
+				cur[j] = max(prev[j], cur[j-1])
+			}
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
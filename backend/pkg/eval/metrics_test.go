@@ -0,0 +1,44 @@
+package eval
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRougeN(t *testing.T) {
+	t.Run("identical text scores 1", func(t *testing.T) {
+		s := RougeN("the port reopened today", "the port reopened today", 1)
+		assert.Equal(t, Score{Precision: 1, Recall: 1, F1: 1}, s)
+	})
+
+	t.Run("partial unigram overlap", func(t *testing.T) {
+		s := RougeN("the port reopened", "the port closed again", 1)
+		assert.InDelta(t, 2.0/3.0, s.Precision, 1e-9)
+		assert.InDelta(t, 2.0/4.0, s.Recall, 1e-9)
+	})
+
+	t.Run("bigram overlap is clipped", func(t *testing.T) {
+		s := RougeN("port port port", "port port", 2)
+		assert.InDelta(t, 0.5, s.Precision, 1e-9)
+		assert.InDelta(t, 1.0, s.Recall, 1e-9)
+	})
+
+	t.Run("empty candidate scores 0", func(t *testing.T) {
+		assert.Equal(t, Score{}, RougeN("", "reference text", 1))
+	})
+}
+
+func TestRougeL(t *testing.T) {
+	s := RougeL("police killed the gunman", "police kill the gunman")
+	assert.InDelta(t, 0.75, s.F1, 1e-9)
+}
+
+func TestFaithfulness(t *testing.T) {
+	source := "The city council approved a budget of 40,000 euros for the new library on Monday."
+
+	assert.Equal(t, 1.0, Faithfulness("Council approved library budget", source))
+	assert.Less(t, Faithfulness("Mayor Smith vetoed stadium plans", source), 0.5)
+	assert.Equal(t, []string{"50000"}, UnsupportedNumbers("Council approved 50,000 euros", source))
+	assert.Empty(t, UnsupportedNumbers("Council approved 40000 euros", source))
+}
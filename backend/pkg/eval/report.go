@@ -0,0 +1,184 @@
+package eval
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/matthewmolinar/tldr/pkg/lang"
+)
+
+// Reference is the human-written summary of a corpus article
+type Reference struct {
+	URL      string   `json:"url"`
+	Headline string   `json:"headline"`
+	Bullets  []string `json:"bullets"`
+}
+
+// Text joins the reference into one string for overlap metrics
+func (r Reference) Text() string {
+	return joinSummary(r.Headline, r.Bullets)
+}
+
+// Limits are the length and bullet constraints a summary was asked to meet
+type Limits struct {
+	MaxWidth int
+	Bullets  int
+}
+
+// Result is the evaluation of one corpus article
+type Result struct {
+	Case               string   `json:"case"`
+	Headline           string   `json:"headline,omitempty"`
+	Bullets            []string `json:"bullets,omitempty"`
+	Width              int      `json:"width"`
+	LengthCompliant    bool     `json:"length_compliant"`
+	BulletCompliant    bool     `json:"bullet_compliant"`
+	Rouge1             Score    `json:"rouge1"`
+	Rouge2             Score    `json:"rouge2"`
+	RougeL             Score    `json:"rougeL"`
+	Faithfulness       float64  `json:"faithfulness"`
+	UnsupportedNumbers []string `json:"unsupported_numbers,omitempty"`
+	LatencyMS          int64    `json:"latency_ms"`
+	Error              string   `json:"error,omitempty"`
+}
+
+// Evaluate scores a generated summary against its reference and source text
+func Evaluate(name, headline string, bullets []string, ref Reference, source string, limits Limits) Result {
+	text := joinSummary(headline, bullets)
+	width := lang.Width(headline)
+	for _, b := range bullets {
+		width += lang.Width(b)
+	}
+
+	return Result{
+		Case:               name,
+		Headline:           headline,
+		Bullets:            bullets,
+		Width:              width,
+		LengthCompliant:    width <= limits.MaxWidth,
+		BulletCompliant:    len(bullets) == limits.Bullets,
+		Rouge1:             RougeN(text, ref.Text(), 1),
+		Rouge2:             RougeN(text, ref.Text(), 2),
+		RougeL:             RougeL(text, ref.Text()),
+		Faithfulness:       Faithfulness(text, source),
+		UnsupportedNumbers: UnsupportedNumbers(text, source),
+	}
+}
+
+// Aggregate summarizes the results of a run. Means cover successful cases only.
+type Aggregate struct {
+	Cases                int     `json:"cases"`
+	Errors               int     `json:"errors"`
+	LengthComplianceRate float64 `json:"length_compliance_rate"`
+	BulletComplianceRate float64 `json:"bullet_compliance_rate"`
+	MeanRouge1F1         float64 `json:"mean_rouge1_f1"`
+	MeanRouge2F1         float64 `json:"mean_rouge2_f1"`
+	MeanRougeLF1         float64 `json:"mean_rougeL_f1"`
+	MeanFaithfulness     float64 `json:"mean_faithfulness"`
+	MeanLatencyMS        float64 `json:"mean_latency_ms"`
+}
+
+// Report is the outcome of evaluating one configuration over a corpus
+type Report struct {
+	Provider      string    `json:"provider"`
+	Model         string    `json:"model,omitempty"`
+	Style         string    `json:"style"`
+	PromptVersion string    `json:"prompt_version,omitempty"`
+	GeneratedAt   time.Time `json:"generated_at"`
+	Aggregate     Aggregate `json:"aggregate"`
+	Results       []Result  `json:"results"`
+}
+
+// Summarize fills in the report's aggregate from its results
+func (r *Report) Summarize() {
+	a := Aggregate{Cases: len(r.Results)}
+	ok := 0
+	for _, res := range r.Results {
+		if res.Error != "" {
+			a.Errors++
+			continue
+		}
+		ok++
+		if res.LengthCompliant {
+			a.LengthComplianceRate++
+		}
+		if res.BulletCompliant {
+			a.BulletComplianceRate++
+		}
+		a.MeanRouge1F1 += res.Rouge1.F1
+		a.MeanRouge2F1 += res.Rouge2.F1
+		a.MeanRougeLF1 += res.RougeL.F1
+		a.MeanFaithfulness += res.Faithfulness
+		a.MeanLatencyMS += float64(res.LatencyMS)
+	}
+	if ok > 0 {
+		n := float64(ok)
+		a.LengthComplianceRate /= n
+		a.BulletComplianceRate /= n
+		a.MeanRouge1F1 /= n
+		a.MeanRouge2F1 /= n
+		a.MeanRougeLF1 /= n
+		a.MeanFaithfulness /= n
+		a.MeanLatencyMS /= n
+	}
+	r.Aggregate = a
+}
+
+// WriteJSON writes the report as indented JSON
+func (r *Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// WriteMarkdown writes the report as a Markdown summary table followed by
+// one row per case
+func (r *Report) WriteMarkdown(w io.Writer) error {
+	var b strings.Builder
+	a := r.Aggregate
+
+	fmt.Fprintf(&b, "# Summary evaluation: %s", r.Provider)
+	if r.Model != "" {
+		fmt.Fprintf(&b, " / %s", r.Model)
+	}
+	fmt.Fprintf(&b, "\n\nStyle `%s`", r.Style)
+	if r.PromptVersion != "" {
+		fmt.Fprintf(&b, ", prompt `%s`", r.PromptVersion)
+	}
+	fmt.Fprintf(&b, ", generated %s\n\n", r.GeneratedAt.Format(time.RFC3339))
+
+	b.WriteString("| Cases | Errors | Length OK | Bullets OK | ROUGE-1 F1 | ROUGE-2 F1 | ROUGE-L F1 | Faithfulness | Mean latency |\n")
+	b.WriteString("|---|---|---|---|---|---|---|---|---|\n")
+	fmt.Fprintf(&b, "| %d | %d | %.0f%% | %.0f%% | %.3f | %.3f | %.3f | %.3f | %.0f ms |\n\n",
+		a.Cases, a.Errors, a.LengthComplianceRate*100, a.BulletComplianceRate*100,
+		a.MeanRouge1F1, a.MeanRouge2F1, a.MeanRougeLF1, a.MeanFaithfulness, a.MeanLatencyMS)
+
+	b.WriteString("| Case | Width | Length OK | Bullets OK | ROUGE-1 F1 | ROUGE-L F1 | Faithfulness | Unsupported numbers |\n")
+	b.WriteString("|---|---|---|---|---|---|---|---|\n")
+	for _, res := range r.Results {
+		if res.Error != "" {
+			fmt.Fprintf(&b, "| %s | error: %s | | | | | | |\n", res.Case, strings.ReplaceAll(res.Error, "|", `\|`))
+			continue
+		}
+		fmt.Fprintf(&b, "| %s | %d | %s | %s | %.3f | %.3f | %.3f | %s |\n",
+			res.Case, res.Width, check(res.LengthCompliant), check(res.BulletCompliant),
+			res.Rouge1.F1, res.RougeL.F1, res.Faithfulness, strings.Join(res.UnsupportedNumbers, ", "))
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func check(ok bool) string {
+	if ok {
+		return "yes"
+	}
+	return "no"
+}
+
+func joinSummary(headline string, bullets []string) string {
+	return strings.Join(append([]string{headline}, bullets...), "\n")
+}
@@ -0,0 +1,46 @@
+package eval
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEvaluateAndReport(t *testing.T) {
+	ref := Reference{Headline: "Library opens downtown", Bullets: []string{"Over 100,000 books", "Children's reading area"}}
+	source := "A new library opened downtown with over 100,000 books and a children's reading area."
+
+	good := Evaluate("library", "Library opens downtown", []string{"Holds 100,000 books", "Has a children's reading area"},
+		ref, source, Limits{MaxWidth: 280, Bullets: 2})
+	assert.True(t, good.LengthCompliant)
+	assert.True(t, good.BulletCompliant)
+	assert.Greater(t, good.Rouge1.F1, 0.5)
+	assert.Empty(t, good.UnsupportedNumbers)
+
+	bad := Evaluate("library-bad", "Library opens", []string{"Holds 250,000 books"},
+		ref, source, Limits{MaxWidth: 10, Bullets: 3})
+	assert.False(t, bad.LengthCompliant)
+	assert.False(t, bad.BulletCompliant)
+	assert.Equal(t, []string{"250000"}, bad.UnsupportedNumbers)
+
+	report := &Report{Provider: "lead", Style: "headline", Results: []Result{good, bad, {Case: "broken", Error: "fetch failed"}}}
+	report.Summarize()
+	assert.Equal(t, 3, report.Aggregate.Cases)
+	assert.Equal(t, 1, report.Aggregate.Errors)
+	assert.Equal(t, 0.5, report.Aggregate.LengthComplianceRate)
+
+	var js bytes.Buffer
+	require.NoError(t, report.WriteJSON(&js))
+	var decoded Report
+	require.NoError(t, json.Unmarshal(js.Bytes(), &decoded))
+	assert.Equal(t, report.Aggregate, decoded.Aggregate)
+
+	var md bytes.Buffer
+	require.NoError(t, report.WriteMarkdown(&md))
+	assert.Contains(t, md.String(), "# Summary evaluation: lead")
+	assert.Contains(t, md.String(), "| library-bad |")
+	assert.Contains(t, md.String(), "error: fetch failed")
+}
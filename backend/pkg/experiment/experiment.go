@@ -0,0 +1,95 @@
+// Package experiment buckets summarize requests into A/B variants of
+// provider, model and prompt version and records how each variant performs
+package experiment
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"os"
+)
+
+// Variant is one arm of an experiment. Empty fields fall back to the
+// server defaults, so a control arm can be declared with just a name.
+type Variant struct {
+	Name          string `json:"name"`
+	Weight        int    `json:"weight"`
+	Provider      string `json:"provider,omitempty"`
+	Model         string `json:"model,omitempty"`
+	PromptVersion string `json:"prompt_version,omitempty"`
+}
+
+// Experiment splits traffic between variants in proportion to their weights
+type Experiment struct {
+	Name     string    `json:"name"`
+	Variants []Variant `json:"variants"`
+}
+
+// Load reads an experiment definition from a JSON file
+func Load(path string) (*Experiment, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read experiment file: %w", err)
+	}
+
+	var e Experiment
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, fmt.Errorf("failed to parse experiment file: %w", err)
+	}
+	if err := e.Validate(); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// Validate checks that the experiment has a name and uniquely named,
+// positively weighted variants
+func (e *Experiment) Validate() error {
+	if e.Name == "" {
+		return errors.New("experiment name is required")
+	}
+	if len(e.Variants) == 0 {
+		return fmt.Errorf("experiment %q has no variants", e.Name)
+	}
+	seen := make(map[string]bool)
+	for _, v := range e.Variants {
+		if v.Name == "" {
+			return fmt.Errorf("experiment %q has a variant without a name", e.Name)
+		}
+		if seen[v.Name] {
+			return fmt.Errorf("experiment %q has duplicate variant %q", e.Name, v.Name)
+		}
+		seen[v.Name] = true
+		if v.Weight <= 0 {
+			return fmt.Errorf("variant %q must have a positive weight", v.Name)
+		}
+	}
+	return nil
+}
+
+// Assign deterministically buckets a unit (client ID or request hash) into a
+// variant. The experiment name salts the hash so units are reshuffled
+// between experiments.
+func (e *Experiment) Assign(unit string) Variant {
+	total := 0
+	for _, v := range e.Variants {
+		total += v.Weight
+	}
+
+	bucket := int(Bucket(e.Name+":"+unit) % uint32(total))
+	for _, v := range e.Variants {
+		if bucket < v.Weight {
+			return v
+		}
+		bucket -= v.Weight
+	}
+	return e.Variants[len(e.Variants)-1]
+}
+
+// Bucket hashes s with FNV-1a. It is also used to derive anonymous unit IDs.
+func Bucket(s string) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(s))
+	return h.Sum32()
+}
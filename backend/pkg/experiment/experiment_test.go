@@ -0,0 +1,73 @@
+package experiment
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "experiment.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{
+		"name": "prompt-v2",
+		"variants": [
+			{"name": "control", "weight": 50},
+			{"name": "mini-v2", "weight": 50, "model": "gpt-4o-mini", "prompt_version": "v2"}
+		]
+	}`), 0o644))
+
+	e, err := Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, "prompt-v2", e.Name)
+	assert.Equal(t, "gpt-4o-mini", e.Variants[1].Model)
+}
+
+func TestExperiment_Validate(t *testing.T) {
+	tests := []struct {
+		name string
+		exp  Experiment
+	}{
+		{name: "missing name", exp: Experiment{Variants: []Variant{{Name: "a", Weight: 1}}}},
+		{name: "no variants", exp: Experiment{Name: "x"}},
+		{name: "unnamed variant", exp: Experiment{Name: "x", Variants: []Variant{{Weight: 1}}}},
+		{name: "duplicate variant", exp: Experiment{Name: "x", Variants: []Variant{{Name: "a", Weight: 1}, {Name: "a", Weight: 1}}}},
+		{name: "zero weight", exp: Experiment{Name: "x", Variants: []Variant{{Name: "a"}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Error(t, tt.exp.Validate())
+		})
+	}
+}
+
+func TestExperiment_Assign(t *testing.T) {
+	e := &Experiment{
+		Name: "split",
+		Variants: []Variant{
+			{Name: "a", Weight: 80},
+			{Name: "b", Weight: 20},
+		},
+	}
+
+	t.Run("same unit always gets the same variant", func(t *testing.T) {
+		first := e.Assign("client-42")
+		for i := 0; i < 10; i++ {
+			assert.Equal(t, first, e.Assign("client-42"))
+		}
+	})
+
+	t.Run("traffic follows the weights", func(t *testing.T) {
+		counts := make(map[string]int)
+		for i := 0; i < 10000; i++ {
+			counts[e.Assign(fmt.Sprintf("client-%d", i)).Name]++
+		}
+		assert.InDelta(t, 8000, counts["a"], 300)
+		assert.InDelta(t, 2000, counts["b"], 300)
+	})
+}
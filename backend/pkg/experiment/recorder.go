@@ -0,0 +1,89 @@
+package experiment
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// Event types written to the experiment log
+const (
+	EventOutcome  = "outcome"
+	EventFeedback = "feedback"
+)
+
+// Event is one line of the experiment log. Outcome events describe a served
+// summary; feedback events carry a user rating for an earlier request and
+// are joined to its outcome by RequestID.
+type Event struct {
+	Type       string    `json:"type"`
+	Time       time.Time `json:"time"`
+	RequestID  string    `json:"request_id"`
+	Experiment string    `json:"experiment,omitempty"`
+	Variant    string    `json:"variant,omitempty"`
+
+	// Outcome fields
+	LatencyMS       int64  `json:"latency_ms,omitempty"`
+	Width           int    `json:"width,omitempty"`
+	LengthCompliant bool   `json:"length_compliant"`
+	BulletCompliant bool   `json:"bullet_compliant"`
+	Error           string `json:"error,omitempty"`
+
+	// Feedback fields
+	Rating  int    `json:"rating,omitempty"` // +1 helpful, -1 not helpful
+	Comment string `json:"comment,omitempty"`
+}
+
+// Recorder appends events as JSON lines to a local file that can be
+// exported and analyzed offline
+type Recorder struct {
+	mu   sync.Mutex
+	path string
+	file *os.File
+	enc  *json.Encoder
+}
+
+// NewRecorder opens (creating if needed) the log file at path for appending
+func NewRecorder(path string) (*Recorder, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open experiment log: %w", err)
+	}
+	return &Recorder{path: path, file: f, enc: json.NewEncoder(f)}, nil
+}
+
+// Path returns the location of the log file
+func (r *Recorder) Path() string {
+	return r.path
+}
+
+// Record appends an event, stamping it with the current time if unset
+func (r *Recorder) Record(e Event) error {
+	if e.Time.IsZero() {
+		e.Time = time.Now().UTC()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file == nil {
+		return fmt.Errorf("experiment log %s is closed", r.path)
+	}
+	return r.enc.Encode(e)
+}
+
+// Close syncs and closes the log file
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file == nil {
+		return nil
+	}
+	err := r.file.Sync()
+	if cerr := r.file.Close(); err == nil {
+		err = cerr
+	}
+	r.file = nil
+	return err
+}
@@ -0,0 +1,59 @@
+package experiment
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecorderAndReport(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "experiments.jsonl")
+	rec, err := NewRecorder(path)
+	require.NoError(t, err)
+
+	events := []Event{
+		{Type: EventOutcome, RequestID: "r1", Experiment: "e", Variant: "a", LatencyMS: 100, LengthCompliant: true, BulletCompliant: true},
+		{Type: EventOutcome, RequestID: "r2", Experiment: "e", Variant: "a", LatencyMS: 300, LengthCompliant: false, BulletCompliant: true},
+		{Type: EventOutcome, RequestID: "r3", Experiment: "e", Variant: "b", Error: "upstream timeout"},
+		{Type: EventFeedback, RequestID: "r1", Rating: 1},
+		{Type: EventFeedback, RequestID: "r2", Rating: -1},
+		{Type: EventFeedback, RequestID: "r2", Rating: 1},
+		{Type: EventFeedback, RequestID: "unknown", Rating: 1},
+	}
+	for _, e := range events {
+		require.NoError(t, rec.Record(e))
+	}
+	require.NoError(t, rec.Close())
+	assert.Error(t, rec.Record(Event{Type: EventOutcome}), "recording after close should fail")
+
+	// Failed checks are logged, not left out like unmeasured fields
+	raw, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(raw), `"request_id":"r2","experiment":"e","variant":"a","latency_ms":300,"length_compliant":false,"bullet_compliant":true`)
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	stats, err := Report(f)
+	require.NoError(t, err)
+	require.Len(t, stats, 2)
+
+	a := stats[0]
+	assert.Equal(t, "a", a.Variant)
+	assert.Equal(t, 2, a.Requests)
+	assert.Equal(t, 200.0, a.MeanLatencyMS)
+	assert.Equal(t, int64(300), a.P95LatencyMS)
+	assert.Equal(t, 0.5, a.LengthComplianceRate)
+	assert.Equal(t, 1.0, a.BulletComplianceRate)
+	assert.Equal(t, 3, a.Ratings)
+	assert.InDelta(t, 1.0/3.0, a.MeanRating, 1e-9)
+
+	b := stats[1]
+	assert.Equal(t, "b", b.Variant)
+	assert.Equal(t, 1, b.Requests)
+	assert.Equal(t, 1, b.Errors)
+}
@@ -0,0 +1,105 @@
+package experiment
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"sort"
+)
+
+// VariantStats aggregates the outcomes and feedback recorded for one variant
+type VariantStats struct {
+	Experiment           string  `json:"experiment"`
+	Variant              string  `json:"variant"`
+	Requests             int     `json:"requests"`
+	Errors               int     `json:"errors"`
+	MeanLatencyMS        float64 `json:"mean_latency_ms"`
+	P95LatencyMS         int64   `json:"p95_latency_ms"`
+	LengthComplianceRate float64 `json:"length_compliance_rate"`
+	BulletComplianceRate float64 `json:"bullet_compliance_rate"`
+	Ratings              int     `json:"ratings"`
+	MeanRating           float64 `json:"mean_rating"`
+}
+
+// Report reads an experiment log and aggregates it per experiment and
+// variant. Feedback is attributed to the variant that served the request.
+func Report(r io.Reader) ([]VariantStats, error) {
+	type acc struct {
+		stats     VariantStats
+		latencies []int64
+		lengthOK  int
+		bulletOK  int
+		ratingSum int
+	}
+	byKey := make(map[string]*acc)
+	servedBy := make(map[string]string) // request ID -> key
+	var feedback []Event
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		var e Event
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, err
+		}
+		if e.Type == EventFeedback {
+			feedback = append(feedback, e)
+			continue
+		}
+
+		key := e.Experiment + "/" + e.Variant
+		a := byKey[key]
+		if a == nil {
+			a = &acc{stats: VariantStats{Experiment: e.Experiment, Variant: e.Variant}}
+			byKey[key] = a
+		}
+		servedBy[e.RequestID] = key
+		a.stats.Requests++
+		if e.Error != "" {
+			a.stats.Errors++
+			continue
+		}
+		a.latencies = append(a.latencies, e.LatencyMS)
+		if e.LengthCompliant {
+			a.lengthOK++
+		}
+		if e.BulletCompliant {
+			a.bulletOK++
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, e := range feedback {
+		if a := byKey[servedBy[e.RequestID]]; a != nil {
+			a.stats.Ratings++
+			a.ratingSum += e.Rating
+		}
+	}
+
+	out := make([]VariantStats, 0, len(byKey))
+	for _, a := range byKey {
+		if n := len(a.latencies); n > 0 {
+			var sum int64
+			for _, l := range a.latencies {
+				sum += l
+			}
+			sort.Slice(a.latencies, func(i, j int) bool { return a.latencies[i] < a.latencies[j] })
+			a.stats.MeanLatencyMS = float64(sum) / float64(n)
+			a.stats.P95LatencyMS = a.latencies[(n*95+99)/100-1]
+			a.stats.LengthComplianceRate = float64(a.lengthOK) / float64(n)
+			a.stats.BulletComplianceRate = float64(a.bulletOK) / float64(n)
+		}
+		if a.stats.Ratings > 0 {
+			a.stats.MeanRating = float64(a.ratingSum) / float64(a.stats.Ratings)
+		}
+		out = append(out, a.stats)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Experiment != out[j].Experiment {
+			return out[i].Experiment < out[j].Experiment
+		}
+		return out[i].Variant < out[j].Variant
+	})
+	return out, nil
+}
@@ -0,0 +1,115 @@
+package extract
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gogs/chardet"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/charset"
+	"golang.org/x/text/transform"
+)
+
+// minDetectConfidence is the lowest chardet confidence trusted for pages that
+// declare no charset at all
+const minDetectConfidence = 50
+
+// toUTF8 transcodes an HTML body to UTF-8 before it is handed to readability.
+// The source encoding is taken from, in order: a byte order mark, the
+// Content-Type charset parameter, a <meta charset> declaration, and finally
+// statistical detection for undeclared pages. It returns the transcoded body
+// and the name of the source encoding.
+func toUTF8(body []byte, contentType string) ([]byte, string, error) {
+	enc, name, certain := charset.DetermineEncoding(body, contentType)
+
+	// A BOM or Content-Type charset makes DetermineEncoding certain; a meta
+	// declaration doesn't, but is honored too. Otherwise it only guessed from
+	// the first 1 KB, falling back to windows-1252, so sniff the whole body.
+	if !certain && !declaresCharset(body) {
+		switch {
+		case utf8.Valid(body):
+			return body, "utf-8", nil
+		default:
+			if res, err := chardet.NewHtmlDetector().DetectBest(body); err == nil && res.Confidence >= minDetectConfidence {
+				if detected, detectedName := charset.Lookup(res.Charset); detected != nil {
+					enc, name = detected, detectedName
+				}
+			}
+		}
+	}
+
+	if name == "utf-8" {
+		return body, name, nil
+	}
+
+	decoded, _, err := transform.Bytes(enc.NewDecoder(), body)
+	if err != nil {
+		return nil, name, fmt.Errorf("failed to decode %s content: %w", name, err)
+	}
+	return decoded, name, nil
+}
+
+// declaresCharset reports whether the first 1 KB of the document has a
+// <meta charset> or <meta http-equiv="Content-Type"> declaring a known
+// encoding, as DetermineEncoding's prescan would find. Mentions of "charset"
+// in scripts, styles or text don't count.
+func declaresCharset(body []byte) bool {
+	if len(body) > 1024 {
+		body = body[:1024]
+	}
+	known := func(label string) bool {
+		enc, _ := charset.Lookup(label)
+		return enc != nil
+	}
+
+	z := html.NewTokenizer(bytes.NewReader(body))
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			return false
+		}
+		if tt != html.StartTagToken && tt != html.SelfClosingTagToken {
+			continue
+		}
+		name, hasAttr := z.TagName()
+		if !hasAttr || string(name) != "meta" {
+			continue
+		}
+
+		var contentType bool
+		var content string
+		for more := true; more; {
+			var key, val []byte
+			key, val, more = z.TagAttr()
+			switch string(key) {
+			case "charset":
+				if known(string(val)) {
+					return true
+				}
+			case "http-equiv":
+				contentType = strings.EqualFold(string(val), "content-type")
+			case "content":
+				content = string(val)
+			}
+		}
+		if contentType {
+			if _, params, err := mime.ParseMediaType(content); err == nil && known(params["charset"]) {
+				return true
+			}
+		}
+	}
+}
+
+// truncateUTF8 trims s to at most n bytes without splitting a multi-byte rune
+func truncateUTF8(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
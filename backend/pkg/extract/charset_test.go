@@ -0,0 +1,117 @@
+package extract
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtract_Charsets(t *testing.T) {
+	tests := []struct {
+		name        string
+		fixture     string
+		contentType string
+		want        string
+	}{
+		{
+			name:        "shift_jis declared in meta charset",
+			fixture:     "testdata/shift_jis.html",
+			contentType: "text/html",
+			want:        "新しい図書館が開館し",
+		},
+		{
+			name:        "gbk declared only in Content-Type header",
+			fixture:     "testdata/gbk.html",
+			contentType: "text/html; charset=GBK",
+			want:        "城市中心公园完成改造",
+		},
+		{
+			name:        "windows-1252 declared in http-equiv meta",
+			fixture:     "testdata/windows1252.html",
+			contentType: "text/html",
+			want:        "renovations costing €40,000",
+		},
+		{
+			name:        "undeclared euc-kr is sniffed",
+			fixture:     "testdata/euc_kr.html",
+			contentType: "text/html",
+			want:        "지역 축제가",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			htmlData, err := os.ReadFile(tt.fixture)
+			require.NoError(t, err)
+			require.False(t, utf8.Valid(htmlData), "fixture should not be UTF-8")
+
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", tt.contentType)
+				w.Write(htmlData)
+			}))
+			defer ts.Close()
+
+			content, err := Extract(ts.URL)
+			require.NoError(t, err)
+			assert.True(t, utf8.ValidString(content))
+			assert.Contains(t, content, tt.want)
+		})
+	}
+}
+
+func TestToUTF8_UndeclaredUTF8(t *testing.T) {
+	// Non-ASCII text past the first 1 KB must not be mistaken for windows-1252
+	body := []byte("<html><body><p>" + strings.Repeat("a", 2048) + " naïve café</p></body></html>")
+
+	decoded, name, err := toUTF8(body, "text/html")
+	require.NoError(t, err)
+	assert.Equal(t, "utf-8", name)
+	assert.Equal(t, body, decoded)
+}
+
+func TestToUTF8_CharsetMentionedInScript(t *testing.T) {
+	// "charset" in a script is not a declaration, so the page is still
+	// recognized as UTF-8 instead of decoded as windows-1252
+	body := []byte(`<html><head><script>document.characterSet || console.log("no charset");</script>` +
+		`<style>/* charset: utf-8 */</style></head><body><p>` + strings.Repeat("a", 2048) + ` Le café ouvre à l'aube.</p></body></html>`)
+
+	decoded, name, err := toUTF8(body, "text/html")
+	require.NoError(t, err)
+	assert.Equal(t, "utf-8", name)
+	assert.Equal(t, body, decoded)
+}
+
+func TestDeclaresCharset(t *testing.T) {
+	tests := []struct {
+		html string
+		want bool
+	}{
+		{`<head><meta charset="shift_jis"></head>`, true},
+		{`<head><META CHARSET=gbk></head>`, true},
+		{`<head><meta http-equiv="Content-Type" content="text/html; charset=windows-1252"></head>`, true},
+		{`<head><meta http-equiv="content-type" content="text/html"></head>`, false},
+		{`<head><meta charset="no-such-encoding"></head>`, false},
+		{`<head><meta name="description" content="Set the charset=utf-8 header"></head>`, false},
+		{`<head><script>var charset = "utf-8";</script></head>`, false},
+		{`<head><!-- <meta charset="gbk"> --></head>`, false},
+		{`<head>` + strings.Repeat(" ", 1024) + `<meta charset="gbk"></head>`, false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, declaresCharset([]byte(tt.html)), tt.html)
+	}
+}
+
+func TestTruncateUTF8(t *testing.T) {
+	s := "日本語テキスト"
+	for n := 0; n <= len(s); n++ {
+		got := truncateUTF8(s, n)
+		assert.LessOrEqual(t, len(got), n)
+		assert.True(t, utf8.ValidString(got), "truncating to %d bytes split a rune", n)
+	}
+}
@@ -0,0 +1,195 @@
+package extract
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// BlockKind identifies why a fetched page holds no readable article
+type BlockKind string
+
+const (
+	BlockConsentWall BlockKind = "consent_wall"
+	BlockPaywall     BlockKind = "paywall"
+	BlockJSRequired  BlockKind = "js_required"
+	BlockBotCheck    BlockKind = "bot_check"
+)
+
+// teaserMaxRunes is the longest extracted text still treated as a possible
+// interstitial. Real articles that merely mention cookies or subscriptions
+// are longer than this and are never flagged by text phrases alone.
+const teaserMaxRunes = 1500
+
+// BlockedError is returned by Extract when the page is a consent wall, paywall
+// teaser, JavaScript-only shell or bot check instead of an article.
+type BlockedError struct {
+	Kind   BlockKind
+	Signal string // marker that triggered the classification
+}
+
+func (e *BlockedError) Error() string {
+	return fmt.Sprintf("page is not an article (%s): matched %q", e.Kind, e.Signal)
+}
+
+// botCheckHTMLMarkers are raw-markup fingerprints found only on bot-check
+// interstitials. They classify a page regardless of how much text it has.
+var botCheckHTMLMarkers = []string{
+	"cf-browser-verification",
+	"_cf_chl_opt",
+	"cf-chl-",
+	`id="challenge-form"`,
+	"px-captcha",
+	"captcha-delivery.com",
+}
+
+// botCheckShortHTMLMarkers also show up on ordinary pages, such as the
+// challenge platform scripts Cloudflare adds to articles, so they only count
+// when the page has little text
+var botCheckShortHTMLMarkers = []string{
+	"cf-challenge",
+	"/cdn-cgi/challenge-platform",
+}
+
+var botCheckPhrases = []string{
+	"verify you are human",
+	"verifying you are human",
+	"checking your browser",
+	"are you a robot",
+	"unusual traffic from your computer",
+	"press & hold",
+	"complete the security check",
+}
+
+var jsRequiredPhrases = []string{
+	"enable javascript",
+	"javascript is required",
+	"javascript is disabled",
+	"requires javascript",
+	"turn on javascript",
+	"javascript to run this app",
+}
+
+// appShellMarkers are mount points of client-rendered single-page apps
+var appShellMarkers = []string{
+	`id="root"`,
+	`id="__next"`,
+	`id="app"`,
+	`id="__nuxt"`,
+}
+
+var consentPhrases = []string{
+	"we value your privacy",
+	"we use cookies",
+	"accept all",
+	"reject all",
+	"manage preferences",
+	"cookie settings",
+	"consent to",
+	"by clicking accept",
+	"our partners",
+}
+
+var paywallPhrases = []string{
+	"subscribe to continue reading",
+	"subscribe to read",
+	"subscribers only",
+	"already a subscriber",
+	"sign in to read",
+	"reached your limit of free articles",
+	"become a member to read",
+	"unlock this article",
+}
+
+// weakPaywallPhrases also appear in ordinary copy ("click to continue
+// reading", "a newsletter for subscribers"), so they only count next to a
+// paywallCTA
+var weakPaywallPhrases = []string{
+	"to continue reading",
+	"for subscribers",
+}
+
+var paywallCTAs = []string{
+	"subscribe now",
+	"subscribe today",
+	"sign in",
+	"log in",
+	"create an account",
+	"become a member",
+	"start your free trial",
+}
+
+// Classify inspects the raw HTML of a page and the text readability extracted
+// from it. It returns a BlockedError for consent walls, paywall teasers,
+// JavaScript-only shells and bot checks, and nil for pages that look like
+// real articles.
+func Classify(rawHTML []byte, text string) *BlockedError {
+	lowerHTML := bytes.ToLower(rawHTML)
+	lowerText := strings.ToLower(text)
+	short := utf8.RuneCountInString(strings.TrimSpace(text)) <= teaserMaxRunes
+
+	if m := firstMarker(lowerHTML, botCheckHTMLMarkers); m != "" {
+		return &BlockedError{Kind: BlockBotCheck, Signal: m}
+	}
+	if !short {
+		return nil
+	}
+
+	if m := firstMarker(lowerHTML, botCheckShortHTMLMarkers); m != "" {
+		return &BlockedError{Kind: BlockBotCheck, Signal: m}
+	}
+	if p := firstPhrase(lowerText, botCheckPhrases); p != "" {
+		return &BlockedError{Kind: BlockBotCheck, Signal: p}
+	}
+	if p := firstPhrase(lowerText, jsRequiredPhrases); p != "" {
+		return &BlockedError{Kind: BlockJSRequired, Signal: p}
+	}
+	if strings.TrimSpace(text) == "" {
+		if m := firstMarker(lowerHTML, appShellMarkers); m != "" {
+			return &BlockedError{Kind: BlockJSRequired, Signal: m}
+		}
+	}
+	// A single cookie phrase is common in footers; require two to call it a wall
+	if hits := matchPhrases(lowerText, consentPhrases); len(hits) >= 2 {
+		return &BlockedError{Kind: BlockConsentWall, Signal: hits[0]}
+	}
+	if p := firstPhrase(lowerText, paywallPhrases); p != "" {
+		return &BlockedError{Kind: BlockPaywall, Signal: p}
+	}
+	if p := firstPhrase(lowerText, weakPaywallPhrases); p != "" && firstPhrase(lowerText, paywallCTAs) != "" {
+		return &BlockedError{Kind: BlockPaywall, Signal: p}
+	}
+	if bytes.Contains(lowerHTML, []byte(`"isaccessibleforfree": false`)) ||
+		bytes.Contains(lowerHTML, []byte(`"isaccessibleforfree":false`)) {
+		return &BlockedError{Kind: BlockPaywall, Signal: "isAccessibleForFree"}
+	}
+
+	return nil
+}
+
+func firstMarker(lowerHTML []byte, markers []string) string {
+	for _, m := range markers {
+		if bytes.Contains(lowerHTML, []byte(strings.ToLower(m))) {
+			return m
+		}
+	}
+	return ""
+}
+
+func firstPhrase(lowerText string, phrases []string) string {
+	if hits := matchPhrases(lowerText, phrases); len(hits) > 0 {
+		return hits[0]
+	}
+	return ""
+}
+
+func matchPhrases(lowerText string, phrases []string) []string {
+	var hits []string
+	for _, p := range phrases {
+		if strings.Contains(lowerText, p) {
+			hits = append(hits, p)
+		}
+	}
+	return hits
+}
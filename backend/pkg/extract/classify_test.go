@@ -0,0 +1,104 @@
+package extract
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtract_BlockedPages(t *testing.T) {
+	tests := []struct {
+		name    string
+		fixture string
+		want    BlockKind
+	}{
+		{name: "cookie consent wall", fixture: "testdata/consent.html", want: BlockConsentWall},
+		{name: "paywall teaser", fixture: "testdata/paywall.html", want: BlockPaywall},
+		{name: "javascript-only shell", fixture: "testdata/js_shell.html", want: BlockJSRequired},
+		{name: "captcha interstitial", fixture: "testdata/captcha.html", want: BlockBotCheck},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			htmlData, err := os.ReadFile(tt.fixture)
+			require.NoError(t, err)
+
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/html")
+				w.Write(htmlData)
+			}))
+			defer ts.Close()
+
+			_, err = Extract(ts.URL)
+			require.Error(t, err)
+
+			var blocked *BlockedError
+			require.True(t, errors.As(err, &blocked), "expected BlockedError, got %v", err)
+			assert.Equal(t, tt.want, blocked.Kind)
+		})
+	}
+}
+
+func TestClassify(t *testing.T) {
+	article, err := os.ReadFile("testdata/article.html")
+	require.NoError(t, err)
+
+	t.Run("plain article is not blocked", func(t *testing.T) {
+		assert.Nil(t, Classify(article, "This is the main content that should be extracted."))
+	})
+
+	t.Run("long article mentioning subscriptions is not blocked", func(t *testing.T) {
+		text := strings.Repeat("Publishers keep experimenting with ways to get readers to subscribe to read more. ", 30)
+		assert.Nil(t, Classify(article, text))
+	})
+
+	t.Run("single cookie phrase in footer is not a consent wall", func(t *testing.T) {
+		assert.Nil(t, Classify(article, "A short note on the release. We use cookies."))
+	})
+
+	t.Run("bot check markup wins regardless of text length", func(t *testing.T) {
+		html := []byte(`<script>window._cf_chl_opt = {cType: 'managed'};</script>`)
+		blocked := Classify(html, strings.Repeat("word ", 2000))
+		require.NotNil(t, blocked)
+		assert.Equal(t, BlockBotCheck, blocked.Kind)
+	})
+
+	t.Run("challenge platform script only counts on short pages", func(t *testing.T) {
+		html := []byte(`<script src="/cdn-cgi/challenge-platform/scripts/jsd/main.js"></script>`)
+		assert.Nil(t, Classify(html, strings.Repeat("word ", 2000)))
+		blocked := Classify(html, "Just a moment...")
+		require.NotNil(t, blocked)
+		assert.Equal(t, BlockBotCheck, blocked.Kind)
+	})
+
+	t.Run("weak paywall phrases need a call to action", func(t *testing.T) {
+		assert.Nil(t, Classify(article, "Scroll down to continue reading about the harbor vote."))
+		assert.Nil(t, Classify(article, "The museum opens an hour early for subscribers to its newsletter."))
+
+		blocked := Classify(article, "The harbor vote went late into the night. To continue reading, subscribe now.")
+		require.NotNil(t, blocked)
+		assert.Equal(t, BlockPaywall, blocked.Kind)
+		assert.Equal(t, "to continue reading", blocked.Signal)
+	})
+}
+
+func TestExtract_CloudflareArticle(t *testing.T) {
+	// Cloudflare injects its challenge platform script into real articles
+	htmlData, err := os.ReadFile("testdata/article_cloudflare.html")
+	require.NoError(t, err)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write(htmlData)
+	}))
+	defer ts.Close()
+
+	content, err := Extract(ts.URL)
+	require.NoError(t, err)
+	assert.Contains(t, content, "harbor authority approved")
+}
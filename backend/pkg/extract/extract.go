@@ -2,79 +2,264 @@ package extract
 
 import (
 	"bytes"
-	"crypto/tls"
+	"context"
 	"fmt"
 	"io"
 	"log"
+	"mime"
 	"net/http"
-	"os"
+	"net/url"
+	"strings"
 
 	"github.com/go-shiori/go-readability"
+	"github.com/matthewmolinar/tldr/pkg/breaker"
+	"github.com/matthewmolinar/tldr/pkg/config"
 )
 
-const maxBytes = 8192 // 8 KB limit as per PRD
+// page is a fetched document decoded to UTF-8
+type page struct {
+	url  *url.URL
+	body []byte
+	size int // bytes downloaded, before decoding
+}
 
-// Extract fetches the given URL and returns its main content using readability,
-// trimmed to a maximum of 8KB.
+// Page is the text extracted from one page of an article
+type Page struct {
+	URL  string
+	Text string
+}
+
+// Article is an article's text and the pages it was stitched from
+type Article struct {
+	Text  string // the pages' text joined, trimmed to the configured maximum
+	Pages []Page
+}
+
+// Extractor fetches articles and extracts their main content
+type Extractor struct {
+	client           *http.Client
+	maxBytes         int
+	maxContentLength int64
+}
+
+// New creates an extractor with the fetch limits of cfg. hc makes the
+// requests; nil uses http.DefaultClient.
+func New(cfg config.Fetch, hc *http.Client) *Extractor {
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	return &Extractor{client: hc, maxBytes: cfg.MaxArticleBytes, maxContentLength: cfg.MaxContentLength}
+}
+
+// MaxBytes returns how much extracted text the extractor keeps
+func (e *Extractor) MaxBytes() int {
+	return e.maxBytes
+}
+
+// WithMaxBytes returns a copy of the extractor that keeps up to n bytes of
+// extracted text
+func (e *Extractor) WithMaxBytes(n int) *Extractor {
+	c := *e
+	c.maxBytes = n
+	return &c
+}
+
+// defaultExtractor backs the package-level functions
+var defaultExtractor = New(config.Default().Fetch, nil)
+
+// Extract is Extractor.Extract with the default configuration
 func Extract(url string) (string, error) {
+	return defaultExtractor.Extract(context.Background(), url)
+}
+
+// FromHTML is Extractor.FromHTML with the default configuration
+func FromHTML(body []byte, pageURL string) (string, error) {
+	return defaultExtractor.FromHTML(body, pageURL)
+}
+
+// Extract fetches the given URL and returns its main content using readability,
+// trimmed to the configured maximum. Articles split across several pages are
+// followed and stitched together before trimming. ctx bounds the downloads.
+func (e *Extractor) Extract(ctx context.Context, url string) (string, error) {
+	article, err := e.ExtractArticle(ctx, url)
+	if err != nil {
+		return "", err
+	}
+	return article.Text, nil
+}
+
+// ExtractArticle is Extract, also returning the text of each page stitched
+// together so quotes can be traced to the page they came from
+func (e *Extractor) ExtractArticle(ctx context.Context, url string) (*Article, error) {
 	// Fetch the page
 	log.Printf("Fetching URL: %s", url)
-	
-	// Create a custom client with TLS certificate verification disabled
-	// only in production environments to handle containerized deployments
-	var client *http.Client
-	_, inProduction := os.LookupEnv("FLY_APP_NAME")
-	if inProduction {
-		log.Printf("Disabling TLS verification for article extraction")
-		client = &http.Client{
-			Transport: &http.Transport{
-				TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-			},
-		}
-	} else {
-		client = http.DefaultClient
+
+	first, err := fetchPage(ctx, e.client, url, fetchLimits{maxBytes: e.maxContentLength})
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := parsePage(first)
+	if err != nil {
+		return nil, err
+	}
+
+	// Follow rel="next" / ?page=N links while within budget
+	pages := e.stitchPages(ctx, first, content)
+	texts := make([]string, len(pages))
+	for i, p := range pages {
+		texts[i] = p.Text
 	}
-	
-	resp, err := client.Get(url)
+
+	// Trim if needed
+	content = truncateUTF8(strings.Join(texts, "\n\n"), e.maxBytes)
+
+	return &Article{Text: content, Pages: pages}, nil
+}
+
+// FromHTML extracts the main content of an already-downloaded page, as
+// Extract does for fetched ones. pageURL resolves relative links and may be
+// empty. Pagination links are not followed.
+func (e *Extractor) FromHTML(body []byte, pageURL string) (string, error) {
+	u, err := url.Parse(pageURL)
 	if err != nil {
+		return "", fmt.Errorf("invalid page URL: %w", err)
+	}
+
+	body, _, err = toUTF8(body, "")
+	if err != nil {
+		return "", err
+	}
+
+	content, err := parsePage(&page{url: u, body: body})
+	if err != nil {
+		return "", err
+	}
+	return truncateUTF8(content, e.maxBytes), nil
+}
+
+// OriginBreakers holds a circuit breaker per origin host, so a site that is
+// down fails fast instead of costing every request a full timeout
+var OriginBreakers = breaker.NewSet(breaker.DefaultConfig)
+
+// fetchLimits bounds what fetchPage accepts
+type fetchLimits struct {
+	maxBytes int64 // larger bodies are rejected
+	htmlOnly bool  // responses declaring a non-HTML type are rejected
+}
+
+// fetchPage downloads url within limits and transcodes the body to UTF-8
+func fetchPage(ctx context.Context, client *http.Client, url string, limits fetchLimits) (*page, error) {
+	b := OriginBreakers.Get(hostOf(url))
+	if err := b.Allow(); err != nil {
+		log.Printf("Not fetching %s: %v", url, err)
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch URL: %w", err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		b.Record(err)
 		log.Printf("Failed to fetch URL %s: %v", url, err)
-		return "", fmt.Errorf("failed to fetch URL: %w", err)
+		return nil, fmt.Errorf("failed to fetch URL: %w", err)
 	}
 	defer resp.Body.Close()
 
+	// Only server errors count against the origin, and their error pages are
+	// never the article. 4xx pages are still parsed, since paywalls and
+	// consent walls often send them with content worth classifying.
+	if resp.StatusCode >= http.StatusInternalServerError {
+		err := fmt.Errorf("origin returned %s", resp.Status)
+		b.Record(err)
+		log.Printf("Failed to fetch URL %s: %v", url, err)
+		return nil, fmt.Errorf("failed to fetch URL: %w", err)
+	}
+	b.Record(nil)
+
 	// Log response status
 	log.Printf("Response status: %s", resp.Status)
 
-	// Parse with readability
-	// Read response body
-	body, err := io.ReadAll(resp.Body)
+	contentType := resp.Header.Get("Content-Type")
+	if limits.htmlOnly && !isHTML(contentType) {
+		return nil, fmt.Errorf("unsupported content type %q", contentType)
+	}
+	if resp.ContentLength > limits.maxBytes {
+		return nil, fmt.Errorf("content too large: %d bytes (max %d bytes)", resp.ContentLength, limits.maxBytes)
+	}
+
+	// Read response body, one byte past the limit to notice bodies exceeding
+	// it without a Content-Length
+	body, err := io.ReadAll(io.LimitReader(resp.Body, limits.maxBytes+1))
 	if err != nil {
 		log.Printf("Failed to read response body: %v", err)
-		return "", fmt.Errorf("failed to read response body: %w", err)
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	if int64(len(body)) > limits.maxBytes {
+		return nil, fmt.Errorf("content too large: over %d bytes", limits.maxBytes)
 	}
 	log.Printf("Read %d bytes from response body", len(body))
+	size := len(body)
+
+	// Transcode to UTF-8 so non-UTF-8 pages don't reach the LLM as mojibake
+	body, encName, err := toUTF8(body, contentType)
+	if err != nil {
+		log.Printf("Failed to transcode response body: %v", err)
+		return nil, err
+	}
+	log.Printf("Decoded response body from %s", encName)
+
+	return &page{url: resp.Request.URL, body: body, size: size}, nil
+}
+
+// isHTML reports whether a Content-Type is an HTML document. A missing type
+// is allowed, as many servers omit it.
+func isHTML(contentType string) bool {
+	if contentType == "" {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && (mediaType == "text/html" || mediaType == "application/xhtml+xml")
+}
 
-	// Parse with readability
+// hostOf returns the host of rawURL, or rawURL itself when it doesn't parse
+func hostOf(rawURL string) string {
+	if u, err := url.Parse(rawURL); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return rawURL
+}
+
+// parsePage runs readability over a fetched page and returns its text content
+func parsePage(p *page) (string, error) {
+	// Parse with readability, ignoring text the reader would never see
 	parser := readability.NewParser()
-	doc, err := parser.Parse(bytes.NewReader(body), resp.Request.URL)
+	doc, err := parser.Parse(bytes.NewReader(stripHidden(p.body)), p.url)
 	if err != nil {
 		log.Printf("Failed to parse content: %v", err)
+		if blocked := Classify(p.body, ""); blocked != nil {
+			return "", blocked
+		}
 		return "", fmt.Errorf("failed to extract article content - site may require JavaScript or have no extractable text: %w", err)
 	}
 	log.Printf("Successfully parsed content with readability")
 
+	// Reject consent walls, paywalls, JS shells and bot checks before they
+	// reach the LLM as if they were the article
+	content := stripInvisible(doc.TextContent)
+	if blocked := Classify(p.body, content); blocked != nil {
+		log.Printf("Page at %s classified as %s: %v", p.url, blocked.Kind, blocked)
+		return "", blocked
+	}
+
 	// Get content and validate
-	content := doc.TextContent
 	if content == "" {
-		log.Printf("No content extracted from URL %s - site may require JavaScript", url)
+		log.Printf("No content extracted from URL %s - site may require JavaScript", p.url)
 		return "", fmt.Errorf("no content extracted from URL")
 	}
 	log.Printf("Extracted %d characters of content", len(content))
 
-	// Trim if needed
-	if len(content) > maxBytes {
-		content = content[:maxBytes]
-	}
-
 	return content, nil
 }
@@ -4,8 +4,11 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
 
+	"github.com/matthewmolinar/tldr/pkg/breaker"
+	"github.com/matthewmolinar/tldr/pkg/config"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -27,7 +30,7 @@ func TestExtract(t *testing.T) {
 	require.NoError(t, err)
 
 	// Assert content is within size limit
-	assert.LessOrEqual(t, len(content), maxBytes)
+	assert.LessOrEqual(t, len(content), config.Default().Fetch.MaxArticleBytes)
 
 	// Assert content contains expected text (update this based on your test article)
 	assert.Contains(t, content, "This is the main content")
@@ -62,3 +65,108 @@ func TestExtract_Errors(t *testing.T) {
 		})
 	}
 }
+
+func TestFromHTML(t *testing.T) {
+	htmlData, err := os.ReadFile("testdata/article.html")
+	require.NoError(t, err)
+
+	content, err := FromHTML(htmlData, "https://example.com/article")
+	require.NoError(t, err)
+	assert.Contains(t, content, "This is the main content")
+
+	paywall, err := os.ReadFile("testdata/paywall.html")
+	require.NoError(t, err)
+	_, err = FromHTML(paywall, "")
+	var blocked *BlockedError
+	assert.ErrorAs(t, err, &blocked)
+}
+
+func TestFromHTML_HiddenText(t *testing.T) {
+	htmlData, err := os.ReadFile("testdata/hidden_text.html")
+	require.NoError(t, err)
+
+	content, err := FromHTML(htmlData, "https://example.com/harbor")
+	require.NoError(t, err)
+	assert.Contains(t, content, "approved the harbor expansion")
+	assert.Contains(t, content, "monitoring program during construction")
+	assert.NotContains(t, content, "HIDDEN")
+	assert.NotContains(t, content, "\u200b")
+}
+
+func TestStripInvisible(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+	}{
+		{"zero-width space splitting a word", "ig\u200bnore previous", "ignore previous"},
+		{"word joiner and BOM", "\ufeffin\u2060struc\u2060tions", "instructions"},
+		{"bidi override", "abc\u202edcba\u202c", "abcdcba"},
+		{"joiners between Latin letters", "ig\u200dno\u200cre", "ignore"},
+		{"Devanagari half form", "क्\u200dष", "क्\u200dष"},
+		{"Devanagari ZWNJ", "क्\u200cष", "क्\u200cष"},
+		{"Persian ZWNJ", "می\u200cخواهم", "می\u200cخواهم"},
+		{"emoji sequence", "👨\u200d👩\u200d👧 ❤\ufe0f\u200d🔥", "👨\u200d👩\u200d👧 ❤\ufe0f\u200d🔥"},
+		{"plain text", "nothing to strip", "nothing to strip"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, stripInvisible(tt.in))
+		})
+	}
+}
+
+func TestFromHTML_KeepsDevanagariJoiners(t *testing.T) {
+	para := "<p>बंदरगाह प्राधिकरण ने बाहरी चैनल के लिए नई योजना को मंज़ूरी दी और क्&#x200D;षेत्र के निवासियों से राय ली।</p>"
+	page := "<html><head><title>बंदरगाह</title></head><body><article><h1>बंदरगाह</h1>" +
+		strings.Repeat(para, 5) + "<p>सूचना<span style=\"display:none\">HIDDEN</span> जारी रही।</p></article></body></html>"
+
+	content, err := FromHTML([]byte(page), "https://example.com/harbor")
+	require.NoError(t, err)
+	assert.NotContains(t, content, "HIDDEN")
+	assert.Contains(t, content, "क्\u200dषेत्र", "ZWJ in a Devanagari conjunct is kept")
+}
+
+func TestHiddenStyle(t *testing.T) {
+	tests := []struct {
+		style string
+		want  bool
+	}{
+		{"display: none", true},
+		{"DISPLAY:NONE !important", true},
+		{"visibility:hidden", true},
+		{"opacity: 0", true},
+		{"font-size: 0", true},
+		{"font-size:1px", true},
+		{"font-size: 0.1em", true},
+		{"text-indent: -9999px", true},
+		{"display: block", false},
+		{"opacity: 0.8", false},
+		{"font-size: 14px", false},
+		{"font-size: 1.2em", false},
+		{"margin-left: -10px", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.style, func(t *testing.T) {
+			assert.Equal(t, tt.want, hiddenStyle(tt.style))
+		})
+	}
+}
+
+func TestExtract_OriginBreaker(t *testing.T) {
+	hits := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		http.Error(w, "upstream overloaded", http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	for i := 0; i < breaker.DefaultConfig.FailureThreshold; i++ {
+		_, err := Extract(ts.URL)
+		require.Error(t, err)
+		assert.NotErrorIs(t, err, breaker.ErrOpen)
+	}
+
+	_, err := Extract(ts.URL)
+	assert.ErrorIs(t, err, breaker.ErrOpen)
+	assert.Equal(t, breaker.DefaultConfig.FailureThreshold, hits, "open breaker skips the fetch")
+	assert.Contains(t, OriginBreakers.Open(), strings.TrimPrefix(ts.URL, "http://"))
+}
@@ -0,0 +1,177 @@
+package extract
+
+import (
+	"bytes"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// minVisibleFontPx is the smallest inline font size treated as readable.
+// Smaller text is a common way to hide instructions from human readers.
+const minVisibleFontPx = 3
+
+var (
+	fontSizeRe   = regexp.MustCompile(`font-size:([0-9.]+)(px|pt|em|rem|%)?`)
+	offscreenRe  = regexp.MustCompile(`(?:left|top|text-indent):-[0-9]{4,}`)
+	hiddenStyles = []string{"display:none", "visibility:hidden", "opacity:0;", "opacity:0.0;", "clip:rect(0", "clip-path:inset(50%", "max-height:0;", "height:0;overflow:hidden"}
+)
+
+// stripHidden removes elements a browser wouldn't show: hidden and
+// aria-hidden elements, templates, and inline styles that hide, shrink or move
+// text off screen. Pages use these to smuggle instructions to the LLM that
+// readers never see. Invisible characters are dropped from the remaining text,
+// since re-rendering would otherwise turn their character references into raw
+// bytes that readability's charset sniffing can misread. Unparseable bodies
+// are returned unchanged.
+func stripHidden(body []byte) []byte {
+	doc, err := html.Parse(bytes.NewReader(body))
+	if err != nil {
+		return body
+	}
+
+	removed, cleaned := removeHidden(doc)
+	if removed == 0 && cleaned == 0 {
+		return body
+	}
+	log.Printf("Removed %d hidden elements and cleaned %d text nodes before extraction", removed, cleaned)
+
+	var buf bytes.Buffer
+	if err := html.Render(&buf, doc); err != nil {
+		return body
+	}
+	return buf.Bytes()
+}
+
+// removeHidden detaches hidden descendants of n and strips invisible
+// characters from the text left behind. It returns how many elements were
+// removed and how many text nodes were changed.
+func removeHidden(n *html.Node) (removed, cleaned int) {
+	for child := n.FirstChild; child != nil; {
+		next := child.NextSibling
+		switch {
+		case child.Type == html.ElementNode && isHidden(child):
+			n.RemoveChild(child)
+			removed++
+		case child.Type == html.TextNode:
+			if text := stripInvisible(child.Data); text != child.Data {
+				child.Data = text
+				cleaned++
+			}
+		default:
+			r, c := removeHidden(child)
+			removed += r
+			cleaned += c
+		}
+		child = next
+	}
+	return removed, cleaned
+}
+
+func isHidden(n *html.Node) bool {
+	if n.DataAtom == atom.Template {
+		return true
+	}
+	for _, a := range n.Attr {
+		switch strings.ToLower(a.Key) {
+		case "hidden":
+			return true
+		case "aria-hidden":
+			if strings.EqualFold(strings.TrimSpace(a.Val), "true") {
+				return true
+			}
+		case "style":
+			if hiddenStyle(a.Val) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// hiddenStyle reports whether an inline style attribute hides its element
+func hiddenStyle(style string) bool {
+	s := strings.ToLower(strings.Join(strings.Fields(style), "")) + ";"
+	s = strings.ReplaceAll(s, "!important", "")
+	for _, h := range hiddenStyles {
+		if strings.Contains(s, h) {
+			return true
+		}
+	}
+	if offscreenRe.MatchString(s) {
+		return true
+	}
+	if m := fontSizeRe.FindStringSubmatch(s); m != nil {
+		size, err := strconv.ParseFloat(m[1], 64)
+		if err != nil {
+			return false
+		}
+		switch m[2] {
+		case "em", "rem":
+			size *= 16
+		case "%":
+			size *= 16.0 / 100
+		}
+		return size < minVisibleFontPx
+	}
+	return false
+}
+
+// joiningScripts use ZWJ and ZWNJ to choose letter forms, so the joiners
+// are part of the text there
+var joiningScripts = []*unicode.RangeTable{
+	unicode.Arabic, unicode.Syriac, unicode.Nko, unicode.Mongolian,
+	unicode.Devanagari, unicode.Bengali, unicode.Gurmukhi, unicode.Gujarati, unicode.Oriya,
+	unicode.Tamil, unicode.Telugu, unicode.Kannada, unicode.Malayalam, unicode.Sinhala,
+	unicode.Myanmar, unicode.Khmer,
+}
+
+// stripInvisible removes zero-width and bidirectional control characters,
+// which can hide text from readers or split words to dodge filters. ZWJ and
+// ZWNJ are kept next to joining-script letters and emoji, where they shape
+// the text rather than hide it.
+func stripInvisible(s string) string {
+	if !strings.ContainsFunc(s, isInvisible) {
+		return s
+	}
+	runes := []rune(s)
+	var b strings.Builder
+	b.Grow(len(s))
+	for i, r := range runes {
+		switch {
+		case r == '\u200c', r == '\u200d':
+			if i > 0 && joins(runes[i-1]) || i+1 < len(runes) && joins(runes[i+1]) {
+				b.WriteRune(r)
+			}
+		case isInvisible(r):
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func isInvisible(r rune) bool {
+	switch {
+	case r == '\u200b', r == '\u200c', r == '\u200d', r == '\u2060', r == '\ufeff':
+		return true
+	case r >= '\u202a' && r <= '\u202e', r >= '\u2066' && r <= '\u2069':
+		return true
+	}
+	return false
+}
+
+// joins reports whether a ZWJ or ZWNJ next to r is shaping text: r is a
+// joining-script character or part of an emoji sequence
+func joins(r rune) bool {
+	switch {
+	case r >= 0x1f000 && r <= 0x1faff, r >= 0x2600 && r <= 0x27bf, r == '\ufe0f':
+		return true
+	}
+	return unicode.In(r, joiningScripts...)
+}
@@ -0,0 +1,184 @@
+package extract
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+const (
+	maxPages           = 5               // first page plus up to four continuations
+	maxPaginationBytes = 2 * 1024 * 1024 // HTML fetched across all pages of one article
+)
+
+// pathPageRe matches WordPress-style /page/N continuation paths
+var pathPageRe = regexp.MustCompile(`/page/(\d+)/?$`)
+
+// stitchPages follows pagination links from the first page of an article and
+// returns its text followed by that of each continuation. Continuations must
+// be HTML within the extractor's maximum content length, and are read only
+// up to what remains of maxPaginationBytes. It stops at maxPages, once the
+// byte budget is spent, once the stitched text is long enough to fill the
+// extractor's maxBytes, or on the first page that fails.
+// Lines already seen on earlier pages (bylines, share prompts, related links)
+// are dropped so repeated boilerplate isn't summarized twice.
+func (e *Extractor) stitchPages(ctx context.Context, first *page, content string) []Page {
+	seen := make(map[string]bool)
+	dedupeLines(content, seen)
+
+	visited := map[string]bool{pageKey(first.url): true}
+	pages := []Page{{URL: first.url.String(), Text: content}}
+	stitched := len(content)
+	fetched := first.size
+
+	current := first
+	for n := 1; n < maxPages && stitched < e.maxBytes; n++ {
+		next := nextPageURL(current)
+		if next == nil || visited[pageKey(next)] {
+			break
+		}
+		visited[pageKey(next)] = true
+
+		remaining := int64(maxPaginationBytes - fetched)
+		if remaining <= 0 {
+			log.Printf("Stopping pagination: fetched %d bytes (max %d bytes)", fetched, maxPaginationBytes)
+			break
+		}
+
+		log.Printf("Following pagination link: %s", next)
+		p, err := fetchPage(ctx, e.client, next.String(), fetchLimits{
+			maxBytes: min(remaining, e.maxContentLength),
+			htmlOnly: true,
+		})
+		if err != nil {
+			log.Printf("Stopping pagination at %s: %v", next, err)
+			break
+		}
+		fetched += p.size
+
+		text, err := parsePage(p)
+		if err != nil {
+			log.Printf("Stopping pagination at %s: %v", next, err)
+			break
+		}
+		text = dedupeLines(text, seen)
+		if text == "" {
+			break
+		}
+
+		pages = append(pages, Page{URL: p.url.String(), Text: text})
+		stitched += len(text)
+		current = p
+	}
+
+	if len(pages) > 1 {
+		log.Printf("Stitched %d pages into %d characters of content", len(pages), stitched)
+	}
+	return pages
+}
+
+// nextPageURL finds the continuation of p on the same host. An explicit
+// rel="next" link wins; otherwise a link to the following ?page=N or
+// /page/N of the same path is used.
+func nextPageURL(p *page) *url.URL {
+	current, base := pageNumber(p.url)
+	var numbered *url.URL
+
+	z := html.NewTokenizer(bytes.NewReader(p.body))
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			return numbered
+		}
+		if tt != html.StartTagToken && tt != html.SelfClosingTagToken {
+			continue
+		}
+		name, hasAttr := z.TagName()
+		if !hasAttr || (string(name) != "a" && string(name) != "link") {
+			continue
+		}
+
+		var href, rel string
+		for more := true; more; {
+			var key, val []byte
+			key, val, more = z.TagAttr()
+			switch string(key) {
+			case "href":
+				href = string(val)
+			case "rel":
+				rel = strings.ToLower(string(val))
+			}
+		}
+		if href == "" {
+			continue
+		}
+
+		u, err := p.url.Parse(href)
+		if err != nil || u.Scheme != p.url.Scheme || u.Host != p.url.Host {
+			continue
+		}
+		u.Fragment = ""
+
+		if hasToken(rel, "next") {
+			return u
+		}
+		if numbered == nil {
+			if n, b := pageNumber(u); n == current+1 && b == base {
+				numbered = u
+			}
+		}
+	}
+}
+
+// pageNumber returns the page index encoded in u (1 when absent) and the
+// path with any /page/N suffix removed
+func pageNumber(u *url.URL) (int, string) {
+	if m := pathPageRe.FindStringSubmatch(u.Path); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		return n, strings.TrimSuffix(u.Path, m[0])
+	}
+	if n, err := strconv.Atoi(u.Query().Get("page")); err == nil {
+		return n, u.Path
+	}
+	return 1, u.Path
+}
+
+// pageKey identifies a page for loop detection
+func pageKey(u *url.URL) string {
+	c := *u
+	c.Fragment = ""
+	return c.String()
+}
+
+// dedupeLines drops lines of text already recorded in seen and records the
+// rest. Comparison ignores surrounding and repeated whitespace.
+func dedupeLines(text string, seen map[string]bool) string {
+	var kept []string
+	for _, line := range strings.Split(text, "\n") {
+		key := strings.Join(strings.Fields(line), " ")
+		if key == "" {
+			continue
+		}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		kept = append(kept, line)
+	}
+	return strings.Join(kept, "\n")
+}
+
+func hasToken(list, token string) bool {
+	for _, t := range strings.Fields(list) {
+		if t == token {
+			return true
+		}
+	}
+	return false
+}
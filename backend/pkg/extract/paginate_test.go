@@ -0,0 +1,191 @@
+package extract
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/matthewmolinar/tldr/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// paginatedArticle renders page n of a story with shared boilerplate and a
+// link to the following page produced by next
+func paginatedArticle(n int, next string) string {
+	return fmt.Sprintf(`<!DOCTYPE html>
+<html>
+<head><title>Harbor expansion</title>%s</head>
+<body>
+    <article>
+        <h1>Harbor expansion</h1>
+        <p>Share this story with a friend.</p>
+        <p>Section %d of the harbor expansion report covers dredging, berths and the new container cranes in detail.</p>
+    </article>
+</body>
+</html>`, next, n)
+}
+
+func TestExtract_Pagination(t *testing.T) {
+	t.Run("follows ?page=N links and drops repeated boilerplate", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			n, err := strconv.Atoi(r.URL.Query().Get("page"))
+			if err != nil {
+				n = 1
+			}
+			next := ""
+			if n < 3 {
+				next = fmt.Sprintf(`<a href="/story?page=%d">Next</a>`, n+1)
+			}
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, strings.Replace(paginatedArticle(n, ""), "</article>", next+"</article>", 1))
+		}))
+		defer ts.Close()
+
+		article, err := defaultExtractor.ExtractArticle(context.Background(), ts.URL+"/story")
+		require.NoError(t, err)
+		content := article.Text
+
+		for n := 1; n <= 3; n++ {
+			assert.Contains(t, content, fmt.Sprintf("Section %d of the harbor", n))
+		}
+		assert.Equal(t, 1, strings.Count(content, "Share this story"))
+
+		// Each page keeps its URL, for citing what came from it
+		require.Len(t, article.Pages, 3)
+		assert.Equal(t, ts.URL+"/story", article.Pages[0].URL)
+		assert.Equal(t, ts.URL+"/story?page=3", article.Pages[2].URL)
+		assert.Contains(t, article.Pages[2].Text, "Section 3 of the harbor")
+		assert.NotContains(t, article.Pages[2].Text, "Section 2 of the harbor")
+	})
+
+	t.Run("follows rel=next links and stops on loops", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			n := 1
+			next := `<link rel="next" href="/story/page/2">`
+			if r.URL.Path == "/story/page/2" {
+				n = 2
+				next = `<link rel="next" href="/story">`
+			}
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, paginatedArticle(n, next))
+		}))
+		defer ts.Close()
+
+		content, err := Extract(ts.URL + "/story")
+		require.NoError(t, err)
+		assert.Contains(t, content, "Section 1 of the harbor")
+		assert.Contains(t, content, "Section 2 of the harbor")
+		assert.Equal(t, 1, strings.Count(content, "Section 1 of the harbor"))
+	})
+
+	t.Run("stops at the page budget", func(t *testing.T) {
+		var requests atomic.Int32
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests.Add(1)
+			n, err := strconv.Atoi(r.URL.Query().Get("page"))
+			if err != nil {
+				n = 1
+			}
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, paginatedArticle(n, fmt.Sprintf(`<link rel="next" href="?page=%d">`, n+1)))
+		}))
+		defer ts.Close()
+
+		content, err := Extract(ts.URL + "/story")
+		require.NoError(t, err)
+		assert.Equal(t, int32(maxPages), requests.Load())
+		assert.NotContains(t, content, fmt.Sprintf("Section %d of the harbor", maxPages+1))
+	})
+
+	t.Run("skips continuations that aren't HTML or are too large", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/pdf/page/2":
+				w.Header().Set("Content-Type", "application/pdf")
+				fmt.Fprint(w, paginatedArticle(2, ""))
+			case "/big/page/2":
+				w.Header().Set("Content-Type", "text/html")
+				w.Header().Set("Content-Length", "20000")
+				fmt.Fprint(w, paginatedArticle(2, "")+strings.Repeat(" ", 20000-len(paginatedArticle(2, ""))))
+			default:
+				w.Header().Set("Content-Type", "text/html")
+				fmt.Fprint(w, paginatedArticle(1, `<link rel="next" href="`+r.URL.Path+`/page/2">`))
+			}
+		}))
+		defer ts.Close()
+
+		e := New(config.Fetch{MaxArticleBytes: 8192, MaxContentLength: 10000}, nil)
+		for _, path := range []string{"/pdf", "/big"} {
+			content, err := e.Extract(context.Background(), ts.URL+path)
+			require.NoError(t, err)
+			assert.Contains(t, content, "Section 1 of the harbor")
+			assert.NotContains(t, content, "Section 2 of the harbor", path)
+		}
+	})
+
+	t.Run("reads continuations only up to the byte budget", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/html")
+			if r.URL.Query().Get("page") == "" {
+				fmt.Fprint(w, paginatedArticle(1, `<link rel="next" href="?page=2">`))
+				return
+			}
+			// An endless body with no Content-Length, which would never
+			// finish downloading were it read in full
+			fmt.Fprint(w, paginatedArticle(2, ""))
+			chunk := strings.Repeat("<p>filler</p>", 1024)
+			for {
+				if _, err := io.WriteString(w, chunk); err != nil {
+					return
+				}
+			}
+		}))
+		defer ts.Close()
+
+		content, err := Extract(ts.URL + "/story")
+		require.NoError(t, err)
+		assert.NotContains(t, content, "Section 2 of the harbor")
+	})
+
+	t.Run("ignores links to other hosts", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, paginatedArticle(1, `<link rel="next" href="https://elsewhere.example/story?page=2">`))
+		}))
+		defer ts.Close()
+
+		content, err := Extract(ts.URL + "/story")
+		require.NoError(t, err)
+		assert.Contains(t, content, "Section 1 of the harbor")
+	})
+}
+
+func TestPageNumber(t *testing.T) {
+	tests := []struct {
+		raw      string
+		wantN    int
+		wantBase string
+	}{
+		{raw: "https://example.com/story", wantN: 1, wantBase: "/story"},
+		{raw: "https://example.com/story?page=3", wantN: 3, wantBase: "/story"},
+		{raw: "https://example.com/story/page/2/", wantN: 2, wantBase: "/story"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			u, err := url.Parse(tt.raw)
+			require.NoError(t, err)
+			n, base := pageNumber(u)
+			assert.Equal(t, tt.wantN, n)
+			assert.Equal(t, tt.wantBase, base)
+		})
+	}
+}
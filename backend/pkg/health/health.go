@@ -0,0 +1,157 @@
+// Package health runs dependency checks for readiness probes. Checks run
+// concurrently with a timeout each, and costly ones can cache their result
+// so frequent probes don't turn into load on the dependency.
+package health
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Status is the outcome of a check or of a whole report
+type Status string
+
+const (
+	StatusOK       Status = "ok"
+	StatusDegraded Status = "degraded" // a non-critical check failed
+	StatusFail     Status = "fail"     // a critical check failed
+)
+
+// defaultTimeout bounds checks that set no Timeout
+const defaultTimeout = 2 * time.Second
+
+// Check is one dependency check
+type Check struct {
+	Name string
+
+	// Run reports whether the dependency is usable; nil means healthy
+	Run func(ctx context.Context) error
+
+	// Critical checks fail readiness; others only mark it degraded
+	Critical bool
+
+	// Interval reuses a result this long, for probes too costly to run on
+	// every request; zero runs the check every time
+	Interval time.Duration
+
+	// Timeout bounds one run; zero uses 2s
+	Timeout time.Duration
+}
+
+// Result is the latest outcome of one check
+type Result struct {
+	Status    Status    `json:"status"`
+	Error     string    `json:"error,omitempty"`
+	Critical  bool      `json:"critical,omitempty"`
+	CheckedAt time.Time `json:"checked_at"`
+	LatencyMS int64     `json:"latency_ms"`
+}
+
+// Report is the outcome of every registered check
+type Report struct {
+	Status Status            `json:"status"`
+	Checks map[string]Result `json:"checks"`
+}
+
+// Ready reports whether every critical check passed
+func (r Report) Ready() bool {
+	return r.Status != StatusFail
+}
+
+type entry struct {
+	Check
+
+	mu     sync.Mutex // held while running, so concurrent probes share one run
+	last   Result
+	ranAt  time.Time
+	hasRun bool
+}
+
+// Registry holds the checks behind a readiness endpoint
+type Registry struct {
+	mu      sync.Mutex
+	entries []*entry
+
+	now func() time.Time
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{now: time.Now}
+}
+
+// Register adds a check, replacing any registered under the same name
+func (r *Registry) Register(c Check) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, e := range r.entries {
+		if e.Name == c.Name {
+			r.entries[i] = &entry{Check: c}
+			return
+		}
+	}
+	r.entries = append(r.entries, &entry{Check: c})
+	sort.Slice(r.entries, func(i, j int) bool { return r.entries[i].Name < r.entries[j].Name })
+}
+
+// Run runs the checks, or reuses their cached results, and summarizes them
+func (r *Registry) Run(ctx context.Context) Report {
+	r.mu.Lock()
+	entries := append([]*entry(nil), r.entries...)
+	r.mu.Unlock()
+
+	results := make([]Result, len(entries))
+	var wg sync.WaitGroup
+	for i, e := range entries {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = r.run(ctx, e)
+		}()
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusOK, Checks: make(map[string]Result, len(entries))}
+	for i, e := range entries {
+		res := results[i]
+		report.Checks[e.Name] = res
+		switch {
+		case res.Status == StatusFail && e.Critical:
+			report.Status = StatusFail
+		case res.Status == StatusFail && report.Status == StatusOK:
+			report.Status = StatusDegraded
+		}
+	}
+	return report
+}
+
+func (r *Registry) run(ctx context.Context, e *entry) Result {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.hasRun && e.Interval > 0 && r.now().Sub(e.ranAt) < e.Interval {
+		return e.last
+	}
+
+	timeout := e.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := r.now()
+	err := e.Run(ctx)
+	res := Result{
+		Status:    StatusOK,
+		Critical:  e.Critical,
+		CheckedAt: start,
+		LatencyMS: r.now().Sub(start).Milliseconds(),
+	}
+	if err != nil {
+		res.Status, res.Error = StatusFail, err.Error()
+	}
+	e.last, e.ranAt, e.hasRun = res, start, true
+	return res
+}
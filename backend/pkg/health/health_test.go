@@ -0,0 +1,66 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistry_Status(t *testing.T) {
+	fail := func(context.Context) error { return errors.New("down") }
+	pass := func(context.Context) error { return nil }
+
+	r := NewRegistry()
+	assert.Equal(t, StatusOK, r.Run(context.Background()).Status, "no checks is ready")
+
+	r.Register(Check{Name: "db", Critical: true, Run: pass})
+	r.Register(Check{Name: "search", Run: fail})
+	report := r.Run(context.Background())
+	assert.Equal(t, StatusDegraded, report.Status)
+	assert.True(t, report.Ready())
+	assert.Equal(t, StatusOK, report.Checks["db"].Status)
+	assert.Equal(t, "down", report.Checks["search"].Error)
+
+	r.Register(Check{Name: "db", Critical: true, Run: fail})
+	report = r.Run(context.Background())
+	assert.Equal(t, StatusFail, report.Status)
+	assert.False(t, report.Ready())
+	assert.Len(t, report.Checks, 2, "re-registering replaces the check")
+}
+
+func TestRegistry_Interval(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	r := NewRegistry()
+	r.now = func() time.Time { return now }
+
+	var runs atomic.Int32
+	r.Register(Check{Name: "provider", Interval: time.Minute, Run: func(context.Context) error {
+		runs.Add(1)
+		return nil
+	}})
+
+	r.Run(context.Background())
+	now = now.Add(30 * time.Second)
+	r.Run(context.Background())
+	assert.Equal(t, int32(1), runs.Load(), "result is reused within the interval")
+
+	now = now.Add(time.Minute)
+	r.Run(context.Background())
+	assert.Equal(t, int32(2), runs.Load())
+}
+
+func TestRegistry_Timeout(t *testing.T) {
+	r := NewRegistry()
+	r.Register(Check{Name: "slow", Critical: true, Timeout: 10 * time.Millisecond, Run: func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}})
+
+	report := r.Run(context.Background())
+	assert.False(t, report.Ready())
+	assert.Equal(t, context.DeadlineExceeded.Error(), report.Checks["slow"].Error)
+}
@@ -0,0 +1,158 @@
+// Package lang provides lightweight language detection and display-width
+// budgeting for multilingual summaries
+package lang
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Default is the summary language used when neither the request nor the
+// source text determines one
+const Default = "en"
+
+// sampleRunes bounds how much of the text Detect looks at
+const sampleRunes = 4000
+
+// names maps supported ISO 639-1 codes to the English language name used in prompts
+var names = map[string]string{
+	"ar": "Arabic",
+	"de": "German",
+	"el": "Greek",
+	"en": "English",
+	"es": "Spanish",
+	"fr": "French",
+	"he": "Hebrew",
+	"hi": "Hindi",
+	"it": "Italian",
+	"ja": "Japanese",
+	"ko": "Korean",
+	"nl": "Dutch",
+	"pt": "Portuguese",
+	"ru": "Russian",
+	"th": "Thai",
+	"uk": "Ukrainian",
+	"zh": "Chinese",
+}
+
+// stopwords are frequent function words used to tell Latin-script languages apart
+var stopwords = map[string][]string{
+	"en": {"the", "and", "of", "to", "is", "in", "that", "it", "was", "for", "with", "on"},
+	"es": {"el", "la", "de", "que", "y", "los", "las", "en", "del", "por", "una", "es"},
+	"fr": {"le", "la", "les", "de", "des", "et", "est", "une", "du", "que", "dans", "pour"},
+	"de": {"der", "die", "das", "und", "ist", "nicht", "ein", "eine", "mit", "den", "auf", "zu"},
+	"it": {"il", "di", "che", "e", "la", "per", "non", "una", "sono", "della", "gli", "nel"},
+	"pt": {"o", "de", "que", "e", "do", "da", "em", "um", "para", "não", "uma", "os"},
+	"nl": {"de", "het", "een", "en", "van", "is", "niet", "dat", "op", "te", "zijn", "voor"},
+}
+
+// Name returns the English name of a supported language code
+func Name(code string) (string, bool) {
+	name, ok := names[strings.ToLower(code)]
+	return name, ok
+}
+
+// Supported reports whether code is a language summaries can be written in
+func Supported(code string) bool {
+	_, ok := names[strings.ToLower(code)]
+	return ok
+}
+
+// Detect guesses the ISO 639-1 code of text's language. Non-Latin scripts are
+// identified by their Unicode ranges and Latin-script languages by stopword
+// frequency. It returns "" when the text gives too little signal.
+func Detect(text string) string {
+	scripts := make(map[string]int)
+	var kana, letters int
+	n := 0
+	for _, r := range text {
+		if n >= sampleRunes {
+			break
+		}
+		n++
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		switch {
+		case unicode.In(r, unicode.Hiragana, unicode.Katakana):
+			kana++
+			scripts["ja"]++
+		case unicode.Is(unicode.Han, r):
+			scripts["han"]++
+		case unicode.Is(unicode.Hangul, r):
+			scripts["ko"]++
+		case unicode.Is(unicode.Cyrillic, r):
+			scripts["cyrillic"]++
+		case unicode.Is(unicode.Arabic, r):
+			scripts["ar"]++
+		case unicode.Is(unicode.Hebrew, r):
+			scripts["he"]++
+		case unicode.Is(unicode.Greek, r):
+			scripts["el"]++
+		case unicode.Is(unicode.Devanagari, r):
+			scripts["hi"]++
+		case unicode.Is(unicode.Thai, r):
+			scripts["th"]++
+		case unicode.Is(unicode.Latin, r):
+			scripts["latin"]++
+		}
+	}
+	if letters == 0 {
+		return ""
+	}
+
+	script, best := "", 0
+	for s, count := range scripts {
+		if count > best || (count == best && s < script) {
+			script, best = s, count
+		}
+	}
+	// Japanese mixes kana with Han; a modest share of kana is decisive
+	if (script == "han" || script == "ja") && kana*10 >= scripts["han"]+kana {
+		return "ja"
+	}
+
+	switch script {
+	case "han":
+		return "zh"
+	case "cyrillic":
+		if strings.ContainsAny(text, "іїєґІЇЄҐ") {
+			return "uk"
+		}
+		return "ru"
+	case "latin":
+		return detectLatin(text)
+	default:
+		return script
+	}
+}
+
+// detectLatin scores Latin-script text against each stopword list
+func detectLatin(text string) string {
+	counts := make(map[string]int)
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	if len(words) > sampleRunes/4 {
+		words = words[:sampleRunes/4]
+	}
+	for _, w := range words {
+		counts[w]++
+	}
+
+	code, best := "", 0
+	for c, list := range stopwords {
+		score := 0
+		for _, sw := range list {
+			score += counts[sw]
+		}
+		if score > best || (score == best && c < code) {
+			code, best = c, score
+		}
+	}
+	if best < 2 {
+		return ""
+	}
+	return code
+}
@@ -0,0 +1,61 @@
+package lang
+
+import (
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{name: "english", text: "The council voted on the budget and it was approved with a large majority for the first time.", want: "en"},
+		{name: "spanish", text: "El gobierno anunció que la nueva ley de vivienda entrará en vigor el próximo mes en todo el país.", want: "es"},
+		{name: "french", text: "Le gouvernement a annoncé que la nouvelle loi sur le logement est entrée en vigueur dans tout le pays.", want: "fr"},
+		{name: "german", text: "Die Regierung hat angekündigt, dass das neue Gesetz nicht vor dem Sommer in Kraft tritt und die Mieten begrenzt.", want: "de"},
+		{name: "japanese", text: "市の中心部に新しい図書館が開館し、初日には多くの市民が訪れました。", want: "ja"},
+		{name: "chinese", text: "经过一年的施工，城市中心公园完成改造并重新向市民开放。", want: "zh"},
+		{name: "korean", text: "사흘 동안 열린 지역 축제가 성황리에 마무리되었습니다.", want: "ko"},
+		{name: "russian", text: "Правительство объявило о новых мерах поддержки малого бизнеса.", want: "ru"},
+		{name: "ukrainian", text: "Уряд оголосив про нові заходи підтримки малого бізнесу.", want: "uk"},
+		{name: "arabic", text: "أعلنت الحكومة عن إجراءات جديدة لدعم الشركات الصغيرة.", want: "ar"},
+		{name: "no letters", text: "12345 !!! 67", want: ""},
+		{name: "too little signal", text: "Hello", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Detect(tt.text))
+		})
+	}
+}
+
+func TestName(t *testing.T) {
+	name, ok := Name("JA")
+	assert.True(t, ok)
+	assert.Equal(t, "Japanese", name)
+
+	_, ok = Name("xx")
+	assert.False(t, ok)
+}
+
+func TestWidth(t *testing.T) {
+	assert.Equal(t, 5, Width("hello"))
+	assert.Equal(t, 6, Width("日本語"))
+	assert.Equal(t, 6, Width("한국어"))
+	assert.Equal(t, 4, Width("café"))
+}
+
+func TestTruncate(t *testing.T) {
+	assert.Equal(t, "short", Truncate("short", 10))
+	assert.Equal(t, "hello…", Truncate("hello world", 7))
+
+	got := Truncate("新しい図書館が開館", 9)
+	assert.LessOrEqual(t, Width(got), 9)
+	assert.True(t, utf8.ValidString(got))
+	assert.Equal(t, "新しい図…", got)
+}
@@ -0,0 +1,55 @@
+package lang
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Width returns the display width of s in character cells. Han, kana, Hangul
+// and fullwidth forms count double, the same weighting social platforms use,
+// so a 280-cell budget holds comparable information across scripts.
+func Width(s string) int {
+	w := 0
+	for _, r := range s {
+		w += runeWidth(r)
+	}
+	return w
+}
+
+// Truncate shortens s to at most width cells, cutting on a rune boundary
+// and marking the cut with an ellipsis
+func Truncate(s string, width int) string {
+	if Width(s) <= width {
+		return s
+	}
+	const ellipsis = "…"
+	limit := width - Width(ellipsis)
+	if limit <= 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	w := 0
+	for _, r := range s {
+		rw := runeWidth(r)
+		if w+rw > limit {
+			break
+		}
+		b.WriteRune(r)
+		w += rw
+	}
+	return strings.TrimRightFunc(b.String(), unicode.IsSpace) + ellipsis
+}
+
+func runeWidth(r rune) int {
+	switch {
+	case unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul):
+		return 2
+	case r >= 0xFF01 && r <= 0xFF60, r >= 0xFFE0 && r <= 0xFFE6: // fullwidth forms
+		return 2
+	case r >= 0x3000 && r <= 0x303F: // CJK punctuation
+		return 2
+	default:
+		return 1
+	}
+}
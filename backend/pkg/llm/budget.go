@@ -0,0 +1,46 @@
+package llm
+
+import "github.com/matthewmolinar/tldr/pkg/lang"
+
+// MaxSummaryWidth is the PRD's tweetable budget for the headline plus all
+// bullets, measured in display cells so CJK summaries aren't twice as long
+const MaxSummaryWidth = 280
+
+// minBulletWidth is the shortest a bullet may be cut to before it is dropped
+const minBulletWidth = 24
+
+// isWideScript reports whether a language is written mostly in double-width characters
+func isWideScript(language string) bool {
+	switch language {
+	case "ja", "zh", "ko":
+		return true
+	}
+	return false
+}
+
+// fitBudget trims a summary that the model let run over budget. Bullets are
+// shortened from the last one backwards, and dropped when too little room is
+// left for them to stay meaningful; the headline is cut only as a last resort.
+func fitBudget(headline string, bullets []string, budget int) (string, []string) {
+	total := lang.Width(headline)
+	for _, b := range bullets {
+		total += lang.Width(b)
+	}
+
+	for i := len(bullets) - 1; i >= 0 && total > budget; i-- {
+		over := total - budget
+		w := lang.Width(bullets[i])
+		if w-over >= minBulletWidth {
+			bullets[i] = lang.Truncate(bullets[i], w-over)
+			total -= w - lang.Width(bullets[i])
+			continue
+		}
+		bullets = bullets[:i]
+		total -= w
+	}
+
+	if total > budget {
+		headline = lang.Truncate(headline, budget)
+	}
+	return headline, bullets
+}
@@ -0,0 +1,46 @@
+package llm
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/matthewmolinar/tldr/pkg/lang"
+	"github.com/stretchr/testify/assert"
+)
+
+func summaryWidth(headline string, bullets []string) int {
+	w := lang.Width(headline)
+	for _, b := range bullets {
+		w += lang.Width(b)
+	}
+	return w
+}
+
+func TestFitBudget(t *testing.T) {
+	t.Run("leaves summaries within budget untouched", func(t *testing.T) {
+		headline, bullets := fitBudget("Short headline", []string{"One", "Two", "Three"}, MaxSummaryWidth)
+		assert.Equal(t, "Short headline", headline)
+		assert.Equal(t, []string{"One", "Two", "Three"}, bullets)
+	})
+
+	t.Run("shortens the last bullet first", func(t *testing.T) {
+		bullets := []string{strings.Repeat("a", 80), strings.Repeat("b", 80), strings.Repeat("c", 80)}
+		headline, got := fitBudget(strings.Repeat("h", 60), bullets, MaxSummaryWidth)
+		assert.Len(t, got, 3)
+		assert.Equal(t, strings.Repeat("a", 80), got[0])
+		assert.LessOrEqual(t, summaryWidth(headline, got), MaxSummaryWidth)
+	})
+
+	t.Run("counts CJK characters as double width", func(t *testing.T) {
+		bullets := []string{strings.Repeat("図", 50), strings.Repeat("書", 50), strings.Repeat("館", 50)}
+		headline, got := fitBudget(strings.Repeat("新", 20), bullets, MaxSummaryWidth)
+		assert.LessOrEqual(t, summaryWidth(headline, got), MaxSummaryWidth)
+		assert.Equal(t, strings.Repeat("新", 20), headline)
+	})
+
+	t.Run("cuts the headline when it alone is over budget", func(t *testing.T) {
+		headline, got := fitBudget(strings.Repeat("h", 300), []string{"bullet"}, MaxSummaryWidth)
+		assert.Empty(t, got)
+		assert.LessOrEqual(t, lang.Width(headline), MaxSummaryWidth)
+	})
+}
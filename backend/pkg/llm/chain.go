@@ -0,0 +1,36 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"log"
+)
+
+// Chain tries each summarizer in turn, moving on when one fails.
+// Put Extractive last so callers get some summary even when every provider
+// is down.
+type Chain []Summarizer
+
+// Summarize returns the first summary a link produces. Any failure moves on
+// to the next link, since an unknown style or an empty article fails the
+// same way everywhere, except a content filter refusal, which the fallback
+// must not route around, invalid sampling parameters, which are the caller's
+// to fix, and a cancelled ctx, since the caller is gone.
+func (c Chain) Summarize(ctx context.Context, req Request) (*Summary, error) {
+	err := errors.New("no summarizers configured")
+	for i, s := range c {
+		var summary *Summary
+		summary, err = s.Summarize(ctx, req)
+		if err == nil {
+			return summary, nil
+		}
+		if errors.Is(err, ErrContentFiltered) || errors.Is(err, ErrInvalidParams) ||
+			errors.Is(err, context.Canceled) || ctx.Err() == context.Canceled {
+			return nil, err
+		}
+		if i < len(c)-1 {
+			log.Printf("Summarizer %d of %d failed, falling back: %v", i+1, len(c), err)
+		}
+	}
+	return nil, err
+}
@@ -0,0 +1,202 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sort"
+
+	"github.com/matthewmolinar/tldr/pkg/lang"
+	"github.com/matthewmolinar/tldr/pkg/nlp"
+)
+
+// ExtractiveModel is the Summary.Model of extractive summaries
+const ExtractiveModel = "extractive"
+
+const (
+	// maxExtractiveSentences bounds the O(n²) similarity graph. Articles are
+	// usually trimmed to MAX_ARTICLE_BYTES, so this bites on choppy text and
+	// the long articles kept for long-context models
+	maxExtractiveSentences = 300
+
+	// minSentenceTokens drops captions, bylines and other fragments
+	minSentenceTokens = 3
+
+	textRankDamping    = 0.85
+	textRankIterations = 100
+	textRankTolerance  = 1e-6
+)
+
+// Extractive summarizes without an LLM by picking the article's most central
+// sentences: TextRank over a TF-IDF cosine similarity graph, with a slight
+// preference for early sentences, where news puts its key facts. The best
+// sentence becomes the headline and the next ones the bullets, in article
+// order. It is deterministic, needs no network, and ignores ctx, so it still
+// answers as the last link of a Chain after the LLM calls timed out.
+//
+// The summary is written in the article's own language whatever the request
+// asks for.
+type Extractive struct{}
+
+func (Extractive) Summarize(ctx context.Context, req Request) (*Summary, error) {
+	style, err := LookupStyle(req.Style)
+	if err != nil {
+		return nil, err
+	}
+	n, err := style.BulletCount(req.Bullets)
+	if err != nil {
+		return nil, err
+	}
+
+	var sentences []string
+	var tokens [][]string
+	for _, s := range nlp.Sentences(req.Text) {
+		if t := nlp.ContentTokens(s); len(t) >= minSentenceTokens {
+			sentences = append(sentences, s)
+			tokens = append(tokens, t)
+		}
+		if len(sentences) == maxExtractiveSentences {
+			break
+		}
+	}
+	if len(sentences) == 0 {
+		return nil, errors.New("no sentences to extract a summary from")
+	}
+
+	scores := textRank(tokens)
+	ranked := make([]int, len(sentences))
+	for i := range ranked {
+		ranked[i] = i
+	}
+	sort.SliceStable(ranked, func(a, b int) bool { return scores[ranked[a]] > scores[ranked[b]] })
+
+	picked := ranked[1:min(len(ranked), n+1)]
+	sort.Ints(picked)
+	parts := []string{sentences[ranked[0]]}
+	for _, i := range picked {
+		parts = append(parts, sentences[i])
+	}
+	parts = fitShares(parts, style.MaxWidth)
+
+	summary := &Summary{
+		Headline: parts[0],
+		Bullets:  parts[1:],
+		Language: lang.Detect(req.Text),
+		Style:    style.Name,
+		Model:    ExtractiveModel,
+
+		Extractive: true,
+	}
+	if summary.Language == "" {
+		summary.Language = req.Language
+	}
+	if req.Language != "" && summary.Language != req.Language {
+		summary.Warnings = append(summary.Warnings, "extractive summary is in the article's language, not the requested one")
+	}
+	if len(summary.Bullets) < n {
+		summary.Warnings = append(summary.Warnings, "article was too short for the requested number of bullets")
+	}
+	return summary, nil
+}
+
+// textRank scores sentences by their centrality in a graph weighted by the
+// TF-IDF cosine similarity of their tokens
+func textRank(sentences [][]string) []float64 {
+	n := len(sentences)
+
+	df := make(map[string]int)
+	for _, tokens := range sentences {
+		seen := make(map[string]bool)
+		for _, t := range tokens {
+			if !seen[t] {
+				seen[t] = true
+				df[t]++
+			}
+		}
+	}
+	vectors := make([]map[string]float64, n)
+	norms := make([]float64, n)
+	for i, tokens := range sentences {
+		v := make(map[string]float64)
+		for _, t := range tokens {
+			v[t]++
+		}
+		for t, tf := range v {
+			v[t] = tf * (1 + math.Log(float64(n)/float64(df[t])))
+			norms[i] += v[t] * v[t]
+		}
+		norms[i] = math.Sqrt(norms[i])
+		vectors[i] = v
+	}
+
+	weights := make([][]float64, n)
+	outSum := make([]float64, n)
+	for i := range weights {
+		weights[i] = make([]float64, n)
+	}
+	for i := 0; i < n; i++ {
+		for j := i + 1; j < n; j++ {
+			dot := 0.0
+			for t, w := range vectors[i] {
+				dot += w * vectors[j][t]
+			}
+			sim := dot / (norms[i] * norms[j])
+			weights[i][j], weights[j][i] = sim, sim
+			outSum[i] += sim
+			outSum[j] += sim
+		}
+	}
+
+	// The teleport vector leans towards the lead, which also settles ties
+	// and isolated sentences in favor of earlier ones
+	prior := make([]float64, n)
+	total := 0.0
+	for i := range prior {
+		prior[i] = 1 / math.Sqrt(float64(i+1))
+		total += prior[i]
+	}
+	for i := range prior {
+		prior[i] /= total
+	}
+
+	scores := append([]float64(nil), prior...)
+	next := make([]float64, n)
+	for iter := 0; iter < textRankIterations; iter++ {
+		delta := 0.0
+		for i := 0; i < n; i++ {
+			sum := 0.0
+			for j := 0; j < n; j++ {
+				if weights[j][i] > 0 {
+					sum += weights[j][i] / outSum[j] * scores[j]
+				}
+			}
+			next[i] = (1-textRankDamping)*prior[i] + textRankDamping*sum
+			delta += math.Abs(next[i] - scores[i])
+		}
+		scores, next = next, scores
+		if delta < textRankTolerance {
+			break
+		}
+	}
+	return scores
+}
+
+// fitShares truncates parts so their total width fits the budget, sharing it
+// fairly: parts narrower than an even share keep their full width and the
+// room they leave goes to the wider ones
+func fitShares(parts []string, budget int) []string {
+	order := make([]int, len(parts))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return lang.Width(parts[order[a]]) < lang.Width(parts[order[b]]) })
+
+	out := make([]string, len(parts))
+	left := budget
+	for k, i := range order {
+		share := left / (len(order) - k)
+		out[i] = lang.Truncate(parts[i], share)
+		left -= lang.Width(out[i])
+	}
+	return out
+}
@@ -0,0 +1,136 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/matthewmolinar/tldr/pkg/lang"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const extractiveArticle = `The city council approved a new budget for public transit on Monday.
+Lunch was served at noon.
+The transit budget adds twelve bus routes and extends late-night train service.
+Council members said the transit budget would be funded by a parking levy.
+A local bakery won an award for its sourdough.
+Riders groups welcomed the new bus routes and the longer train service.`
+
+func TestExtractive_Summarize(t *testing.T) {
+	summary, err := Extractive{}.Summarize(context.Background(), Request{Text: extractiveArticle, Language: "en"})
+	require.NoError(t, err)
+
+	assert.True(t, summary.Extractive)
+	assert.Equal(t, ExtractiveModel, summary.Model)
+	assert.Equal(t, DefaultStyle, summary.Style)
+	assert.Equal(t, "en", summary.Language)
+	assert.Zero(t, summary.Usage)
+	require.Len(t, summary.Bullets, 3)
+
+	// Off-topic sentences lose to the ones about the transit budget
+	for _, s := range append([]string{summary.Headline}, summary.Bullets...) {
+		assert.NotContains(t, s, "bakery")
+		assert.NotContains(t, s, "Lunch")
+	}
+
+	// Bullets keep article order
+	positions := make([]int, len(summary.Bullets))
+	for i, b := range summary.Bullets {
+		positions[i] = strings.Index(extractiveArticle, strings.TrimSuffix(b, "…"))
+		require.GreaterOrEqual(t, positions[i], 0, b)
+	}
+	assert.IsIncreasing(t, positions)
+
+	again, err := Extractive{}.Summarize(context.Background(), Request{Text: extractiveArticle, Language: "en"})
+	require.NoError(t, err)
+	assert.Equal(t, summary, again, "extractive summaries are deterministic")
+}
+
+func TestExtractive_Budget(t *testing.T) {
+	long := strings.Repeat("The committee reviewed the harbor dredging plan and its long-term costs in great detail. ", 20)
+	for _, style := range StyleNames() {
+		s, _ := LookupStyle(style)
+		summary, err := Extractive{}.Summarize(context.Background(), Request{Text: long + extractiveArticle, Style: style})
+		require.NoError(t, err, style)
+
+		width := lang.Width(summary.Headline)
+		for _, b := range summary.Bullets {
+			width += lang.Width(b)
+		}
+		assert.LessOrEqual(t, width, s.MaxWidth, style)
+		assert.Len(t, summary.Bullets, s.DefaultBullets, style)
+	}
+}
+
+func TestExtractive_Edges(t *testing.T) {
+	_, err := Extractive{}.Summarize(context.Background(), Request{Text: "Too short."})
+	assert.Error(t, err)
+
+	_, err = Extractive{}.Summarize(context.Background(), Request{Text: extractiveArticle, Style: "haiku"})
+	assert.Error(t, err)
+
+	// A cancelled context doesn't stop the last resort
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	summary, err := Extractive{}.Summarize(ctx, Request{Text: "The transit budget adds twelve bus routes and longer service.", Language: "fr"})
+	require.NoError(t, err)
+	assert.Equal(t, "en", summary.Language)
+	assert.Contains(t, summary.Warnings, "extractive summary is in the article's language, not the requested one")
+	assert.Contains(t, summary.Warnings, "article was too short for the requested number of bullets")
+}
+
+func TestFitShares(t *testing.T) {
+	parts := fitShares([]string{strings.Repeat("a", 50), "short", strings.Repeat("b", 200)}, 100)
+	assert.Equal(t, strings.Repeat("a", 46)+"…", parts[0])
+	assert.Equal(t, "short", parts[1])
+	assert.Equal(t, 100-5-47, lang.Width(parts[2]))
+}
+
+type stubSummarizer struct {
+	summary *Summary
+	err     error
+	calls   int
+}
+
+func (s *stubSummarizer) Summarize(ctx context.Context, req Request) (*Summary, error) {
+	s.calls++
+	return s.summary, s.err
+}
+
+func TestChain(t *testing.T) {
+	req := Request{Text: extractiveArticle}
+
+	t.Run("falls back on failure", func(t *testing.T) {
+		down := &stubSummarizer{err: errors.New("provider down")}
+		summary, err := Chain{down, Extractive{}}.Summarize(context.Background(), req)
+		require.NoError(t, err)
+		assert.True(t, summary.Extractive)
+		assert.Equal(t, 1, down.calls)
+	})
+
+	t.Run("stops at the first success", func(t *testing.T) {
+		up := &stubSummarizer{summary: &Summary{Headline: "LLM"}}
+		last := &stubSummarizer{err: errors.New("unused")}
+		summary, err := Chain{up, last}.Summarize(context.Background(), req)
+		require.NoError(t, err)
+		assert.Equal(t, "LLM", summary.Headline)
+		assert.Zero(t, last.calls)
+	})
+
+	t.Run("doesn't route around refusals or cancellations", func(t *testing.T) {
+		for _, cause := range []error{ErrContentFiltered, context.Canceled} {
+			_, err := Chain{&stubSummarizer{err: cause}, Extractive{}}.Summarize(context.Background(), req)
+			assert.ErrorIs(t, err, cause)
+		}
+	})
+
+	t.Run("returns the last error", func(t *testing.T) {
+		_, err := Chain{&stubSummarizer{err: errors.New("first")}, &stubSummarizer{err: errors.New("second")}}.Summarize(context.Background(), req)
+		assert.EqualError(t, err, "second")
+
+		_, err = Chain{}.Summarize(context.Background(), req)
+		assert.Error(t, err)
+	})
+}
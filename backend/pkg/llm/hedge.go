@@ -0,0 +1,221 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// HedgeEvent is reported to HedgeConfig.OnEvent as hedged calls progress
+type HedgeEvent string
+
+const (
+	HedgeRequest HedgeEvent = "request" // a call arrived
+	HedgeFired   HedgeEvent = "hedged"  // the primary was slow and a hedge was sent
+	HedgeWon     HedgeEvent = "won"     // the hedge answered first
+)
+
+// HedgeConfig tunes when a hedge is sent
+type HedgeConfig struct {
+	Percentile float64       // hedge once the primary is slower than this share of recent calls, e.g. 0.95
+	MinDelay   time.Duration // floor on the hedge delay, so fast periods don't double traffic
+	MaxDelay   time.Duration // ceiling on the hedge delay, also used until enough samples exist
+	Window     int           // recent latencies kept for the percentile
+	MinSamples int           // latencies needed before the percentile is trusted
+
+	// OnEvent, when set, is called for metrics
+	OnEvent func(HedgeEvent)
+}
+
+// DefaultHedgeConfig hedges at the 95th percentile, between 1s and 4s, so a
+// stalled call still has time to finish within the PRD's 5s P95 target
+var DefaultHedgeConfig = HedgeConfig{
+	Percentile: 0.95,
+	MinDelay:   time.Second,
+	MaxDelay:   4 * time.Second,
+	Window:     200,
+	MinSamples: 20,
+}
+
+// Hedger sends a second request when the first is slow and returns whichever
+// valid summary arrives first, cancelling the other. The hedge goes to
+// Secondary when set, else to Primary again. The provider may still bill the
+// cancelled call, so Hedger waits for it to return and adds its usage, or its
+// prompt estimate, to the winner's.
+type Hedger struct {
+	Primary   Summarizer
+	Secondary Summarizer
+	cfg       HedgeConfig
+
+	mu        sync.Mutex
+	latencies []time.Duration // ring buffer of successful call latencies
+	next      int
+}
+
+// NewHedger creates a hedging summarizer. Zero fields of cfg take their
+// DefaultHedgeConfig values.
+func NewHedger(primary, secondary Summarizer, cfg HedgeConfig) *Hedger {
+	d := DefaultHedgeConfig
+	if cfg.Percentile <= 0 || cfg.Percentile >= 1 {
+		cfg.Percentile = d.Percentile
+	}
+	if cfg.MinDelay <= 0 {
+		cfg.MinDelay = d.MinDelay
+	}
+	if cfg.MaxDelay <= 0 {
+		cfg.MaxDelay = d.MaxDelay
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = d.Window
+	}
+	if cfg.MinSamples <= 0 {
+		cfg.MinSamples = d.MinSamples
+	}
+	return &Hedger{Primary: primary, Secondary: secondary, cfg: cfg}
+}
+
+// Delay is how long the next call waits for the primary before hedging: the
+// configured percentile of recent latencies, clamped to [MinDelay, MaxDelay]
+func (h *Hedger) Delay() time.Duration {
+	h.mu.Lock()
+	samples := append([]time.Duration(nil), h.latencies...)
+	h.mu.Unlock()
+
+	if len(samples) < h.cfg.MinSamples {
+		return h.cfg.MaxDelay
+	}
+	sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+	idx := int(math.Ceil(h.cfg.Percentile*float64(len(samples)))) - 1
+	return min(max(samples[max(idx, 0)], h.cfg.MinDelay), h.cfg.MaxDelay)
+}
+
+func (h *Hedger) observe(d time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.latencies) < h.cfg.Window {
+		h.latencies = append(h.latencies, d)
+		return
+	}
+	h.latencies[h.next] = d
+	h.next = (h.next + 1) % h.cfg.Window
+}
+
+func (h *Hedger) emit(e HedgeEvent) {
+	if h.cfg.OnEvent != nil {
+		h.cfg.OnEvent(e)
+	}
+}
+
+type hedgeResult struct {
+	summary *Summary
+	err     error
+	hedge   bool
+}
+
+// Summarize implements Summarizer
+func (h *Hedger) Summarize(ctx context.Context, req Request) (*Summary, error) {
+	h.emit(HedgeRequest)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan hedgeResult, 2)
+	launch := func(s Summarizer, hedge bool) {
+		start := time.Now()
+		summary, err := s.Summarize(ctx, req)
+		if err == nil {
+			h.observe(time.Since(start))
+		}
+		results <- hedgeResult{summary: summary, err: err, hedge: hedge}
+	}
+	go launch(h.Primary, false)
+
+	timer := time.NewTimer(h.Delay())
+	defer timer.Stop()
+
+	pending, hedged := 1, false
+	var firstErr error
+	var billed Usage // of the failed calls
+	for {
+		select {
+		case <-timer.C:
+			if hedged {
+				continue
+			}
+			hedged = true
+			pending++
+			secondary := h.Secondary
+			if secondary == nil {
+				secondary = h.Primary
+			}
+			log.Printf("Primary summarizer is slow, sending hedge request")
+			h.emit(HedgeFired)
+			go launch(secondary, true)
+
+		case res := <-results:
+			pending--
+			if res.err == nil {
+				if res.hedge {
+					h.emit(HedgeWon)
+				}
+				cancel()
+				summary := *res.summary
+				summary.Usage = summary.Usage.Add(drainUsage(results, pending))
+				return &summary, nil
+			}
+			billed = billed.Add(billedUsage(res.err))
+			if firstErr == nil || !res.hedge {
+				firstErr = res.err
+			}
+			// A primary that fails before the hedge delay is reported
+			// as is; it has already had its own retries
+			if pending == 0 {
+				return nil, withUsage(firstErr, billed)
+			}
+
+		case <-ctx.Done():
+			return nil, withUsage(ctx.Err(), billed.Add(drainUsage(results, pending)))
+		}
+	}
+}
+
+// drainUsage waits for the n cancelled calls still running and returns the
+// usage they were billed for
+func drainUsage(results <-chan hedgeResult, n int) Usage {
+	var u Usage
+	for ; n > 0; n-- {
+		res := <-results
+		if res.err == nil {
+			u = u.Add(res.summary.Usage)
+		} else {
+			u = u.Add(billedUsage(res.err))
+		}
+	}
+	return u
+}
+
+// billedUsage returns the usage a failed call was billed for, if any
+func billedUsage(err error) Usage {
+	var billed *BilledError
+	if errors.As(err, &billed) {
+		return billed.Usage
+	}
+	return Usage{}
+}
+
+// withUsage reports the usage of every failed call on err, replacing the
+// usage err carries for its own call
+func withUsage(err error, u Usage) error {
+	if u == (Usage{}) {
+		return err
+	}
+	var billed *BilledError
+	model := ""
+	if errors.As(err, &billed) {
+		model, err = billed.Model, billed.Err
+	}
+	return &BilledError{Err: err, Model: model, Usage: u}
+}
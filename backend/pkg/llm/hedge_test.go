@@ -0,0 +1,218 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// delayedSummarizer answers after a delay, or fails, unless cancelled first.
+// Like Client, it reports usage, and its prompt estimate when cancelled.
+type delayedSummarizer struct {
+	name     string
+	delay    time.Duration
+	err      error
+	usage    Usage
+	estimate Usage
+
+	mu        sync.Mutex
+	calls     int
+	cancelled int
+}
+
+func (d *delayedSummarizer) Summarize(ctx context.Context, req Request) (*Summary, error) {
+	d.mu.Lock()
+	d.calls++
+	d.mu.Unlock()
+
+	select {
+	case <-time.After(d.delay):
+		if d.err != nil {
+			return nil, d.err
+		}
+		return &Summary{Headline: d.name, Usage: d.usage}, nil
+	case <-ctx.Done():
+		d.mu.Lock()
+		d.cancelled++
+		d.mu.Unlock()
+		if d.estimate != (Usage{}) {
+			return nil, &BilledError{Err: ctx.Err(), Usage: d.estimate}
+		}
+		return nil, ctx.Err()
+	}
+}
+
+func (d *delayedSummarizer) counts() (calls, cancelled int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.calls, d.cancelled
+}
+
+// eventLog collects hedge events
+type eventLog struct {
+	mu     sync.Mutex
+	events []HedgeEvent
+}
+
+func (l *eventLog) record(e HedgeEvent) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.events = append(l.events, e)
+}
+
+func (l *eventLog) all() []HedgeEvent {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]HedgeEvent(nil), l.events...)
+}
+
+func testHedgeConfig(events *eventLog) HedgeConfig {
+	return HedgeConfig{
+		Percentile: 0.9,
+		MinDelay:   20 * time.Millisecond,
+		MaxDelay:   50 * time.Millisecond,
+		MinSamples: 5,
+		OnEvent:    events.record,
+	}
+}
+
+func TestHedger(t *testing.T) {
+	t.Run("fast primary is not hedged", func(t *testing.T) {
+		events := &eventLog{}
+		primary := &delayedSummarizer{name: "primary", delay: time.Millisecond}
+		secondary := &delayedSummarizer{name: "secondary"}
+		h := NewHedger(primary, secondary, testHedgeConfig(events))
+
+		summary, err := h.Summarize(context.Background(), Request{})
+		require.NoError(t, err)
+		assert.Equal(t, "primary", summary.Headline)
+		calls, _ := secondary.counts()
+		assert.Zero(t, calls)
+		assert.Equal(t, []HedgeEvent{HedgeRequest}, events.all())
+	})
+
+	t.Run("slow primary loses to the hedge and is cancelled", func(t *testing.T) {
+		events := &eventLog{}
+		primary := &delayedSummarizer{name: "primary", delay: time.Second}
+		secondary := &delayedSummarizer{name: "secondary", delay: time.Millisecond}
+		h := NewHedger(primary, secondary, testHedgeConfig(events))
+
+		start := time.Now()
+		summary, err := h.Summarize(context.Background(), Request{})
+		require.NoError(t, err)
+		assert.Equal(t, "secondary", summary.Headline)
+		assert.Less(t, time.Since(start), 500*time.Millisecond)
+		assert.Equal(t, []HedgeEvent{HedgeRequest, HedgeFired, HedgeWon}, events.all())
+
+		assert.Eventually(t, func() bool {
+			_, cancelled := primary.counts()
+			return cancelled == 1
+		}, time.Second, 5*time.Millisecond)
+	})
+
+	t.Run("hedges against the primary when there is no secondary", func(t *testing.T) {
+		primary := &delayedSummarizer{name: "primary", delay: 80 * time.Millisecond}
+		h := NewHedger(primary, nil, testHedgeConfig(&eventLog{}))
+
+		_, err := h.Summarize(context.Background(), Request{})
+		require.NoError(t, err)
+		calls, _ := primary.counts()
+		assert.Equal(t, 2, calls)
+	})
+
+	t.Run("a failed primary waits for the hedge", func(t *testing.T) {
+		primary := &delayedSummarizer{delay: 70 * time.Millisecond, err: errors.New("primary failed")}
+		secondary := &delayedSummarizer{name: "secondary", delay: 40 * time.Millisecond}
+		h := NewHedger(primary, secondary, testHedgeConfig(&eventLog{}))
+
+		summary, err := h.Summarize(context.Background(), Request{})
+		require.NoError(t, err)
+		assert.Equal(t, "secondary", summary.Headline)
+	})
+
+	t.Run("early primary failure is returned without hedging", func(t *testing.T) {
+		errPrimary := errors.New("primary failed")
+		primary := &delayedSummarizer{delay: time.Millisecond, err: errPrimary}
+		secondary := &delayedSummarizer{name: "secondary"}
+		h := NewHedger(primary, secondary, testHedgeConfig(&eventLog{}))
+
+		_, err := h.Summarize(context.Background(), Request{})
+		assert.ErrorIs(t, err, errPrimary)
+		calls, _ := secondary.counts()
+		assert.Zero(t, calls)
+	})
+
+	t.Run("both failing returns the primary's error", func(t *testing.T) {
+		errPrimary := errors.New("primary failed")
+		primary := &delayedSummarizer{delay: 100 * time.Millisecond, err: errPrimary}
+		secondary := &delayedSummarizer{delay: time.Millisecond, err: errors.New("secondary failed")}
+		h := NewHedger(primary, secondary, testHedgeConfig(&eventLog{}))
+
+		_, err := h.Summarize(context.Background(), Request{})
+		assert.ErrorIs(t, err, errPrimary)
+	})
+}
+
+func TestHedger_Usage(t *testing.T) {
+	t.Run("adds the cancelled loser's prompt estimate to the winner's usage", func(t *testing.T) {
+		primary := &delayedSummarizer{name: "primary", delay: time.Second, estimate: Usage{PromptTokens: 1000, CostUSD: 0.0015}}
+		secondary := &delayedSummarizer{name: "secondary", delay: time.Millisecond, usage: Usage{PromptTokens: 1000, CompletionTokens: 50, CostUSD: 0.0016}}
+		h := NewHedger(primary, secondary, testHedgeConfig(&eventLog{}))
+
+		summary, err := h.Summarize(context.Background(), Request{})
+		require.NoError(t, err)
+		assert.Equal(t, "secondary", summary.Headline)
+		assert.Equal(t, 2000, summary.Usage.PromptTokens)
+		assert.Equal(t, 50, summary.Usage.CompletionTokens)
+		assert.InDelta(t, 0.0031, summary.Usage.CostUSD, 1e-12)
+		_, cancelled := primary.counts()
+		assert.Equal(t, 1, cancelled, "the loser has returned by the time the winner is reported")
+	})
+
+	t.Run("reports the usage of every failed call", func(t *testing.T) {
+		errPrimary := errors.New("primary failed")
+		primary := &delayedSummarizer{delay: 100 * time.Millisecond, err: &BilledError{Err: errPrimary, Model: "gpt-4o-mini", Usage: Usage{PromptTokens: 1000, CostUSD: 0.002}}}
+		secondary := &delayedSummarizer{delay: time.Millisecond, err: &BilledError{Err: errors.New("secondary failed"), Usage: Usage{PromptTokens: 900, CostUSD: 0.001}}}
+		h := NewHedger(primary, secondary, testHedgeConfig(&eventLog{}))
+
+		_, err := h.Summarize(context.Background(), Request{})
+		assert.ErrorIs(t, err, errPrimary)
+		var billed *BilledError
+		require.ErrorAs(t, err, &billed)
+		assert.Equal(t, "gpt-4o-mini", billed.Model)
+		assert.Equal(t, 1900, billed.Usage.PromptTokens)
+		assert.InDelta(t, 0.003, billed.Usage.CostUSD, 1e-12)
+	})
+}
+
+func TestHedger_Delay(t *testing.T) {
+	h := NewHedger(nil, nil, HedgeConfig{
+		Percentile: 0.9,
+		MinDelay:   100 * time.Millisecond,
+		MaxDelay:   time.Second,
+		Window:     10,
+		MinSamples: 5,
+	})
+	assert.Equal(t, time.Second, h.Delay(), "max delay until there are enough samples")
+
+	for i := 1; i <= 10; i++ {
+		h.observe(time.Duration(i) * 50 * time.Millisecond)
+	}
+	assert.Equal(t, 450*time.Millisecond, h.Delay())
+
+	// New samples replace the oldest once the window is full
+	for i := 0; i < 10; i++ {
+		h.observe(10 * time.Millisecond)
+	}
+	assert.Equal(t, 100*time.Millisecond, h.Delay(), "clamped to the minimum")
+
+	for i := 0; i < 10; i++ {
+		h.observe(5 * time.Second)
+	}
+	assert.Equal(t, time.Second, h.Delay(), "clamped to the maximum")
+}
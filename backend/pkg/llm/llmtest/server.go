@@ -0,0 +1,173 @@
+// Package llmtest provides a fake OpenAI-compatible chat completions server
+// for tests. Replies are scripted in order and can mimic the quirks of
+// self-hosted model servers.
+package llmtest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// Reply is one scripted response
+type Reply struct {
+	Status       int               // defaults to 200
+	Header       map[string]string // extra response headers, e.g. Retry-After
+	Content      string            // assistant message content for successful replies
+	FinishReason string            // defaults to "stop"
+	Usage        *openai.Usage     // omitted from the response when nil, as some servers do
+	Body         string            // raw body, sent instead of a generated one when set
+	Delay        time.Duration     // wait before replying
+}
+
+// OK is a successful reply with the given content
+func OK(content string) Reply {
+	return Reply{Content: content}
+}
+
+// Error is an OpenAI-style error reply
+func Error(status int, code string) Reply {
+	return Reply{
+		Status: status,
+		Body:   `{"error":{"message":"scripted failure","type":"test","code":"` + code + `"}}`,
+	}
+}
+
+// Request is a chat completion call the server received
+type Request struct {
+	Header http.Header
+	Body   openai.ChatCompletionRequest
+	At     time.Time
+}
+
+// Server serves scripted replies to chat completion calls at both
+// /v1/chat/completions and /chat/completions, repeating the last reply once
+// the script runs out. It also lists Models at /v1/models.
+type Server struct {
+	*httptest.Server
+
+	// AuthHeader and AuthValue, when set, are required on every call;
+	// calls without them get 401
+	AuthHeader string
+	AuthValue  string
+
+	// Models are the model IDs the server lists
+	Models []string
+
+	mu       sync.Mutex
+	replies  []Reply
+	requests []Request
+}
+
+// NewServer starts a server with the given script. Close it when done.
+func NewServer(replies ...Reply) *Server {
+	s := &Server{replies: replies}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	return s
+}
+
+// BaseURL is the API root to configure clients with
+func (s *Server) BaseURL() string {
+	return s.URL + "/v1"
+}
+
+// Requests returns the calls received so far
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+// Calls is the number of calls received so far
+func (s *Server) Calls() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.requests)
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	if s.AuthHeader != "" && r.Header.Get(s.AuthHeader) != s.AuthValue {
+		writeJSON(w, http.StatusUnauthorized, `{"error":{"message":"invalid api key","type":"auth","code":"invalid_api_key"}}`)
+		return
+	}
+	if strings.HasSuffix(r.URL.Path, "/models") && r.Method == http.MethodGet {
+		s.serveModels(w)
+		return
+	}
+	if !strings.HasSuffix(r.URL.Path, "/chat/completions") || r.Method != http.MethodPost {
+		http.NotFound(w, r)
+		return
+	}
+
+	var body openai.ChatCompletionRequest
+	json.NewDecoder(r.Body).Decode(&body)
+
+	s.mu.Lock()
+	s.requests = append(s.requests, Request{Header: r.Header.Clone(), Body: body, At: time.Now()})
+	var reply Reply
+	if len(s.replies) > 0 {
+		reply = s.replies[min(len(s.requests)-1, len(s.replies)-1)]
+	}
+	s.mu.Unlock()
+
+	if reply.Delay > 0 {
+		select {
+		case <-time.After(reply.Delay):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	for k, v := range reply.Header {
+		w.Header().Set(k, v)
+	}
+	status := reply.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	if reply.Body != "" {
+		writeJSON(w, status, reply.Body)
+		return
+	}
+
+	finish := reply.FinishReason
+	if finish == "" {
+		finish = "stop"
+	}
+	resp := map[string]any{
+		"id":      "chatcmpl-test",
+		"object":  "chat.completion",
+		"created": time.Now().Unix(),
+		"model":   body.Model,
+		"choices": []map[string]any{{
+			"index":         0,
+			"message":       map[string]string{"role": "assistant", "content": reply.Content},
+			"finish_reason": finish,
+		}},
+	}
+	if reply.Usage != nil {
+		resp["usage"] = reply.Usage
+	}
+	out, _ := json.Marshal(resp)
+	writeJSON(w, status, string(out))
+}
+
+func (s *Server) serveModels(w http.ResponseWriter) {
+	data := []map[string]string{}
+	for _, id := range s.Models {
+		data = append(data, map[string]string{"id": id, "object": "model", "owned_by": "test"})
+	}
+	out, _ := json.Marshal(map[string]any{"object": "list", "data": data})
+	writeJSON(w, http.StatusOK, string(out))
+}
+
+func writeJSON(w http.ResponseWriter, status int, body string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write([]byte(body))
+}
@@ -0,0 +1,161 @@
+package llm
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+
+	"github.com/matthewmolinar/tldr/pkg/config"
+)
+
+// ErrInvalidParams is returned for generation parameters out of range
+var ErrInvalidParams = errors.New("invalid model parameters")
+
+// maxRequestTokens caps a per-request MaxTokens override; the longest style
+// needs a fraction of it
+const maxRequestTokens = 4096
+
+// ModelConfig holds a deployment's model and sampling settings
+type ModelConfig struct {
+	Model       string  // used when a request names none
+	Temperature float32 // 0 to 2
+	TopP        float32 // 0 to 1; zero leaves the provider default
+	MaxTokens   int     // completion limit; zero leaves the provider default
+
+	// Allowed lists the models requests may ask for besides Model and the
+	// LongContext ones
+	Allowed []string
+
+	// LongContext lists models to switch to, in order of preference, when an
+	// article doesn't fit Model's context window
+	LongContext []string
+}
+
+// DefaultModelConfig is the configuration of clients that set none
+var DefaultModelConfig = ModelConfig{Model: DefaultModel, Temperature: 0.5}
+
+func (m ModelConfig) isZero() bool {
+	return m.Model == "" && m.Temperature == 0 && m.TopP == 0 && m.MaxTokens == 0 &&
+		len(m.Allowed) == 0 && len(m.LongContext) == 0
+}
+
+// Validate checks the sampling settings are in range
+func (m ModelConfig) Validate() error {
+	if m.Temperature < 0 || m.Temperature > 2 {
+		return fmt.Errorf("%w: temperature must be between 0 and 2, got %g", ErrInvalidParams, m.Temperature)
+	}
+	if m.TopP < 0 || m.TopP > 1 {
+		return fmt.Errorf("%w: top_p must be between 0 and 1, got %g", ErrInvalidParams, m.TopP)
+	}
+	if m.MaxTokens < 0 {
+		return fmt.Errorf("%w: max_tokens must not be negative, got %d", ErrInvalidParams, m.MaxTokens)
+	}
+	return nil
+}
+
+// Allows reports whether a request may ask for model
+func (m ModelConfig) Allows(model string) bool {
+	return model == m.Model || slices.Contains(m.Allowed, model) || slices.Contains(m.LongContext, model)
+}
+
+// AllowedModels lists every model a request may ask for
+func (m ModelConfig) AllowedModels() []string {
+	out := []string{m.Model}
+	for _, name := range append(append([]string(nil), m.Allowed...), m.LongContext...) {
+		if !slices.Contains(out, name) {
+			out = append(out, name)
+		}
+	}
+	return out
+}
+
+// withOverrides applies a request's sampling overrides
+func (m ModelConfig) withOverrides(req Request) ModelConfig {
+	if req.Temperature != nil {
+		m.Temperature = *req.Temperature
+	}
+	if req.TopP != nil {
+		m.TopP = *req.TopP
+	}
+	if req.MaxTokens != nil {
+		m.MaxTokens = *req.MaxTokens
+	}
+	return m
+}
+
+// CheckOverrides validates a request's sampling overrides, so callers can
+// reject a bad request before doing any work for it
+func CheckOverrides(req Request) error {
+	if req.MaxTokens != nil && (*req.MaxTokens < 1 || *req.MaxTokens > maxRequestTokens) {
+		return fmt.Errorf("%w: max_tokens must be between 1 and %d, got %d", ErrInvalidParams, maxRequestTokens, *req.MaxTokens)
+	}
+	return ModelConfig{}.withOverrides(req).Validate()
+}
+
+// reserve is the room held back from the context window for the completion
+func (m ModelConfig) reserve() int {
+	return max(m.MaxTokens, completionReserveTokens)
+}
+
+// temperature converts the setting for the OpenAI client, which omits a zero
+// temperature from the request and so gets the provider default of 1
+func (m ModelConfig) temperature() float32 {
+	if m.Temperature == 0 {
+		return math.SmallestNonzeroFloat32
+	}
+	return m.Temperature
+}
+
+// NewModelConfig builds the model configuration from the LLM settings,
+// checking that the long-context models have known context windows
+func NewModelConfig(cfg config.LLM) (ModelConfig, error) {
+	m := ModelConfig{
+		Model:       cfg.Model,
+		Temperature: cfg.Temperature,
+		TopP:        cfg.TopP,
+		MaxTokens:   cfg.MaxTokens,
+		Allowed:     cfg.AllowedModels,
+		LongContext: cfg.LongContextModels,
+	}
+	if m.Model == "" {
+		m.Model = DefaultModel
+	}
+	for _, name := range m.LongContext {
+		if _, ok := LookupModel(name); !ok {
+			return m, fmt.Errorf("long-context model %q has no known context window", name)
+		}
+	}
+	return m, m.Validate()
+}
+
+// ArticleBytes returns how much extracted article text to keep for a
+// request to model: base, or for a long-context model as much text as its
+// window holds at EstimateTokens' four bytes a token, if that is more.
+// Articles longer than the window are still truncated to fit.
+func (m ModelConfig) ArticleBytes(model string, base int) int {
+	if !slices.Contains(m.LongContext, model) {
+		return base
+	}
+	if info, ok := LookupModel(model); ok {
+		return max(base, 4*(info.ContextTokens-m.reserve()))
+	}
+	return base
+}
+
+// pickLongContext returns the first LongContext model whose window fits
+// promptTokens, or failing that the one with the largest window if it beats
+// current. window reports a model's context window, zero when unknown.
+func (m ModelConfig) pickLongContext(current string, promptTokens int, window func(string) int) (string, bool) {
+	best, bestWindow := "", window(current)
+	for _, name := range m.LongContext {
+		w := window(name)
+		if promptTokens <= w-m.reserve() {
+			return name, true
+		}
+		if w > bestWindow {
+			best, bestWindow = name, w
+		}
+	}
+	return best, best != ""
+}
@@ -0,0 +1,158 @@
+package llm
+
+import (
+	"context"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/matthewmolinar/tldr/pkg/config"
+	"github.com/matthewmolinar/tldr/pkg/llm/llmtest"
+	"github.com/sashabaranov/go-openai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestModelConfig_Allows(t *testing.T) {
+	m := ModelConfig{Model: "gpt-4o-mini", Allowed: []string{"gpt-4o"}, LongContext: []string{"gpt-4.1-mini", "gpt-4o"}}
+	assert.True(t, m.Allows("gpt-4o-mini"))
+	assert.True(t, m.Allows("gpt-4o"))
+	assert.True(t, m.Allows("gpt-4.1-mini"))
+	assert.False(t, m.Allows("gpt-4"))
+	assert.Equal(t, []string{"gpt-4o-mini", "gpt-4o", "gpt-4.1-mini"}, m.AllowedModels())
+}
+
+func TestCheckOverrides(t *testing.T) {
+	f := func(v float32) *float32 { return &v }
+	n := func(v int) *int { return &v }
+
+	assert.NoError(t, CheckOverrides(Request{}))
+	assert.NoError(t, CheckOverrides(Request{Temperature: f(0), TopP: f(1), MaxTokens: n(300)}))
+	for _, req := range []Request{
+		{Temperature: f(-0.1)},
+		{Temperature: f(2.5)},
+		{TopP: f(1.1)},
+		{MaxTokens: n(0)},
+		{MaxTokens: n(maxRequestTokens + 1)},
+	} {
+		assert.ErrorIs(t, CheckOverrides(req), ErrInvalidParams)
+	}
+}
+
+func TestNewModelConfig(t *testing.T) {
+	m, err := NewModelConfig(config.Default().LLM)
+	require.NoError(t, err)
+	assert.Equal(t, DefaultModelConfig, m)
+
+	cfg := config.LLM{
+		Model: "gpt-4o-mini", Temperature: 0.2, TopP: 0.9, MaxTokens: 400,
+		AllowedModels: []string{"gpt-4o", "gpt-4.1-mini"}, LongContextModels: []string{"gpt-4.1-nano"},
+	}
+	m, err = NewModelConfig(cfg)
+	require.NoError(t, err)
+	assert.Equal(t, ModelConfig{
+		Model: "gpt-4o-mini", Temperature: 0.2, TopP: 0.9, MaxTokens: 400,
+		Allowed: []string{"gpt-4o", "gpt-4.1-mini"}, LongContext: []string{"gpt-4.1-nano"},
+	}, m)
+
+	for name, bad := range map[string]config.LLM{
+		"temperature":   {Temperature: 3},
+		"top_p":         {TopP: 2},
+		"unknown model": {LongContextModels: []string{"llama3"}},
+	} {
+		_, err := NewModelConfig(bad)
+		assert.Error(t, err, name)
+	}
+}
+
+func TestClient_Summarize_ModelConfig(t *testing.T) {
+	newClient := func(t *testing.T, cfg ModelConfig) (*Client, *llmtest.Server) {
+		fake := llmtest.NewServer(llmtest.OK("Headline: Budget passes\n- Point one\n- Point two\n- Point three"))
+		t.Cleanup(fake.Close)
+		config := openai.DefaultConfig("test-key")
+		config.BaseURL = fake.BaseURL()
+		client := NewClientWithConfig(config)
+		client.Config = cfg
+		return client, fake
+	}
+	article := "The council passed the budget. Point one. Point two. Point three."
+
+	t.Run("sends configured sampling settings", func(t *testing.T) {
+		client, fake := newClient(t, ModelConfig{Model: "gpt-4o-mini", TopP: 0.8, MaxTokens: 300})
+		_, err := client.Summarize(context.Background(), Request{Text: article})
+		require.NoError(t, err)
+
+		body := fake.Requests()[0].Body
+		assert.Equal(t, "gpt-4o-mini", body.Model)
+		assert.Equal(t, float32(math.SmallestNonzeroFloat32), body.Temperature, "zero temperature must survive omitempty")
+		assert.Equal(t, float32(0.8), body.TopP)
+		assert.Equal(t, 300, body.MaxTokens)
+	})
+
+	t.Run("request overrides win", func(t *testing.T) {
+		client, fake := newClient(t, DefaultModelConfig)
+		temp, maxTokens := float32(1.2), 250
+		_, err := client.Summarize(context.Background(), Request{Text: article, Model: "gpt-4o", Temperature: &temp, MaxTokens: &maxTokens})
+		require.NoError(t, err)
+
+		body := fake.Requests()[0].Body
+		assert.Equal(t, "gpt-4o", body.Model)
+		assert.Equal(t, float32(1.2), body.Temperature)
+		assert.Equal(t, 250, body.MaxTokens)
+	})
+
+	long := strings.Repeat("The council passed the budget after a long debate. ", 4000)
+
+	t.Run("switches to a long-context model", func(t *testing.T) {
+		client, fake := newClient(t, ModelConfig{Model: "gpt-4", Temperature: 0.5, LongContext: []string{"gpt-4o-mini"}})
+		summary, err := client.Summarize(context.Background(), Request{Text: long})
+		require.NoError(t, err)
+		assert.Equal(t, "gpt-4o-mini", fake.Requests()[0].Body.Model)
+		assert.Equal(t, "gpt-4o-mini", summary.Model)
+		assert.Contains(t, summary.Warnings, "used gpt-4o-mini because the article is too long for gpt-4")
+		assert.NotContains(t, summary.Warnings, "article was truncated to fit the model's context window")
+	})
+
+	t.Run("keeps an explicitly requested model", func(t *testing.T) {
+		client, fake := newClient(t, ModelConfig{Model: "gpt-4o-mini", Temperature: 0.5, LongContext: []string{"gpt-4.1-mini"}})
+		summary, err := client.Summarize(context.Background(), Request{Text: long, Model: "gpt-4"})
+		require.NoError(t, err)
+		assert.Equal(t, "gpt-4", fake.Requests()[0].Body.Model)
+		assert.Contains(t, summary.Warnings, "article was truncated to fit the model's context window")
+	})
+}
+
+func TestModelConfig_ArticleBytes(t *testing.T) {
+	m := ModelConfig{Model: "gpt-3.5-turbo", Allowed: []string{"gpt-4o-mini"}, LongContext: []string{"gpt-4o", "unknown-model"}}
+	assert.Equal(t, 8192, m.ArticleBytes("", 8192), "the default model keeps the default cap")
+	assert.Equal(t, 8192, m.ArticleBytes("gpt-3.5-turbo", 8192))
+	assert.Equal(t, 8192, m.ArticleBytes("gpt-4o-mini", 8192), "only long-context models raise it")
+	assert.Equal(t, 8192, m.ArticleBytes("unknown-model", 8192))
+	assert.Equal(t, 4*(128000-completionReserveTokens), m.ArticleBytes("gpt-4o", 8192))
+
+	m.MaxTokens = 2000
+	assert.Equal(t, 4*(128000-2000), m.ArticleBytes("gpt-4o", 8192), "room for the completion is held back")
+	assert.Equal(t, 1<<20, m.ArticleBytes("gpt-4o", 1<<20), "a larger configured cap is kept")
+}
+
+func TestModelConfig_PickLongContext(t *testing.T) {
+	window := func(name string) int {
+		return map[string]int{"small": 4000, "medium": 16000, "large": 128000}[name]
+	}
+	m := ModelConfig{LongContext: []string{"medium", "large"}}
+
+	got, ok := m.pickLongContext("small", 10000, window)
+	assert.True(t, ok)
+	assert.Equal(t, "medium", got, "the first model that fits wins")
+
+	got, ok = m.pickLongContext("small", 100000, window)
+	assert.True(t, ok)
+	assert.Equal(t, "large", got)
+
+	got, ok = m.pickLongContext("small", 500000, window)
+	assert.True(t, ok)
+	assert.Equal(t, "large", got, "the largest window when none fits")
+
+	_, ok = m.pickLongContext("large", 500000, window)
+	assert.False(t, ok, "no switch to a smaller window")
+}
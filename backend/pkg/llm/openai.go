@@ -2,108 +2,321 @@ package llm
 
 import (
 	"context"
-	"crypto/tls"
 	"errors"
+	"fmt"
 	"log"
 	"net/http"
-	"os"
 	"strings"
 
+	"github.com/matthewmolinar/tldr/pkg/config"
+	"github.com/matthewmolinar/tldr/pkg/lang"
 	"github.com/sashabaranov/go-openai"
 )
 
-// Summarizer defines the interface for text summarization
+// Request describes a summarization job
+type Request struct {
+	// The article and what to make of it
+	Text          string
+	Language      string // ISO 639-1 code of the language to write the summary in
+	Style         string // summary style preset; empty selects DefaultStyle
+	Bullets       *int   // bullet count; nil selects the style default
+	PromptVersion string // prompt template version; empty selects the latest
+	Model         string // chat model; empty selects the client's, or a long-context one
+	Strict        bool   // instruct the model to use only facts stated in the text
+
+	// Sampling overrides; nil keeps the client's ModelConfig
+	Temperature *float32
+	TopP        *float32
+	MaxTokens   *int
+}
+
+// Summary is a generated headline with its bullet takeaway points
+type Summary struct {
+	// The summary itself
+	Headline string
+	Bullets  []string
+	Language string
+	Style    string
+
+	// How it was produced
+	PromptVersion string   // ID of the prompt template that produced the summary
+	Model         string   // model that answered, or ExtractiveModel
+	Extractive    bool     // made of article sentences rather than generated by a model
+	Usage         Usage    // tokens and cost of the call
+	Warnings      []string // changes to the article or output worth surfacing, such as removed injection attempts
+}
+
+// Summarizer defines the interface for text summarization. Implementations
+// stop work, including retries, when ctx is done.
 type Summarizer interface {
-	Summarize(text string) (headline string, bullets []string, err error)
+	Summarize(ctx context.Context, req Request) (*Summary, error)
 }
 
+// DefaultModel is the chat model used when a request names none
+const DefaultModel = openai.GPT3Dot5Turbo
+
 // Client wraps the OpenAI client to provide summarization capabilities
 type Client struct {
 	*openai.Client
+
+	// Prompts holds the system prompt templates; nil uses DefaultPrompts
+	Prompts *PromptSet
+
+	// Retry governs retries of failed calls; the zero value uses DefaultRetryPolicy
+	Retry RetryPolicy
+
+	// Config holds the default model and sampling settings; the zero value
+	// uses DefaultModelConfig
+	Config ModelConfig
+
+	// ContextTokens overrides the known context window of Config.Model, for
+	// self-hosted models the pricing table doesn't list
+	ContextTokens int
 }
 
-// NewClient creates a new OpenAI client using the API key from environment
-func NewClient() (*Client, error) {
-	apiKey := os.Getenv("OPENAI_API_KEY")
-	if apiKey == "" {
-		return nil, errors.New("OPENAI_API_KEY environment variable is required")
-	}
-	
-	// Check if running in production environment
-	_, inProduction := os.LookupEnv("FLY_APP_NAME")
-	
-	var client *openai.Client
-	if inProduction {
-		log.Printf("Running in production environment, disabling TLS verification for OpenAI client")
-		// Create a custom HTTP client with TLS certificate verification disabled
-		httpClient := &http.Client{
-			Transport: &http.Transport{
-				TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-			},
+// NewClient creates an OpenAI client from the LLM configuration. hc makes
+// the calls; nil uses http.DefaultClient.
+func NewClient(cfg config.LLM, hc *http.Client) (*Client, error) {
+	if cfg.APIKey == "" {
+		return nil, errors.New("OpenAI API key is required")
+	}
+
+	clientConfig := openai.DefaultConfig(cfg.APIKey)
+	if hc != nil {
+		clientConfig.HTTPClient = hc
+	}
+
+	// Load prompt templates, with optional overrides from the prompt directory
+	prompts, err := LoadPrompts(cfg.PromptDir)
+	if err != nil {
+		return nil, err
+	}
+	models, err := NewModelConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	client := NewClientWithConfig(clientConfig)
+	client.Prompts = prompts
+	client.Config = models
+	return client, nil
+}
+
+// NewClientWithConfig creates a client from an explicit OpenAI configuration,
+// wrapping its HTTP transport so retries can honor Retry-After headers
+func NewClientWithConfig(config openai.ClientConfig) *Client {
+	if hc, ok := config.HTTPClient.(*http.Client); ok {
+		wrapped := *hc
+		base := wrapped.Transport
+		if base == nil {
+			base = http.DefaultTransport
 		}
-		
-		// Create client with default config
-		config := openai.DefaultConfig(apiKey)
-		// Set custom HTTP client
-		config.HTTPClient = httpClient
-		client = openai.NewClientWithConfig(config)
-	} else {
-		client = openai.NewClient(apiKey)
-	}
-	
-	return &Client{client}, nil
+		wrapped.Transport = &retryAfterTransport{base: base}
+		config.HTTPClient = &wrapped
+	}
+	return &Client{Client: openai.NewClientWithConfig(config)}
 }
 
 // Summarize takes an article text and returns a headline and bullet points
-func (c *Client) Summarize(text string) (headline string, bullets []string, err error) {
-	resp, err := c.CreateChatCompletion(
-		context.Background(),
-		openai.ChatCompletionRequest{
-			Model: openai.GPT3Dot5Turbo,
+// written in the requested language
+func (c *Client) Summarize(ctx context.Context, req Request) (*Summary, error) {
+	language := req.Language
+	if !lang.Supported(language) {
+		language = lang.Default
+	}
+	style, err := LookupStyle(req.Style)
+	if err != nil {
+		return nil, err
+	}
+	bullets, err := style.BulletCount(req.Bullets)
+	if err != nil {
+		return nil, err
+	}
+
+	prompts := c.Prompts
+	if prompts == nil {
+		prompts = DefaultPrompts()
+	}
+	prompt, tmpl, err := systemPrompt(prompts, style, req.PromptVersion, bullets, language, req.Strict)
+	if err != nil {
+		return nil, err
+	}
+	params := c.config().withOverrides(req)
+	if err := params.Validate(); err != nil {
+		return nil, err
+	}
+	if params.Model == "" {
+		params.Model = DefaultModel
+	}
+	model := req.Model
+	if model == "" {
+		model = params.Model
+	}
+
+	// Drop text addressed to the model and fence off the rest as data
+	text, injections := sanitizeArticle(req.Text)
+	var warnings []string
+	if len(injections) > 0 {
+		log.Printf("Removed possible prompt injection from article: %s", strings.Join(injections, ", "))
+		warnings = append(warnings, "removed text that looked like instructions to the model ("+strings.Join(injections, ", ")+")")
+	}
+
+	// Keep the prompt inside the model's context window, moving to a larger
+	// model first if the request left the choice to us
+	content := wrapUntrusted(text)
+	estimate := estimatePrompt(prompt, content)
+	if window := c.window(model); req.Model == "" && window > 0 && estimate > window-params.reserve() {
+		if larger, ok := params.pickLongContext(model, estimate, c.window); ok {
+			log.Printf("Article needs ~%d prompt tokens, switching from %s to %s", estimate, model, larger)
+			warnings = append(warnings, "used "+larger+" because the article is too long for "+model)
+			model = larger
+		}
+	}
+	log.Printf("Summarizing with model %s and prompt %s", model, tmpl.ID())
+	if window := c.window(model); window > 0 && estimate > window-params.reserve() {
+		room := max(window-params.reserve()-estimatePrompt(prompt, wrapUntrusted("")), 0)
+		content = wrapUntrusted(truncateToTokens(text, room))
+		truncated := estimatePrompt(prompt, content)
+		log.Printf("Truncated article from ~%d to ~%d prompt tokens for %s", estimate, truncated, model)
+		estimate = truncated
+		warnings = append(warnings, "article was truncated to fit the model's context window")
+	}
+
+	policy := c.Retry
+	if policy.MaxAttempts == 0 {
+		policy = DefaultRetryPolicy
+	}
+	var resp openai.ChatCompletionResponse
+	cancelledInFlight := false
+	err = policy.Do(ctx, func(ctx context.Context) error {
+		var err error
+		sent := ctx.Err() == nil
+		resp, err = c.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
+			Model: model,
 			Messages: []openai.ChatCompletionMessage{
 				{
-					Role: openai.ChatMessageRoleSystem,
-					Content: "You are a master headline writer. Provide a one-sentence headline and 3 bullet " +
-						"takeaway points, total < 280 chars.",
+					Role:    openai.ChatMessageRoleSystem,
+					Content: prompt,
 				},
 				{
 					Role:    openai.ChatMessageRoleUser,
-					Content: text,
+					Content: content,
 				},
 			},
-			Temperature: 0.5,
-		},
-	)
+			Temperature: params.temperature(),
+			TopP:        params.TopP,
+			MaxTokens:   params.MaxTokens,
+		})
+		cancelledInFlight = sent && err != nil && ctx.Err() != nil
+		return err
+	})
+	if err != nil && cancelledInFlight {
+		// Cancelled in flight, as when a hedge wins: the provider may have
+		// processed the prompt already, so bill it at the estimate
+		cost, _ := Cost(model, estimate, 0)
+		return nil, &BilledError{Err: err, Model: model, Usage: Usage{EstimatedPromptTokens: estimate, PromptTokens: estimate, CostUSD: cost}}
+	}
 	if err != nil {
-		return "", nil, err
+		return nil, err
+	}
+
+	// Some self-hosted servers report no usage; fall back to estimates
+	var output string
+	if len(resp.Choices) > 0 {
+		output = resp.Choices[0].Message.Content
+	}
+	usage := Usage{
+		EstimatedPromptTokens: estimate,
+		PromptTokens:          resp.Usage.PromptTokens,
+		CompletionTokens:      resp.Usage.CompletionTokens,
+	}
+	if usage.PromptTokens == 0 && usage.CompletionTokens == 0 {
+		usage.PromptTokens = estimate
+		usage.CompletionTokens = EstimateTokens(output)
+	}
+	var priced bool
+	usage.CostUSD, priced = Cost(model, usage.PromptTokens, usage.CompletionTokens)
+	if !priced {
+		log.Printf("No price for model %s; reporting zero cost", model)
+	}
+	log.Printf("Used %d prompt (estimated %d) and %d completion tokens, $%.6f", usage.PromptTokens, estimate, usage.CompletionTokens, usage.CostUSD)
+
+	// The call is billed from here on, so failures carry its usage
+	billed := func(err error) error {
+		return &BilledError{Err: err, Model: model, Usage: usage}
+	}
+	if output == "" {
+		return nil, billed(errors.New("no summary generated"))
+	}
+	switch normalizeFinishReason(resp.Choices[0].FinishReason) {
+	case openai.FinishReasonContentFilter:
+		return nil, billed(ErrContentFiltered)
+	case openai.FinishReasonLength:
+		log.Printf("Model %s stopped at its token limit", model)
+		warnings = append(warnings, "summary may be incomplete because the model hit its output limit")
 	}
 
 	// Parse the response into headline and bullets
-	if len(resp.Choices) == 0 || resp.Choices[0].Message.Content == "" {
-		return "", nil, errors.New("no summary generated")
+	headline, points, err := parseSummary(normalizeOutput(output))
+	if err != nil {
+		return nil, billed(err)
 	}
+	if err := validateSummary(headline, points, bullets, req.Text); err != nil {
+		log.Printf("Rejected model output: %v", err)
+		return nil, billed(err)
+	}
+	if len(points) > bullets {
+		points = points[:bullets]
+	}
+	headline, points = fitBudget(headline, points, style.MaxWidth)
+
+	return &Summary{
+		Headline:      headline,
+		Bullets:       points,
+		Language:      language,
+		Style:         style.Name,
+		PromptVersion: tmpl.ID(),
+		Model:         model,
+		Warnings:      warnings,
+		Usage:         usage,
+	}, nil
+}
 
-	// Parse response - format is expected to be:
-	// Headline: ...
-	// - Point 1
-	// - Point 2
-	// - Point 3
-	lines := strings.Split(resp.Choices[0].Message.Content, "\n")
-	if len(lines) < 4 {
-		return "", nil, errors.New("invalid response format")
+// config returns the client's model configuration
+func (c *Client) config() ModelConfig {
+	if c.Config.isZero() {
+		return DefaultModelConfig
 	}
+	return c.Config
+}
 
-	// Extract headline (remove "Headline: " prefix if present)
-	headline = strings.TrimPrefix(lines[0], "Headline: ")
+// window returns the context window of model, zero when unknown
+func (c *Client) window(model string) int {
+	if c.ContextTokens > 0 && model == c.config().Model {
+		return c.ContextTokens
+	}
+	if info, ok := LookupModel(model); ok {
+		return info.ContextTokens
+	}
+	return 0
+}
 
-	// Extract bullet points
-	bullets = make([]string, 0, 3)
-	for _, line := range lines[1:] {
-		line = strings.TrimSpace(line)
-		if strings.HasPrefix(line, "- ") {
-			bullets = append(bullets, strings.TrimPrefix(line, "- "))
+// Ping checks the provider is reachable and accepts the credentials by
+// listing its models, which costs no tokens. It fails if the list omits the
+// configured model; servers that list no models at all are trusted.
+func (c *Client) Ping(ctx context.Context) error {
+	list, err := c.ListModels(ctx)
+	if err != nil {
+		return err
+	}
+	if len(list.Models) == 0 {
+		return nil
+	}
+	model := c.config().Model
+	for _, m := range list.Models {
+		if m.ID == model {
+			return nil
 		}
 	}
-
-	return headline, bullets, nil
+	return fmt.Errorf("provider does not offer model %q", model)
 }
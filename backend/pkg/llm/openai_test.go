@@ -2,12 +2,13 @@ package llm
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"io"
 	"net/http"
-	"os"
 	"testing"
 
+	"github.com/matthewmolinar/tldr/pkg/config"
 	"github.com/sashabaranov/go-openai"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -25,18 +26,18 @@ func (m *mockTransport) RoundTrip(req *http.Request) (*http.Response, error) {
 
 func TestNewClient(t *testing.T) {
 	t.Run("fails without API key", func(t *testing.T) {
-		os.Unsetenv("OPENAI_API_KEY")
-		_, err := NewClient()
+		_, err := NewClient(config.Default().LLM, nil)
 		assert.Error(t, err)
 	})
 
 	t.Run("succeeds with API key", func(t *testing.T) {
-		os.Setenv("OPENAI_API_KEY", "test-key")
-		defer os.Unsetenv("OPENAI_API_KEY")
-		
-		client, err := NewClient()
+		cfg := config.Default().LLM
+		cfg.APIKey = "test-key"
+
+		client, err := NewClient(cfg, nil)
 		require.NoError(t, err)
 		assert.NotNil(t, client)
+		assert.Equal(t, DefaultModelConfig, client.Config)
 	})
 }
 
@@ -68,10 +69,11 @@ func TestClient_Summarize(t *testing.T) {
 	}
 
 	t.Run("sends correct prompt format", func(t *testing.T) {
-		headline, bullets, err := client.Summarize("Test article content")
+		summary, err := client.Summarize(context.Background(), Request{Text: "Test article content"})
 		require.NoError(t, err)
-		assert.Equal(t, "Test Headline", headline)
-		assert.Equal(t, []string{"Point 1", "Point 2", "Point 3"}, bullets)
+		assert.Equal(t, "Test Headline", summary.Headline)
+		assert.Equal(t, []string{"Point 1", "Point 2", "Point 3"}, summary.Bullets)
+		assert.Equal(t, "en", summary.Language)
 
 		// Verify request was properly formed
 		assert.NotNil(t, mock.request)
@@ -85,12 +87,68 @@ func TestClient_Summarize(t *testing.T) {
 		require.GreaterOrEqual(t, len(reqBody.Messages), 2)
 		assert.Equal(t, openai.ChatMessageRoleSystem, reqBody.Messages[0].Role)
 		assert.Contains(t, reqBody.Messages[0].Content, "master headline writer")
+		assert.Contains(t, reqBody.Messages[0].Content, "in English")
 		
 		// Verify user content
 		assert.Equal(t, openai.ChatMessageRoleUser, reqBody.Messages[1].Role)
-		assert.Equal(t, "Test article content", reqBody.Messages[1].Content)
+		assert.Equal(t, "<article>\nTest article content\n</article>", reqBody.Messages[1].Content)
 
 		// Verify temperature
 		assert.Equal(t, float32(0.5), reqBody.Temperature)
+		assert.Equal(t, DefaultModel, reqBody.Model)
+	})
+}
+
+func TestClient_Summarize_Styles(t *testing.T) {
+	mockResp := openai.ChatCompletionResponse{
+		Choices: []openai.ChatCompletionChoice{
+			{
+				Message: openai.ChatCompletionMessage{
+					Content: "Headline: Test Headline\n- Point 1\n- Point 2\n- Point 3\n- Point 4",
+				},
+			},
+		},
+	}
+	respBody, err := json.Marshal(mockResp)
+	require.NoError(t, err)
+
+	newClient := func(mock *mockTransport) *Client {
+		config := openai.DefaultConfig("test-key")
+		config.HTTPClient = &http.Client{Transport: mock}
+		return &Client{Client: openai.NewClientWithConfig(config)}
+	}
+
+	t.Run("caps bullets at the requested count", func(t *testing.T) {
+		mock := &mockTransport{response: &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(bytes.NewReader(respBody)),
+		}}
+		two := 2
+		summary, err := newClient(mock).Summarize(context.Background(), Request{Text: "article", Style: "tweet", Bullets: &two})
+		require.NoError(t, err)
+		assert.Equal(t, "tweet", summary.Style)
+		assert.Equal(t, []string{"Point 1", "Point 2"}, summary.Bullets)
+	})
+
+	t.Run("uses the requested model and prompt version", func(t *testing.T) {
+		mock := &mockTransport{response: &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(bytes.NewReader(respBody)),
+		}}
+		summary, err := newClient(mock).Summarize(context.Background(), Request{Text: "article", Model: "gpt-4o-mini", PromptVersion: "v1"})
+		require.NoError(t, err)
+		assert.Equal(t, "gpt-4o-mini", summary.Model)
+		assert.Contains(t, summary.PromptVersion, "headline.v1@")
+
+		var reqBody openai.ChatCompletionRequest
+		require.NoError(t, json.NewDecoder(mock.request.Body).Decode(&reqBody))
+		assert.Equal(t, "gpt-4o-mini", reqBody.Model)
+	})
+
+	t.Run("rejects unknown style before calling the API", func(t *testing.T) {
+		mock := &mockTransport{}
+		_, err := newClient(mock).Summarize(context.Background(), Request{Text: "article", Style: "sonnet"})
+		assert.Error(t, err)
+		assert.Nil(t, mock.request)
 	})
 }
@@ -0,0 +1,83 @@
+package llm
+
+import (
+	"strings"
+)
+
+// ModelInfo is what the service needs to know about a chat model to budget
+// and bill a request
+type ModelInfo struct {
+	ContextTokens int     // context window, prompt and completion combined
+	InputPerMTok  float64 // USD per million prompt tokens
+	OutputPerMTok float64 // USD per million completion tokens
+}
+
+// models holds list prices for the OpenAI chat models we use. Providers
+// report dated snapshots such as gpt-4o-2024-08-06, which LookupModel
+// resolves by longest prefix.
+var models = map[string]ModelInfo{
+	"gpt-3.5-turbo": {ContextTokens: 16385, InputPerMTok: 0.50, OutputPerMTok: 1.50},
+	"gpt-4":         {ContextTokens: 8192, InputPerMTok: 30.00, OutputPerMTok: 60.00},
+	"gpt-4-turbo":   {ContextTokens: 128000, InputPerMTok: 10.00, OutputPerMTok: 30.00},
+	"gpt-4o":        {ContextTokens: 128000, InputPerMTok: 2.50, OutputPerMTok: 10.00},
+	"gpt-4o-mini":   {ContextTokens: 128000, InputPerMTok: 0.15, OutputPerMTok: 0.60},
+	"gpt-4.1":       {ContextTokens: 1047576, InputPerMTok: 2.00, OutputPerMTok: 8.00},
+	"gpt-4.1-mini":  {ContextTokens: 1047576, InputPerMTok: 0.40, OutputPerMTok: 1.60},
+	"gpt-4.1-nano":  {ContextTokens: 1047576, InputPerMTok: 0.10, OutputPerMTok: 0.40},
+}
+
+// LookupModel returns the price and context window of a model, matching
+// dated snapshot names to their base model
+func LookupModel(name string) (ModelInfo, bool) {
+	best := ""
+	for base := range models {
+		if (name == base || strings.HasPrefix(name, base+"-")) && len(base) > len(best) {
+			best = base
+		}
+	}
+	if best == "" {
+		return ModelInfo{}, false
+	}
+	return models[best], true
+}
+
+// Usage is the token consumption and cost of one or more completions
+type Usage struct {
+	EstimatedPromptTokens int     // estimate made before the call
+	PromptTokens          int     // as reported by the provider
+	CompletionTokens      int     // as reported by the provider
+	CostUSD               float64 // zero for models missing from the price table
+}
+
+// Add returns the combined usage of u and o
+func (u Usage) Add(o Usage) Usage {
+	return Usage{
+		EstimatedPromptTokens: u.EstimatedPromptTokens + o.EstimatedPromptTokens,
+		PromptTokens:          u.PromptTokens + o.PromptTokens,
+		CompletionTokens:      u.CompletionTokens + o.CompletionTokens,
+		CostUSD:               u.CostUSD + o.CostUSD,
+	}
+}
+
+// BilledError is returned when the provider completed, and so billed, a call
+// whose output was then rejected, like a filtered or malformed summary, or
+// when a call was cancelled in flight, which is billed at its prompt
+// estimate. It carries the usage so callers can count it against spend limits.
+type BilledError struct {
+	Err   error
+	Model string
+	Usage Usage
+}
+
+func (e *BilledError) Error() string { return e.Err.Error() }
+func (e *BilledError) Unwrap() error { return e.Err }
+
+// Cost prices a completion at the model's list rates. Unknown models cost
+// zero and report false.
+func Cost(model string, promptTokens, completionTokens int) (float64, bool) {
+	info, ok := LookupModel(model)
+	if !ok {
+		return 0, false
+	}
+	return (float64(promptTokens)*info.InputPerMTok + float64(completionTokens)*info.OutputPerMTok) / 1e6, true
+}
@@ -0,0 +1,182 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLookupModel(t *testing.T) {
+	info, ok := LookupModel("gpt-4o-mini-2024-07-18")
+	require.True(t, ok)
+	assert.Equal(t, 0.15, info.InputPerMTok)
+
+	info, ok = LookupModel("gpt-4o-2024-08-06")
+	require.True(t, ok)
+	assert.Equal(t, 2.50, info.InputPerMTok)
+
+	_, ok = LookupModel("gpt-4omni")
+	assert.False(t, ok)
+	_, ok = LookupModel("llama-3-70b")
+	assert.False(t, ok)
+}
+
+func TestCost(t *testing.T) {
+	cost, ok := Cost("gpt-3.5-turbo", 2000, 100)
+	require.True(t, ok)
+	assert.InDelta(t, 0.00115, cost, 1e-9)
+
+	cost, ok = Cost("unknown-model", 2000, 100)
+	assert.False(t, ok)
+	assert.Zero(t, cost)
+}
+
+func TestEstimateTokens(t *testing.T) {
+	assert.Equal(t, 0, EstimateTokens(""))
+	assert.Equal(t, 3, EstimateTokens("hello world"))
+	assert.Equal(t, 5, EstimateTokens("東京で会議"))
+}
+
+func TestTruncateToTokens(t *testing.T) {
+	text := strings.Repeat("word ", 100)
+	cut := truncateToTokens(text, 10)
+	assert.LessOrEqual(t, EstimateTokens(cut), 10)
+	assert.True(t, strings.HasPrefix(text, cut))
+	assert.Greater(t, len(cut), 30)
+
+	assert.Equal(t, "short", truncateToTokens("short", 10))
+	assert.Equal(t, "東京", truncateToTokens("東京で会議", 2))
+}
+
+func TestClient_Summarize_Usage(t *testing.T) {
+	newClient := func() (*Client, *mockTransport) {
+		body, err := json.Marshal(openai.ChatCompletionResponse{
+			Choices: []openai.ChatCompletionChoice{{Message: openai.ChatCompletionMessage{Content: "Headline: H\n- Point"}}},
+			Usage:   openai.Usage{PromptTokens: 1200, CompletionTokens: 40, TotalTokens: 1240},
+		})
+		require.NoError(t, err)
+		mock := &mockTransport{response: &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(bytes.NewReader(body)),
+		}}
+		config := openai.DefaultConfig("test-key")
+		config.HTTPClient = &http.Client{Transport: mock}
+		return &Client{Client: openai.NewClientWithConfig(config)}, mock
+	}
+
+	t.Run("reports provider usage and cost", func(t *testing.T) {
+		client, _ := newClient()
+		summary, err := client.Summarize(context.Background(), Request{Text: "Point of the article.", Model: "gpt-4o-mini"})
+		require.NoError(t, err)
+		assert.Equal(t, 1200, summary.Usage.PromptTokens)
+		assert.Equal(t, 40, summary.Usage.CompletionTokens)
+		assert.Greater(t, summary.Usage.EstimatedPromptTokens, 0)
+		assert.InDelta(t, 0.000204, summary.Usage.CostUSD, 1e-9)
+	})
+
+	t.Run("truncates articles that overflow the context window", func(t *testing.T) {
+		client, mock := newClient()
+		article := strings.Repeat("Point of the article. ", 4000) // ~22k tokens, over gpt-4's 8k
+		summary, err := client.Summarize(context.Background(), Request{Text: article, Model: "gpt-4"})
+		require.NoError(t, err)
+		assert.Contains(t, summary.Warnings, "article was truncated to fit the model's context window")
+		assert.LessOrEqual(t, summary.Usage.EstimatedPromptTokens, 8192-completionReserveTokens)
+
+		var reqBody openai.ChatCompletionRequest
+		require.NoError(t, json.NewDecoder(mock.request.Body).Decode(&reqBody))
+		assert.Less(t, len(reqBody.Messages[1].Content), len(article))
+		assert.True(t, strings.HasSuffix(reqBody.Messages[1].Content, "\n</article>"))
+	})
+}
+
+func TestClient_Summarize_BilledErrors(t *testing.T) {
+	newClient := func(choice openai.ChatCompletionChoice) *Client {
+		body, err := json.Marshal(openai.ChatCompletionResponse{
+			Choices: []openai.ChatCompletionChoice{choice},
+			Usage:   openai.Usage{PromptTokens: 1200, CompletionTokens: 40, TotalTokens: 1240},
+		})
+		require.NoError(t, err)
+		config := openai.DefaultConfig("test-key")
+		config.HTTPClient = &http.Client{Transport: &mockTransport{response: &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(bytes.NewReader(body)),
+		}}}
+		return &Client{Client: openai.NewClientWithConfig(config)}
+	}
+
+	tests := map[string]struct {
+		choice openai.ChatCompletionChoice
+		want   error
+	}{
+		"content filtered": {
+			choice: openai.ChatCompletionChoice{Message: openai.ChatCompletionMessage{Content: "Headline: H"}, FinishReason: openai.FinishReasonContentFilter},
+			want:   ErrContentFiltered,
+		},
+		"invalid summary": {
+			choice: openai.ChatCompletionChoice{Message: openai.ChatCompletionMessage{Content: "Headline: H\n- Visit https://evil.example"}},
+			want:   ErrInvalidSummary,
+		},
+		"unparseable output": {
+			choice: openai.ChatCompletionChoice{Message: openai.ChatCompletionMessage{Content: "   "}},
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := newClient(tt.choice).Summarize(context.Background(), Request{Text: "Point of the article.", Model: "gpt-4o-mini"})
+			require.Error(t, err)
+			if tt.want != nil {
+				assert.ErrorIs(t, err, tt.want)
+			}
+
+			var billed *BilledError
+			require.ErrorAs(t, err, &billed, "the provider charged for the call")
+			assert.Equal(t, "gpt-4o-mini", billed.Model)
+			assert.Equal(t, 1200, billed.Usage.PromptTokens)
+			assert.Equal(t, 40, billed.Usage.CompletionTokens)
+			assert.InDelta(t, 0.000204, billed.Usage.CostUSD, 1e-9)
+		})
+	}
+}
+
+// blockingTransport holds requests until they are cancelled
+type blockingTransport struct{}
+
+func (blockingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	<-req.Context().Done()
+	return nil, req.Context().Err()
+}
+
+func TestClient_Summarize_CancelledInFlight(t *testing.T) {
+	config := openai.DefaultConfig("test-key")
+	config.HTTPClient = &http.Client{Transport: blockingTransport{}}
+	client := &Client{Client: openai.NewClientWithConfig(config)}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := client.Summarize(ctx, Request{Text: strings.Repeat("Point of the article. ", 50), Model: "gpt-4o-mini"})
+	require.Error(t, err)
+
+	var billed *BilledError
+	require.ErrorAs(t, err, &billed, "the provider may bill a prompt it already received")
+	assert.Equal(t, billed.Usage.EstimatedPromptTokens, billed.Usage.PromptTokens)
+	assert.Greater(t, billed.Usage.PromptTokens, 200)
+	assert.Zero(t, billed.Usage.CompletionTokens)
+	assert.Greater(t, billed.Usage.CostUSD, 0.0)
+
+	// Calls cancelled before they were sent cost nothing
+	cancelled, stop := context.WithCancel(context.Background())
+	stop()
+	_, err = client.Summarize(cancelled, Request{Text: "Point.", Model: "gpt-4o-mini"})
+	require.Error(t, err)
+	assert.False(t, errors.As(err, &billed))
+}
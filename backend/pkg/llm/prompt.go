@@ -0,0 +1,68 @@
+package llm
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/matthewmolinar/tldr/pkg/lang"
+)
+
+// bulletRe matches the list markers models use for takeaway points
+var bulletRe = regexp.MustCompile(`^(?:[-*•]|\d+[.)])\s+`)
+
+// systemPrompt renders the system prompt for a style, bullet count and
+// target language from the given prompt version (latest when empty), followed
+// by the notice that the article is untrusted
+func systemPrompt(prompts *PromptSet, style Style, version string, bullets int, language string, strict bool) (string, *PromptTemplate, error) {
+	tmpl, err := prompts.Get(style.Name, version)
+	if err != nil {
+		return "", nil, err
+	}
+
+	name, _ := lang.Name(language)
+	prompt, err := tmpl.Render(PromptData{
+		Language:     language,
+		LanguageName: name,
+		Bullets:      bullets,
+		MaxWidth:     style.MaxWidth,
+		WideScript:   isWideScript(language),
+		WideMaxChars: style.MaxWidth / 2,
+		Strict:       strict,
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	return strings.TrimRight(prompt, "\n") + "\n" + untrustedNotice, tmpl, nil
+}
+
+// parseSummary splits the model output into a headline and bullet points
+func parseSummary(content string) (headline string, bullets []string, err error) {
+	// Parse response - format is expected to be:
+	// Headline: ...
+	// - Point 1
+	// - Point 2
+	// - Point 3
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		// The first line is the headline (remove "Headline: " prefix if present)
+		if headline == "" {
+			headline = strings.TrimSpace(strings.TrimPrefix(line, "Headline:"))
+			continue
+		}
+
+		// Extract bullet points
+		if loc := bulletRe.FindStringIndex(line); loc != nil {
+			bullets = append(bullets, line[loc[1]:])
+		}
+	}
+
+	if headline == "" {
+		return "", nil, errors.New("invalid response format")
+	}
+	return headline, bullets, nil
+}
@@ -0,0 +1,99 @@
+package llm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSystemPrompt(t *testing.T) {
+	headline, err := LookupStyle("headline")
+	require.NoError(t, err)
+	oneLiner, err := LookupStyle("one_liner")
+	require.NoError(t, err)
+
+	render := func(style Style, bullets int, language string) string {
+		prompt, _, err := systemPrompt(DefaultPrompts(), style, "", bullets, language, false)
+		require.NoError(t, err)
+		return prompt
+	}
+
+	t.Run("names the target language", func(t *testing.T) {
+		assert.Contains(t, render(headline, 3, "fr"), "in French")
+	})
+
+	t.Run("includes the bullet count and budget", func(t *testing.T) {
+		prompt := render(headline, 4, "en")
+		assert.Contains(t, prompt, "master headline writer")
+		assert.Contains(t, prompt, "4 bullet takeaway points")
+		assert.Contains(t, prompt, "under 280 chars")
+	})
+
+	t.Run("halves the character budget for wide scripts", func(t *testing.T) {
+		assert.Contains(t, render(headline, 3, "ja"), "under 140 Japanese characters")
+		assert.NotContains(t, render(headline, 3, "de"), "under 140")
+	})
+
+	t.Run("omits bullet formatting for styles without bullets", func(t *testing.T) {
+		assert.NotContains(t, render(oneLiner, 0, "en"), "each bullet")
+	})
+
+	t.Run("strict mode forbids unstated facts", func(t *testing.T) {
+		prompt, _, err := systemPrompt(DefaultPrompts(), headline, "", 3, "en", true)
+		require.NoError(t, err)
+		assert.Contains(t, prompt, "Use only names, numbers and facts stated in the article")
+		assert.NotContains(t, render(headline, 3, "en"), "Use only names")
+	})
+
+	t.Run("unknown prompt version is an error", func(t *testing.T) {
+		_, _, err := systemPrompt(DefaultPrompts(), headline, "v999", 3, "en", false)
+		assert.Error(t, err)
+	})
+}
+
+func TestParseSummary(t *testing.T) {
+	tests := []struct {
+		name         string
+		content      string
+		wantHeadline string
+		wantBullets  []string
+		wantErr      bool
+	}{
+		{
+			name:         "dash bullets",
+			content:      "Headline: Test Headline\n- Point 1\n- Point 2\n- Point 3",
+			wantHeadline: "Test Headline",
+			wantBullets:  []string{"Point 1", "Point 2", "Point 3"},
+		},
+		{
+			name:         "numbered bullets and blank lines",
+			content:      "\nHeadline: Test Headline\n\n1. Point 1\n2) Point 2",
+			wantHeadline: "Test Headline",
+			wantBullets:  []string{"Point 1", "Point 2"},
+		},
+		{
+			name:         "headline only",
+			content:      "Headline: Just one line",
+			wantHeadline: "Just one line",
+		},
+		{
+			name:    "empty output",
+			content: "\n\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			headline, bullets, err := parseSummary(tt.content)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantHeadline, headline)
+			assert.Equal(t, tt.wantBullets, bullets)
+		})
+	}
+}
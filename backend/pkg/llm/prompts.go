@@ -0,0 +1,186 @@
+package llm
+
+import (
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+)
+
+//go:embed prompts/*.tmpl
+var embeddedPrompts embed.FS
+
+// PromptData is the data every prompt template is rendered with
+type PromptData struct {
+	Language     string // ISO 639-1 code of the summary language
+	LanguageName string // English name of the summary language
+	Bullets      int
+	MaxWidth     int  // display-cell budget for the whole summary
+	WideScript   bool // summary language is written in double-width characters
+	WideMaxChars int  // MaxWidth expressed in double-width characters
+	Strict       bool // retrying after unsupported claims; stick to the article's facts
+}
+
+// PromptTemplate is one version of a style's system prompt. Templates are
+// named <style>.<version>.tmpl; Digest fingerprints the template text so an
+// override file reusing a version name is still distinguishable in logs.
+type PromptTemplate struct {
+	Style   string
+	Version string
+	Digest  string
+	tmpl    *template.Template
+}
+
+// ID identifies the exact prompt text, e.g. "headline.v1@3fa2b9c1"
+func (p *PromptTemplate) ID() string {
+	return p.Style + "." + p.Version + "@" + p.Digest
+}
+
+// Render executes the template
+func (p *PromptTemplate) Render(data PromptData) (string, error) {
+	var b strings.Builder
+	if err := p.tmpl.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("failed to render prompt %s: %w", p.ID(), err)
+	}
+	return strings.TrimSpace(b.String()), nil
+}
+
+// PromptSet holds every loaded prompt version keyed by style then version
+type PromptSet struct {
+	templates map[string]map[string]*PromptTemplate
+}
+
+// LoadPrompts loads the embedded prompt templates and then any *.tmpl files
+// in overrideDir, which add new versions or replace embedded ones. An empty
+// overrideDir loads only the embedded templates; a missing one is an error.
+func LoadPrompts(overrideDir string) (*PromptSet, error) {
+	set := &PromptSet{templates: make(map[string]map[string]*PromptTemplate)}
+
+	embedded, err := fs.Sub(embeddedPrompts, "prompts")
+	if err != nil {
+		return nil, err
+	}
+	if err := set.addDir(embedded); err != nil {
+		return nil, err
+	}
+
+	if overrideDir != "" {
+		log.Printf("Loading prompt overrides from %s", overrideDir)
+		if info, err := os.Stat(overrideDir); err != nil {
+			return nil, fmt.Errorf("prompt directory: %w", err)
+		} else if !info.IsDir() {
+			return nil, fmt.Errorf("prompt directory %s is not a directory", overrideDir)
+		}
+		if err := set.addDir(os.DirFS(overrideDir)); err != nil {
+			return nil, err
+		}
+	}
+	return set, nil
+}
+
+func (s *PromptSet) addDir(dir fs.FS) error {
+	files, err := fs.Glob(dir, "*.tmpl")
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		style, version, ok := strings.Cut(strings.TrimSuffix(path.Base(file), ".tmpl"), ".")
+		if !ok || style == "" || version == "" {
+			return fmt.Errorf("prompt file %s must be named <style>.<version>.tmpl", file)
+		}
+
+		text, err := fs.ReadFile(dir, file)
+		if err != nil {
+			return fmt.Errorf("failed to read prompt %s: %w", file, err)
+		}
+		tmpl, err := template.New(file).Option("missingkey=error").Parse(string(text))
+		if err != nil {
+			return fmt.Errorf("failed to parse prompt %s: %w", file, err)
+		}
+
+		sum := sha256.Sum256(text)
+		if s.templates[style] == nil {
+			s.templates[style] = make(map[string]*PromptTemplate)
+		}
+		s.templates[style][version] = &PromptTemplate{
+			Style:   style,
+			Version: version,
+			Digest:  hex.EncodeToString(sum[:4]),
+			tmpl:    tmpl,
+		}
+	}
+	return nil
+}
+
+// Get returns a style's prompt at the given version, or its latest version
+// when version is empty
+func (s *PromptSet) Get(style, version string) (*PromptTemplate, error) {
+	versions := s.templates[style]
+	if len(versions) == 0 {
+		return nil, fmt.Errorf("no prompt templates for style %q", style)
+	}
+	if version == "" {
+		version = latestVersion(versions)
+	}
+	p, ok := versions[version]
+	if !ok {
+		return nil, fmt.Errorf("no prompt version %q for style %q", version, style)
+	}
+	return p, nil
+}
+
+// Versions lists the prompt versions available for a style, oldest first
+func (s *PromptSet) Versions(style string) []string {
+	versions := make([]string, 0, len(s.templates[style]))
+	for v := range s.templates[style] {
+		versions = append(versions, v)
+	}
+	sort.Slice(versions, func(i, j int) bool { return versionLess(versions[i], versions[j]) })
+	return versions
+}
+
+func latestVersion(versions map[string]*PromptTemplate) string {
+	latest := ""
+	for v := range versions {
+		if latest == "" || versionLess(latest, v) {
+			latest = v
+		}
+	}
+	return latest
+}
+
+// versionLess orders "v2" before "v10"; non-numeric versions sort lexically
+func versionLess(a, b string) bool {
+	na, errA := strconv.Atoi(strings.TrimPrefix(a, "v"))
+	nb, errB := strconv.Atoi(strings.TrimPrefix(b, "v"))
+	if errA == nil && errB == nil {
+		return na < nb
+	}
+	return a < b
+}
+
+var (
+	defaultPromptsOnce sync.Once
+	defaultPrompts     *PromptSet
+)
+
+// DefaultPrompts returns the embedded prompt templates without overrides
+func DefaultPrompts() *PromptSet {
+	defaultPromptsOnce.Do(func() {
+		set, err := LoadPrompts("")
+		if err != nil {
+			panic(fmt.Sprintf("embedded prompts are invalid: %v", err))
+		}
+		defaultPrompts = set
+	})
+	return defaultPrompts
+}
@@ -0,0 +1,89 @@
+package llm
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefaultPrompts(t *testing.T) {
+	prompts := DefaultPrompts()
+
+	for _, name := range StyleNames() {
+		t.Run(name, func(t *testing.T) {
+			tmpl, err := prompts.Get(name, "")
+			require.NoError(t, err, "every style needs an embedded prompt")
+
+			out, err := tmpl.Render(PromptData{LanguageName: "English", Bullets: 3, MaxWidth: 280})
+			require.NoError(t, err)
+			assert.NotEmpty(t, out)
+			assert.Regexp(t, `^`+name+`\.v\d+@[0-9a-f]{8}$`, tmpl.ID())
+		})
+	}
+}
+
+func TestLoadPrompts_Overrides(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "headline.v2.tmpl"),
+		[]byte("Override v2 with {{.Bullets}} bullets in {{.LanguageName}}."), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "headline.v10.tmpl"),
+		[]byte("Override v10."), 0o644))
+
+	prompts, err := LoadPrompts(dir)
+	require.NoError(t, err)
+
+	t.Run("latest version wins by number", func(t *testing.T) {
+		tmpl, err := prompts.Get("headline", "")
+		require.NoError(t, err)
+		assert.Equal(t, "v10", tmpl.Version)
+		assert.Equal(t, []string{"v1", "v2", "v10"}, prompts.Versions("headline"))
+	})
+
+	t.Run("pinned version renders its own text", func(t *testing.T) {
+		tmpl, err := prompts.Get("headline", "v2")
+		require.NoError(t, err)
+		out, err := tmpl.Render(PromptData{LanguageName: "German", Bullets: 2})
+		require.NoError(t, err)
+		assert.Equal(t, "Override v2 with 2 bullets in German.", out)
+	})
+
+	t.Run("embedded versions are still available", func(t *testing.T) {
+		embedded, err := DefaultPrompts().Get("headline", "v1")
+		require.NoError(t, err)
+		tmpl, err := prompts.Get("headline", "v1")
+		require.NoError(t, err)
+		assert.Equal(t, embedded.ID(), tmpl.ID())
+	})
+}
+
+func TestLoadPrompts_Errors(t *testing.T) {
+	t.Run("misnamed file", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "headline.tmpl"), []byte("x"), 0o644))
+		_, err := LoadPrompts(dir)
+		assert.Error(t, err)
+	})
+
+	t.Run("invalid template", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "headline.v2.tmpl"), []byte("{{.Bullets"), 0o644))
+		_, err := LoadPrompts(dir)
+		assert.Error(t, err)
+	})
+
+	t.Run("missing directory", func(t *testing.T) {
+		_, err := LoadPrompts(filepath.Join(t.TempDir(), "prompts"))
+		assert.ErrorIs(t, err, fs.ErrNotExist)
+	})
+
+	t.Run("file instead of directory", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "headline.v2.tmpl")
+		require.NoError(t, os.WriteFile(file, []byte("x"), 0o644))
+		_, err := LoadPrompts(file)
+		assert.ErrorContains(t, err, "not a directory")
+	})
+}
@@ -0,0 +1,177 @@
+package llm
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/matthewmolinar/tldr/pkg/config"
+	"github.com/sashabaranov/go-openai"
+)
+
+// AuthMode is how a provider expects its API key to be sent
+type AuthMode string
+
+const (
+	AuthBearer AuthMode = "bearer" // Authorization: Bearer <key>, as OpenAI and vLLM do
+	AuthHeader AuthMode = "header" // the key alone in a custom header such as X-API-Key
+	AuthNone   AuthMode = "none"   // no credentials, as a local Ollama or llama.cpp server
+)
+
+// ProviderConfig describes an OpenAI-compatible chat completions endpoint,
+// such as a self-hosted Ollama, llama.cpp or vLLM server
+type ProviderConfig struct {
+	BaseURL string // API root, e.g. http://localhost:11434/v1
+	Model   string // model used when a request names none
+	APIKey  string
+
+	Auth       AuthMode // defaults to AuthBearer, or AuthNone without an API key
+	AuthHeader string   // header carrying the key for AuthHeader
+
+	// ContextTokens is the model's context window, used to truncate long
+	// articles. Zero falls back to the known OpenAI models.
+	ContextTokens int
+
+	HTTPClient *http.Client // defaults to http.DefaultClient
+}
+
+// ParseAuth parses an auth mode setting: "bearer", "none", or
+// "header:<name>" for a custom header. Empty means bearer.
+func ParseAuth(s string) (mode AuthMode, header string, err error) {
+	s = strings.TrimSpace(s)
+	switch {
+	case s == "" || strings.EqualFold(s, string(AuthBearer)):
+		return AuthBearer, "", nil
+	case strings.EqualFold(s, string(AuthNone)):
+		return AuthNone, "", nil
+	case strings.HasPrefix(strings.ToLower(s), string(AuthHeader)+":"):
+		header = strings.TrimSpace(s[len(AuthHeader)+1:])
+		if header == "" {
+			return "", "", errors.New("auth mode header: needs a header name")
+		}
+		return AuthHeader, header, nil
+	}
+	return "", "", fmt.Errorf("unknown auth mode %q (want bearer, none or header:<name>)", s)
+}
+
+// NewProvider creates a client for an OpenAI-compatible endpoint
+func NewProvider(cfg ProviderConfig) (*Client, error) {
+	u, err := url.Parse(cfg.BaseURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid provider base URL %q", cfg.BaseURL)
+	}
+	if cfg.Model == "" {
+		return nil, errors.New("provider model is required")
+	}
+
+	auth := cfg.Auth
+	if auth == "" {
+		auth = AuthBearer
+		if cfg.APIKey == "" {
+			auth = AuthNone
+		}
+	}
+	switch auth {
+	case AuthBearer, AuthNone:
+	case AuthHeader:
+		if cfg.AuthHeader == "" {
+			return nil, errors.New("provider auth mode header needs a header name")
+		}
+	default:
+		return nil, fmt.Errorf("unknown provider auth mode %q", auth)
+	}
+	if auth != AuthNone && cfg.APIKey == "" {
+		return nil, fmt.Errorf("provider auth mode %s needs an API key", auth)
+	}
+
+	hc := http.DefaultClient
+	if cfg.HTTPClient != nil {
+		hc = cfg.HTTPClient
+	}
+	wrapped := *hc
+	base := wrapped.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	wrapped.Transport = &authTransport{base: base, mode: auth, header: cfg.AuthHeader, key: cfg.APIKey}
+
+	config := openai.DefaultConfig(cfg.APIKey)
+	config.BaseURL = strings.TrimRight(cfg.BaseURL, "/")
+	config.HTTPClient = &wrapped
+
+	client := NewClientWithConfig(config)
+	client.Config = DefaultModelConfig
+	client.Config.Model = cfg.Model
+	client.ContextTokens = cfg.ContextTokens
+	return client, nil
+}
+
+// authTransport rewrites the bearer token go-openai always sends into what
+// the provider expects
+type authTransport struct {
+	base   http.RoundTripper
+	mode   AuthMode
+	header string
+	key    string
+}
+
+func (t *authTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.mode == AuthBearer {
+		return t.base.RoundTrip(req)
+	}
+	req = req.Clone(req.Context())
+	req.Header.Del("Authorization")
+	if t.mode == AuthHeader {
+		req.Header.Set(t.header, t.key)
+	}
+	return t.base.RoundTrip(req)
+}
+
+// NewLocalClient creates a client for the self-hosted model server in
+// cfg.Local, with the prompts and sampling settings of cfg. hc makes the
+// calls; nil uses http.DefaultClient.
+func NewLocalClient(cfg config.LLM, hc *http.Client) (*Client, error) {
+	local := cfg.Local
+	if local.BaseURL == "" {
+		return nil, errors.New("local model server base URL is required")
+	}
+
+	// Without an explicit mode NewProvider picks bearer or none by the key
+	var auth AuthMode
+	var header string
+	if local.Auth != "" {
+		var err error
+		auth, header, err = ParseAuth(local.Auth)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if local.ContextTokens != 0 && local.ContextTokens <= completionReserveTokens {
+		return nil, fmt.Errorf("local model context window must be above %d tokens, got %d", completionReserveTokens, local.ContextTokens)
+	}
+
+	client, err := NewProvider(ProviderConfig{
+		BaseURL:       local.BaseURL,
+		Model:         local.Model,
+		APIKey:        local.APIKey,
+		Auth:          auth,
+		AuthHeader:    header,
+		ContextTokens: local.ContextTokens,
+		HTTPClient:    hc,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	prompts, err := LoadPrompts(cfg.PromptDir)
+	if err != nil {
+		return nil, err
+	}
+	client.Prompts = prompts
+
+	// Sampling settings are shared with the OpenAI provider; the models aren't
+	client.Config.Temperature, client.Config.TopP, client.Config.MaxTokens = cfg.Temperature, cfg.TopP, cfg.MaxTokens
+	return client, client.Config.Validate()
+}
@@ -0,0 +1,234 @@
+package llm
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/matthewmolinar/tldr/pkg/config"
+	"github.com/matthewmolinar/tldr/pkg/llm/llmtest"
+	"github.com/sashabaranov/go-openai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const localSummary = "Headline: Local model works\n- First point\n- Second point\n- Third point"
+
+func TestParseAuth(t *testing.T) {
+	tests := []struct {
+		in, header string
+		want       AuthMode
+		wantErr    bool
+	}{
+		{in: "", want: AuthBearer},
+		{in: "Bearer", want: AuthBearer},
+		{in: "none", want: AuthNone},
+		{in: "header:X-API-Key", want: AuthHeader, header: "X-API-Key"},
+		{in: "header:", wantErr: true},
+		{in: "basic", wantErr: true},
+	}
+	for _, tt := range tests {
+		mode, header, err := ParseAuth(tt.in)
+		if tt.wantErr {
+			assert.Error(t, err, tt.in)
+			continue
+		}
+		require.NoError(t, err, tt.in)
+		assert.Equal(t, tt.want, mode, tt.in)
+		assert.Equal(t, tt.header, header, tt.in)
+	}
+}
+
+func TestNewProvider(t *testing.T) {
+	t.Run("rejects bad config", func(t *testing.T) {
+		for _, cfg := range []ProviderConfig{
+			{BaseURL: "localhost:11434", Model: "llama3"},
+			{BaseURL: "http://localhost:11434/v1"},
+			{BaseURL: "http://localhost:11434/v1", Model: "llama3", Auth: AuthBearer},
+			{BaseURL: "http://localhost:11434/v1", Model: "llama3", APIKey: "k", Auth: AuthHeader},
+		} {
+			_, err := NewProvider(cfg)
+			assert.Error(t, err, "%+v", cfg)
+		}
+	})
+
+	auth := []struct {
+		name        string
+		cfg         ProviderConfig
+		header, val string
+	}{
+		{"bearer", ProviderConfig{APIKey: "secret"}, "Authorization", "Bearer secret"},
+		{"custom header", ProviderConfig{APIKey: "secret", Auth: AuthHeader, AuthHeader: "X-API-Key"}, "X-API-Key", "secret"},
+		{"none", ProviderConfig{}, "Authorization", ""},
+	}
+	for _, tt := range auth {
+		t.Run("auth "+tt.name, func(t *testing.T) {
+			fake := llmtest.NewServer(llmtest.OK(localSummary))
+			defer fake.Close()
+			fake.AuthHeader, fake.AuthValue = tt.header, tt.val
+
+			tt.cfg.BaseURL = fake.BaseURL() + "/"
+			tt.cfg.Model = "llama3.1:8b"
+			client, err := NewProvider(tt.cfg)
+			require.NoError(t, err)
+
+			summary, err := client.Summarize(context.Background(), Request{Text: "First point. Second point. Third point."})
+			require.NoError(t, err)
+			assert.Equal(t, "Local model works", summary.Headline)
+			assert.Equal(t, "llama3.1:8b", summary.Model)
+			assert.Zero(t, summary.Usage.CostUSD)
+
+			reqs := fake.Requests()
+			require.Len(t, reqs, 1)
+			assert.Equal(t, "llama3.1:8b", reqs[0].Body.Model)
+			assert.Nil(t, reqs[0].Body.ResponseFormat, "self-hosted servers may not support JSON mode")
+			if tt.header != "Authorization" {
+				assert.Empty(t, reqs[0].Header.Get("Authorization"))
+			}
+		})
+	}
+
+	t.Run("request model overrides provider model", func(t *testing.T) {
+		fake := llmtest.NewServer(llmtest.OK(localSummary))
+		defer fake.Close()
+		client, err := NewProvider(ProviderConfig{BaseURL: fake.BaseURL(), Model: "llama3"})
+		require.NoError(t, err)
+
+		_, err = client.Summarize(context.Background(), Request{Text: "First point.", Model: "qwen2.5"})
+		require.NoError(t, err)
+		assert.Equal(t, "qwen2.5", fake.Requests()[0].Body.Model)
+	})
+
+	t.Run("truncates to the configured context window", func(t *testing.T) {
+		fake := llmtest.NewServer(llmtest.OK(localSummary))
+		defer fake.Close()
+		client, err := NewProvider(ProviderConfig{BaseURL: fake.BaseURL(), Model: "llama3", ContextTokens: 1024})
+		require.NoError(t, err)
+
+		summary, err := client.Summarize(context.Background(), Request{Text: strings.Repeat("First point. ", 2000)})
+		require.NoError(t, err)
+		assert.Contains(t, summary.Warnings, "article was truncated to fit the model's context window")
+		assert.LessOrEqual(t, summary.Usage.EstimatedPromptTokens, 1024-completionReserveTokens)
+	})
+}
+
+func TestNewLocalClient(t *testing.T) {
+	fake := llmtest.NewServer(llmtest.OK(localSummary))
+	defer fake.Close()
+	fake.AuthHeader, fake.AuthValue = "X-API-Key", "secret"
+
+	cfg := config.LLM{Local: config.LocalLLM{
+		BaseURL:       fake.BaseURL(),
+		Model:         "mistral",
+		APIKey:        "secret",
+		Auth:          "header:X-API-Key",
+		ContextTokens: 8192,
+	}}
+	client, err := NewLocalClient(cfg, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "mistral", client.Config.Model)
+	assert.Equal(t, 8192, client.ContextTokens)
+
+	_, err = client.Summarize(context.Background(), Request{Text: "First point."})
+	require.NoError(t, err)
+
+	cfg.Local.ContextTokens = 256
+	_, err = NewLocalClient(cfg, nil)
+	assert.Error(t, err)
+}
+
+func TestClient_Ping(t *testing.T) {
+	fake := llmtest.NewServer()
+	defer fake.Close()
+	client, err := NewProvider(ProviderConfig{BaseURL: fake.BaseURL(), Model: "llama3", APIKey: "secret"})
+	require.NoError(t, err)
+
+	assert.NoError(t, client.Ping(context.Background()), "servers listing no models are trusted")
+
+	fake.Models = []string{"mistral", "llama3"}
+	assert.NoError(t, client.Ping(context.Background()))
+
+	fake.Models = []string{"mistral"}
+	assert.ErrorContains(t, client.Ping(context.Background()), `model "llama3"`)
+
+	fake.AuthHeader, fake.AuthValue = "Authorization", "Bearer other"
+	assert.Error(t, client.Ping(context.Background()))
+	assert.Zero(t, fake.Calls(), "probes are not chat completions")
+}
+
+func TestClient_Summarize_ProviderQuirks(t *testing.T) {
+	summarize := func(t *testing.T, reply llmtest.Reply) (*Summary, error) {
+		fake := llmtest.NewServer(reply)
+		t.Cleanup(fake.Close)
+		client, err := NewProvider(ProviderConfig{BaseURL: fake.BaseURL(), Model: "deepseek-r1"})
+		require.NoError(t, err)
+		return client.Summarize(context.Background(), Request{Text: "First point. Second point. Third point."})
+	}
+
+	t.Run("strips reasoning and markdown", func(t *testing.T) {
+		summary, err := summarize(t, llmtest.OK("<think>\nThe user wants a summary.\n- Not a bullet\n</think>\n```\n**Headline:** Local model works\n- **First** point\n- Second point\n- Third point\n```"))
+		require.NoError(t, err)
+		assert.Equal(t, "Local model works", summary.Headline)
+		assert.Equal(t, []string{"First point", "Second point", "Third point"}, summary.Bullets)
+	})
+
+	t.Run("accepts JSON answers", func(t *testing.T) {
+		summary, err := summarize(t, llmtest.OK(`{"headline": "Local model works", "bullets": ["First point", "Second point", "Third point"]}`))
+		require.NoError(t, err)
+		assert.Equal(t, "Local model works", summary.Headline)
+		assert.Len(t, summary.Bullets, 3)
+	})
+
+	t.Run("maps non-standard finish reasons", func(t *testing.T) {
+		for _, reason := range []string{"eos", "eos_token", "end_turn"} {
+			reply := llmtest.OK(localSummary)
+			reply.FinishReason = reason
+			summary, err := summarize(t, reply)
+			require.NoError(t, err, reason)
+			assert.Empty(t, summary.Warnings, reason)
+		}
+	})
+
+	t.Run("warns when the output limit was hit", func(t *testing.T) {
+		reply := llmtest.OK(localSummary)
+		reply.FinishReason = "max_tokens"
+		summary, err := summarize(t, reply)
+		require.NoError(t, err)
+		assert.Contains(t, summary.Warnings, "summary may be incomplete because the model hit its output limit")
+	})
+
+	t.Run("content filter is an error", func(t *testing.T) {
+		reply := llmtest.OK(localSummary)
+		reply.FinishReason = "content_filter"
+		_, err := summarize(t, reply)
+		assert.ErrorIs(t, err, ErrContentFiltered)
+	})
+
+	t.Run("estimates missing usage", func(t *testing.T) {
+		summary, err := summarize(t, llmtest.OK(localSummary))
+		require.NoError(t, err)
+		assert.Equal(t, summary.Usage.EstimatedPromptTokens, summary.Usage.PromptTokens)
+		assert.Equal(t, EstimateTokens(localSummary), summary.Usage.CompletionTokens)
+
+		reply := llmtest.OK(localSummary)
+		reply.Usage = &openai.Usage{PromptTokens: 321, CompletionTokens: 45}
+		summary, err = summarize(t, reply)
+		require.NoError(t, err)
+		assert.Equal(t, 321, summary.Usage.PromptTokens)
+		assert.Equal(t, 45, summary.Usage.CompletionTokens)
+	})
+
+	t.Run("provider errors pass through", func(t *testing.T) {
+		_, err := summarize(t, llmtest.Error(http.StatusBadRequest, "model_not_found"))
+		assert.Error(t, err)
+		assert.False(t, IsRetryable(context.Background(), err))
+	})
+}
+
+func TestNormalizeFinishReason(t *testing.T) {
+	assert.Equal(t, openai.FinishReasonStop, normalizeFinishReason(""))
+	assert.Equal(t, openai.FinishReasonStop, normalizeFinishReason("EOS"))
+	assert.Equal(t, openai.FinishReasonLength, normalizeFinishReason("model_length"))
+	assert.Equal(t, openai.FinishReasonToolCalls, normalizeFinishReason(openai.FinishReasonToolCalls))
+}
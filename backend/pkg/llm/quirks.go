@@ -0,0 +1,68 @@
+package llm
+
+import (
+	"encoding/json"
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// ErrContentFiltered is returned when the provider withheld the summary
+var ErrContentFiltered = errors.New("summary was blocked by the provider's content filter")
+
+// Self-hosted models don't follow the output format as reliably as OpenAI's:
+// reasoning models emit their chain of thought, chat-tuned models wrap the
+// answer in Markdown, and none of them support JSON mode, so some answer in
+// ad-hoc JSON when asked for structure.
+var (
+	thinkRe = regexp.MustCompile(`(?s)<think>.*?(</think>|$)`)
+	fenceRe = regexp.MustCompile("(?m)^\\s*```[a-zA-Z]*\\s*$")
+)
+
+// normalizeOutput rewrites model output into the plain "Headline: / - point"
+// format parseSummary expects
+func normalizeOutput(content string) string {
+	content = thinkRe.ReplaceAllString(content, "")
+	content = fenceRe.ReplaceAllString(content, "")
+	content = strings.TrimSpace(content)
+
+	if strings.HasPrefix(content, "{") {
+		var out struct {
+			Headline string   `json:"headline"`
+			Bullets  []string `json:"bullets"`
+		}
+		if json.Unmarshal([]byte(content), &out) == nil && out.Headline != "" {
+			lines := []string{"Headline: " + out.Headline}
+			for _, b := range out.Bullets {
+				lines = append(lines, "- "+b)
+			}
+			return strings.Join(lines, "\n")
+		}
+	}
+
+	lines := strings.Split(content, "\n")
+	for i, line := range lines {
+		line = strings.ReplaceAll(line, "**", "")
+		line = strings.TrimLeft(line, "# ")
+		lines[i] = line
+	}
+	return strings.Join(lines, "\n")
+}
+
+// normalizeFinishReason maps the finish reasons of OpenAI-compatible servers
+// onto OpenAI's. Ollama and llama.cpp report "eos" or omit the field, TGI
+// reports "eos_token", and Anthropic-style proxies report "end_turn" and
+// "max_tokens".
+func normalizeFinishReason(reason openai.FinishReason) openai.FinishReason {
+	switch strings.ToLower(string(reason)) {
+	case "", "stop", "eos", "eos_token", "end_turn", "stop_sequence":
+		return openai.FinishReasonStop
+	case "length", "max_tokens", "model_length":
+		return openai.FinishReasonLength
+	case "content_filter":
+		return openai.FinishReasonContentFilter
+	}
+	return reason
+}
@@ -0,0 +1,184 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// RetryPolicy decides how often and how patiently failed LLM calls are retried
+type RetryPolicy struct {
+	MaxAttempts int           // total attempts including the first; 1 disables retries
+	BaseDelay   time.Duration // backoff before the second attempt, doubled for each one after
+	MaxDelay    time.Duration // cap on a single backoff, and on how long a Retry-After is honored
+
+	// jitter returns a value in [0, 1); nil uses math/rand
+	jitter func() float64
+}
+
+// DefaultRetryPolicy is used by clients that don't set one
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   500 * time.Millisecond,
+	MaxDelay:    10 * time.Second,
+}
+
+// backoff returns the delay before the given retry (1 for the first retry):
+// a random duration between half and all of the exponential step, so clients
+// that failed together don't retry together
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	step := p.BaseDelay << (retry - 1)
+	if step > p.MaxDelay || step <= 0 {
+		step = p.MaxDelay
+	}
+	jitter := rand.Float64
+	if p.jitter != nil {
+		jitter = p.jitter
+	}
+	return step/2 + time.Duration(jitter()*float64(step/2))
+}
+
+// Do calls fn until it succeeds, fails with a non-retryable error, runs out of
+// attempts, or the next wait would pass ctx's deadline. A Retry-After sent
+// with the failure replaces the computed backoff.
+func (p RetryPolicy) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	attempts := max(p.MaxAttempts, 1)
+	var err error
+	for attempt := 1; ; attempt++ {
+		hint := &retryAfterHint{}
+		err = fn(context.WithValue(ctx, retryAfterKey{}, hint))
+		if err == nil || attempt == attempts || !IsRetryable(ctx, err) {
+			return err
+		}
+
+		wait := p.backoff(attempt)
+		if after, ok := hint.get(); ok {
+			if after > p.MaxDelay {
+				log.Printf("LLM call failed: %v; Retry-After %s exceeds the %s limit, giving up", err, after, p.MaxDelay)
+				return err
+			}
+			wait = after
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) <= wait {
+			log.Printf("LLM call failed: %v; not enough time left before the deadline to retry", err)
+			return err
+		}
+
+		log.Printf("LLM call failed (attempt %d/%d): %v; retrying in %s", attempt, attempts, err, wait.Round(time.Millisecond))
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// IsRetryable reports whether err is worth another attempt: rate limits
+// (except exhausted quota), server errors, timeouts and dropped connections.
+// Nothing is retryable once ctx itself is done.
+func IsRetryable(ctx context.Context, err error) bool {
+	if err == nil || ctx.Err() != nil {
+		return false
+	}
+
+	var apiErr *openai.APIError
+	if errors.As(err, &apiErr) {
+		if code, _ := apiErr.Code.(string); code == "insufficient_quota" {
+			return false
+		}
+		return retryableStatus(apiErr.HTTPStatusCode)
+	}
+	var reqErr *openai.RequestError
+	if errors.As(err, &reqErr) {
+		return retryableStatus(reqErr.HTTPStatusCode)
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+func retryableStatus(status int) bool {
+	switch status {
+	case http.StatusRequestTimeout, http.StatusConflict, http.StatusTooManyRequests,
+		http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// retryAfterKey carries a *retryAfterHint in the request context
+type retryAfterKey struct{}
+
+// retryAfterHint receives the Retry-After of a failed attempt. The OpenAI
+// client's errors don't expose response headers, so retryAfterTransport
+// stores it here on the way back.
+type retryAfterHint struct {
+	mu    sync.Mutex
+	after time.Duration
+	set   bool
+}
+
+func (h *retryAfterHint) put(d time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.after, h.set = d, true
+}
+
+func (h *retryAfterHint) get() (time.Duration, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.after, h.set
+}
+
+// retryAfterTransport records Retry-After headers for RetryPolicy.Do
+type retryAfterTransport struct {
+	base http.RoundTripper
+}
+
+func (t *retryAfterTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil || resp.StatusCode < 400 {
+		return resp, err
+	}
+	if hint, ok := req.Context().Value(retryAfterKey{}).(*retryAfterHint); ok {
+		if d, ok := parseRetryAfter(resp.Header, time.Now()); ok {
+			hint.put(d)
+		}
+	}
+	return resp, err
+}
+
+// parseRetryAfter reads OpenAI's retry-after-ms, else the standard
+// Retry-After in seconds or as an HTTP date
+func parseRetryAfter(h http.Header, now time.Time) (time.Duration, bool) {
+	if ms, err := strconv.ParseFloat(h.Get("Retry-After-Ms"), 64); err == nil && ms >= 0 {
+		return time.Duration(ms * float64(time.Millisecond)), true
+	}
+	v := h.Get("Retry-After")
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+	if at, err := http.ParseTime(v); err == nil {
+		return max(at.Sub(now), 0), true
+	}
+	return 0, false
+}
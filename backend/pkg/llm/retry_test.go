@@ -0,0 +1,150 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/matthewmolinar/tldr/pkg/llm/llmtest"
+	"github.com/sashabaranov/go-openai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newFakeClient points a client with a fast retry policy at a fake server
+func newFakeClient(t *testing.T, script ...llmtest.Reply) (*Client, *llmtest.Server) {
+	fake := llmtest.NewServer(script...)
+	t.Cleanup(fake.Close)
+
+	config := openai.DefaultConfig("test-key")
+	config.BaseURL = fake.BaseURL()
+	client := NewClientWithConfig(config)
+	client.Retry = RetryPolicy{MaxAttempts: 3, BaseDelay: 5 * time.Millisecond, MaxDelay: time.Second}
+	return client, fake
+}
+
+// withHeader adds a response header to a scripted reply
+func withHeader(r llmtest.Reply, key, value string) llmtest.Reply {
+	r.Header = map[string]string{key: value}
+	return r
+}
+
+func TestClient_Summarize_Retries(t *testing.T) {
+	t.Run("recovers from transient failures", func(t *testing.T) {
+		client, fake := newFakeClient(t,
+			llmtest.Error(http.StatusTooManyRequests, "rate_limit_exceeded"),
+			llmtest.Error(http.StatusServiceUnavailable, ""),
+			llmtest.OK("Headline: Recovered\n- Point"),
+		)
+		summary, err := client.Summarize(context.Background(), Request{Text: "Point of the story."})
+		require.NoError(t, err)
+		assert.Equal(t, "Recovered", summary.Headline)
+		assert.Equal(t, 3, fake.Calls())
+	})
+
+	t.Run("honors Retry-After", func(t *testing.T) {
+		client, fake := newFakeClient(t,
+			withHeader(llmtest.Error(http.StatusTooManyRequests, "rate_limit_exceeded"), "Retry-After-Ms", "150"),
+			llmtest.OK("Headline: Recovered\n- Point"),
+		)
+		_, err := client.Summarize(context.Background(), Request{Text: "Point of the story."})
+		require.NoError(t, err)
+		calls := fake.Requests()
+		require.Len(t, calls, 2)
+		assert.GreaterOrEqual(t, calls[1].At.Sub(calls[0].At), 150*time.Millisecond)
+	})
+
+	t.Run("gives up after max attempts", func(t *testing.T) {
+		client, fake := newFakeClient(t, llmtest.Error(http.StatusBadGateway, ""))
+		_, err := client.Summarize(context.Background(), Request{Text: "Point of the story."})
+		var apiErr *openai.APIError
+		require.ErrorAs(t, err, &apiErr)
+		assert.Equal(t, http.StatusBadGateway, apiErr.HTTPStatusCode)
+		assert.Equal(t, 3, fake.Calls())
+	})
+
+	t.Run("does not retry client errors or exhausted quota", func(t *testing.T) {
+		client, fake := newFakeClient(t, llmtest.Error(http.StatusBadRequest, "invalid_request"))
+		_, err := client.Summarize(context.Background(), Request{Text: "Point of the story."})
+		assert.Error(t, err)
+		assert.Equal(t, 1, fake.Calls())
+
+		client, fake = newFakeClient(t, llmtest.Error(http.StatusTooManyRequests, "insufficient_quota"))
+		_, err = client.Summarize(context.Background(), Request{Text: "Point of the story."})
+		assert.Error(t, err)
+		assert.Equal(t, 1, fake.Calls())
+	})
+
+	t.Run("stops when the wait would pass the deadline", func(t *testing.T) {
+		client, fake := newFakeClient(t,
+			withHeader(llmtest.Error(http.StatusTooManyRequests, "rate_limit_exceeded"), "Retry-After", "1"),
+			llmtest.OK("Headline: Recovered\n- Point"),
+		)
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		defer cancel()
+
+		start := time.Now()
+		_, err := client.Summarize(ctx, Request{Text: "Point of the story."})
+		assert.Error(t, err)
+		assert.Equal(t, 1, fake.Calls())
+		assert.Less(t, time.Since(start), 150*time.Millisecond)
+	})
+}
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	p := RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+
+	p.jitter = func() float64 { return 0 }
+	assert.Equal(t, 50*time.Millisecond, p.backoff(1))
+	assert.Equal(t, 100*time.Millisecond, p.backoff(2))
+	assert.Equal(t, 500*time.Millisecond, p.backoff(10))
+
+	p.jitter = func() float64 { return 0.999999 }
+	assert.InDelta(t, float64(100*time.Millisecond), float64(p.backoff(1)), float64(time.Microsecond))
+	assert.InDelta(t, float64(time.Second), float64(p.backoff(5)), float64(time.Microsecond))
+	assert.InDelta(t, float64(time.Second), float64(p.backoff(80)), float64(time.Microsecond))
+}
+
+func TestIsRetryable(t *testing.T) {
+	ctx := context.Background()
+	assert.True(t, IsRetryable(ctx, &openai.APIError{HTTPStatusCode: 429}))
+	assert.True(t, IsRetryable(ctx, &openai.RequestError{HTTPStatusCode: 503}))
+	assert.False(t, IsRetryable(ctx, &openai.APIError{HTTPStatusCode: 401}))
+	assert.False(t, IsRetryable(ctx, &openai.APIError{HTTPStatusCode: 429, Code: "insufficient_quota"}))
+	assert.False(t, IsRetryable(ctx, errors.New("invalid response format")))
+	assert.False(t, IsRetryable(ctx, ErrInvalidSummary))
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	assert.False(t, IsRetryable(canceled, &openai.APIError{HTTPStatusCode: 503}))
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	header := func(kv ...string) http.Header {
+		h := http.Header{}
+		for i := 0; i < len(kv); i += 2 {
+			h.Set(kv[i], kv[i+1])
+		}
+		return h
+	}
+
+	d, ok := parseRetryAfter(header("Retry-After", "7"), now)
+	assert.True(t, ok)
+	assert.Equal(t, 7*time.Second, d)
+
+	d, ok = parseRetryAfter(header("Retry-After", now.Add(3*time.Second).Format(http.TimeFormat)), now)
+	assert.True(t, ok)
+	assert.Equal(t, 3*time.Second, d)
+
+	d, ok = parseRetryAfter(header("Retry-After", "7", "Retry-After-Ms", "250"), now)
+	assert.True(t, ok)
+	assert.Equal(t, 250*time.Millisecond, d)
+
+	_, ok = parseRetryAfter(header("Retry-After", "soon"), now)
+	assert.False(t, ok)
+	_, ok = parseRetryAfter(header(), now)
+	assert.False(t, ok)
+}
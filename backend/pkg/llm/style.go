@@ -0,0 +1,95 @@
+package llm
+
+import (
+	"fmt"
+	"sort"
+)
+
+// DefaultStyle is the summary style used when a request names none
+const DefaultStyle = "headline"
+
+// Style is a named summary preset with its own limits. Its instructions live
+// in the prompt templates named after it (see prompts/).
+type Style struct {
+	Name           string
+	MinBullets     int
+	MaxBullets     int
+	DefaultBullets int
+	MaxWidth       int // display-cell budget for headline plus bullets
+}
+
+var styles = map[string]Style{
+	"headline": {
+		Name:           "headline",
+		MinBullets:     1,
+		MaxBullets:     5,
+		DefaultBullets: 3,
+		MaxWidth:       MaxSummaryWidth,
+	},
+	"tweet": {
+		Name:           "tweet",
+		MinBullets:     0,
+		MaxBullets:     2,
+		DefaultBullets: 1,
+		MaxWidth:       MaxSummaryWidth,
+	},
+	"executive": {
+		Name:           "executive",
+		MinBullets:     3,
+		MaxBullets:     6,
+		DefaultBullets: 4,
+		MaxWidth:       600,
+	},
+	"eli5": {
+		Name:           "eli5",
+		MinBullets:     2,
+		MaxBullets:     4,
+		DefaultBullets: 3,
+		MaxWidth:       400,
+	},
+	"technical": {
+		Name:           "technical",
+		MinBullets:     3,
+		MaxBullets:     6,
+		DefaultBullets: 4,
+		MaxWidth:       600,
+	},
+	"one_liner": {
+		Name:     "one_liner",
+		MaxWidth: 140,
+	},
+}
+
+// LookupStyle returns the named style, or DefaultStyle when name is empty
+func LookupStyle(name string) (Style, error) {
+	if name == "" {
+		name = DefaultStyle
+	}
+	s, ok := styles[name]
+	if !ok {
+		return Style{}, fmt.Errorf("unknown summary style %q", name)
+	}
+	return s, nil
+}
+
+// StyleNames lists the available styles in alphabetical order
+func StyleNames() []string {
+	names := make([]string, 0, len(styles))
+	for name := range styles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// BulletCount resolves a requested bullet count against the style's range.
+// A nil request selects the style default.
+func (s Style) BulletCount(requested *int) (int, error) {
+	if requested == nil {
+		return s.DefaultBullets, nil
+	}
+	if *requested < s.MinBullets || *requested > s.MaxBullets {
+		return 0, fmt.Errorf("style %q supports %d to %d bullets, got %d", s.Name, s.MinBullets, s.MaxBullets, *requested)
+	}
+	return *requested, nil
+}
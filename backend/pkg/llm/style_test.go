@@ -0,0 +1,63 @@
+package llm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLookupStyle(t *testing.T) {
+	t.Run("empty name selects the default style", func(t *testing.T) {
+		s, err := LookupStyle("")
+		require.NoError(t, err)
+		assert.Equal(t, DefaultStyle, s.Name)
+	})
+
+	t.Run("every listed style resolves", func(t *testing.T) {
+		for _, name := range StyleNames() {
+			s, err := LookupStyle(name)
+			require.NoError(t, err)
+			assert.Equal(t, name, s.Name)
+			assert.LessOrEqual(t, s.MinBullets, s.DefaultBullets)
+			assert.LessOrEqual(t, s.DefaultBullets, s.MaxBullets)
+			assert.Positive(t, s.MaxWidth)
+		}
+	})
+
+	t.Run("unknown style is an error", func(t *testing.T) {
+		_, err := LookupStyle("haiku")
+		assert.Error(t, err)
+	})
+}
+
+func TestStyle_BulletCount(t *testing.T) {
+	headline, err := LookupStyle("headline")
+	require.NoError(t, err)
+
+	intPtr := func(n int) *int { return &n }
+
+	tests := []struct {
+		name      string
+		requested *int
+		want      int
+		wantErr   bool
+	}{
+		{name: "nil selects default", requested: nil, want: 3},
+		{name: "within range", requested: intPtr(5), want: 5},
+		{name: "below range", requested: intPtr(0), wantErr: true},
+		{name: "above range", requested: intPtr(6), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := headline.BulletCount(tt.requested)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package llm
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// messageOverheadTokens covers the role and framing tokens the chat
+	// format adds to every message
+	messageOverheadTokens = 4
+
+	// completionReserveTokens is held back from the context window for the
+	// summary itself
+	completionReserveTokens = 512
+)
+
+// EstimateTokens approximates how many tokens text costs without a
+// tokenizer: about four bytes per token for alphabetic scripts, and one token
+// per character for Han, kana and Hangul, which BPE vocabularies split finely
+func EstimateTokens(text string) int {
+	wide, other := 0, 0
+	for _, r := range text {
+		if unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) {
+			wide++
+		} else {
+			other += utf8.RuneLen(r)
+		}
+	}
+	return wide + (other+3)/4
+}
+
+// estimatePrompt approximates the prompt tokens of a system and user message
+func estimatePrompt(system, user string) int {
+	return EstimateTokens(system) + EstimateTokens(user) + 2*messageOverheadTokens
+}
+
+// truncateToTokens cuts text so its estimate fits within maxTokens, keeping
+// the beginning of the article, where news puts the most important facts
+func truncateToTokens(text string, maxTokens int) string {
+	if maxTokens <= 0 {
+		return ""
+	}
+	if EstimateTokens(text) <= maxTokens {
+		return text
+	}
+
+	// Binary search the longest rune prefix that fits
+	runes := []rune(text)
+	lo, hi := 0, len(runes)
+	for lo < hi {
+		mid := (lo + hi + 1) / 2
+		if EstimateTokens(string(runes[:mid])) <= maxTokens {
+			lo = mid
+		} else {
+			hi = mid - 1
+		}
+	}
+	return string(runes[:lo])
+}
@@ -0,0 +1,263 @@
+// Package metrics keeps in-process counters and gauges for usage, cost and
+// reliability reporting
+package metrics
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Type is the kind of a metric family
+type Type string
+
+const (
+	TypeCounter   Type = "counter"
+	TypeGauge     Type = "gauge"
+	TypeHistogram Type = "histogram"
+)
+
+// Labels distinguish the series of one metric, e.g. {"model": "gpt-4o"}
+type Labels map[string]string
+
+// key is a stable identity for a label set
+func (l Labels) key() string {
+	keys := make([]string, 0, len(l))
+	for k := range l {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	for _, k := range keys {
+		b.WriteString(k)
+		b.WriteByte('=')
+		b.WriteString(l[k])
+		b.WriteByte(0xff)
+	}
+	return b.String()
+}
+
+// Sample is the current value of one labeled series
+type Sample struct {
+	Labels Labels  `json:"labels,omitempty"`
+	Value  float64 `json:"value"` // for histograms, the sum of observations
+
+	// Histograms only: the number of observations, and the cumulative count
+	// of those at or below each of the family's Bounds
+	Count   uint64   `json:"count,omitempty"`
+	Buckets []uint64 `json:"buckets,omitempty"`
+}
+
+// Family is a snapshot of one metric and all its series
+type Family struct {
+	Name    string    `json:"name"`
+	Help    string    `json:"help"`
+	Type    Type      `json:"type"`
+	Bounds  []float64 `json:"bounds,omitempty"` // histogram bucket upper bounds
+	Samples []Sample  `json:"samples"`
+}
+
+type family struct {
+	name, help string
+	typ        Type
+	bounds     []float64
+
+	mu     sync.Mutex
+	series map[string]*Sample
+}
+
+// sample returns the series for labels, creating it on first use; f.mu must
+// be held
+func (f *family) sample(labels Labels) *Sample {
+	k := labels.key()
+	s, ok := f.series[k]
+	if !ok {
+		copied := make(Labels, len(labels))
+		for lk, lv := range labels {
+			copied[lk] = lv
+		}
+		s = &Sample{Labels: copied}
+		if f.typ == TypeHistogram {
+			s.Buckets = make([]uint64, len(f.bounds))
+		}
+		f.series[k] = s
+	}
+	return s
+}
+
+func (f *family) add(v float64, labels Labels, set bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	s := f.sample(labels)
+	if set {
+		s.Value = v
+	} else {
+		s.Value += v
+	}
+}
+
+func (f *family) value(labels Labels) float64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if s, ok := f.series[labels.key()]; ok {
+		return s.Value
+	}
+	return 0
+}
+
+// Counter is a monotonically increasing metric
+type Counter struct{ f *family }
+
+// Add increases the series for labels by v. Negative values are ignored.
+func (c *Counter) Add(v float64, labels Labels) {
+	if v < 0 {
+		return
+	}
+	c.f.add(v, labels, false)
+}
+
+// Inc adds one to the series for labels
+func (c *Counter) Inc(labels Labels) {
+	c.f.add(1, labels, false)
+}
+
+// Value returns the current value of the series for labels
+func (c *Counter) Value(labels Labels) float64 {
+	return c.f.value(labels)
+}
+
+// Gauge is a metric that can go up and down
+type Gauge struct{ f *family }
+
+// Set replaces the series for labels with v
+func (g *Gauge) Set(v float64, labels Labels) {
+	g.f.add(v, labels, true)
+}
+
+// Add moves the series for labels by v
+func (g *Gauge) Add(v float64, labels Labels) {
+	g.f.add(v, labels, false)
+}
+
+// Value returns the current value of the series for labels
+func (g *Gauge) Value(labels Labels) float64 {
+	return g.f.value(labels)
+}
+
+// Histogram counts observations, such as latencies or sizes, in buckets
+type Histogram struct{ f *family }
+
+// Observe records v in the series for labels
+func (h *Histogram) Observe(v float64, labels Labels) {
+	h.f.mu.Lock()
+	defer h.f.mu.Unlock()
+	s := h.f.sample(labels)
+	s.Value += v
+	s.Count++
+	for i, bound := range h.f.bounds {
+		if v <= bound {
+			s.Buckets[i]++
+		}
+	}
+}
+
+// Count returns the number of observations in the series for labels
+func (h *Histogram) Count(labels Labels) uint64 {
+	h.f.mu.Lock()
+	defer h.f.mu.Unlock()
+	if s, ok := h.f.series[labels.key()]; ok {
+		return s.Count
+	}
+	return 0
+}
+
+// DurationBuckets are bucket bounds in seconds for request and call latencies
+var DurationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// ExponentialBuckets returns n bucket bounds starting at start, each factor
+// times the previous
+func ExponentialBuckets(start, factor float64, n int) []float64 {
+	out := make([]float64, n)
+	for i := range out {
+		out[i] = start
+		start *= factor
+	}
+	return out
+}
+
+// Registry holds metric families by name
+type Registry struct {
+	mu       sync.Mutex
+	families map[string]*family
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{families: make(map[string]*family)}
+}
+
+// Default is the registry the server reports from
+var Default = NewRegistry()
+
+// Counter returns the counter called name, creating it on first use
+func (r *Registry) Counter(name, help string) *Counter {
+	return &Counter{r.family(name, help, TypeCounter, nil)}
+}
+
+// Gauge returns the gauge called name, creating it on first use
+func (r *Registry) Gauge(name, help string) *Gauge {
+	return &Gauge{r.family(name, help, TypeGauge, nil)}
+}
+
+// Histogram returns the histogram called name, creating it on first use with
+// the given ascending bucket bounds
+func (r *Registry) Histogram(name, help string, bounds []float64) *Histogram {
+	if !sort.Float64sAreSorted(bounds) {
+		panic("metrics: " + name + " bucket bounds are not sorted")
+	}
+	return &Histogram{r.family(name, help, TypeHistogram, bounds)}
+}
+
+func (r *Registry) family(name, help string, typ Type, bounds []float64) *family {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	f, ok := r.families[name]
+	if !ok {
+		f = &family{name: name, help: help, typ: typ, bounds: append([]float64(nil), bounds...), series: make(map[string]*Sample)}
+		r.families[name] = f
+	}
+	if f.typ != typ {
+		panic("metrics: " + name + " registered as both " + string(f.typ) + " and " + string(typ))
+	}
+	return f
+}
+
+// Snapshot copies every family, sorted by name with series sorted by labels
+func (r *Registry) Snapshot() []Family {
+	r.mu.Lock()
+	fams := make([]*family, 0, len(r.families))
+	for _, f := range r.families {
+		fams = append(fams, f)
+	}
+	r.mu.Unlock()
+	sort.Slice(fams, func(i, j int) bool { return fams[i].name < fams[j].name })
+
+	out := make([]Family, 0, len(fams))
+	for _, f := range fams {
+		f.mu.Lock()
+		keys := make([]string, 0, len(f.series))
+		for k := range f.series {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		samples := make([]Sample, 0, len(keys))
+		for _, k := range keys {
+			s := *f.series[k]
+			s.Buckets = append([]uint64(nil), s.Buckets...)
+			samples = append(samples, s)
+		}
+		f.mu.Unlock()
+		out = append(out, Family{Name: f.name, Help: f.help, Type: f.typ, Bounds: f.bounds, Samples: samples})
+	}
+	return out
+}
@@ -0,0 +1,102 @@
+package metrics
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCounter(t *testing.T) {
+	r := NewRegistry()
+	c := r.Counter("requests_total", "Requests served")
+
+	c.Inc(Labels{"model": "a"})
+	c.Add(2.5, Labels{"model": "a"})
+	c.Add(-1, Labels{"model": "a"})
+	c.Inc(Labels{"model": "b"})
+
+	assert.Equal(t, 3.5, c.Value(Labels{"model": "a"}))
+	assert.Equal(t, 1.0, c.Value(Labels{"model": "b"}))
+	assert.Zero(t, c.Value(Labels{"model": "c"}))
+
+	// The same name returns the same family
+	assert.Equal(t, 3.5, r.Counter("requests_total", "").Value(Labels{"model": "a"}))
+}
+
+func TestGauge(t *testing.T) {
+	r := NewRegistry()
+	g := r.Gauge("in_flight", "Requests in flight")
+	g.Add(2, nil)
+	g.Add(-1, nil)
+	assert.Equal(t, 1.0, g.Value(nil))
+	g.Set(7, nil)
+	assert.Equal(t, 7.0, g.Value(nil))
+}
+
+func TestRegistry_TypeClash(t *testing.T) {
+	r := NewRegistry()
+	r.Counter("x", "")
+	assert.Panics(t, func() { r.Gauge("x", "") })
+}
+
+func TestRegistry_Snapshot(t *testing.T) {
+	r := NewRegistry()
+	r.Gauge("b_gauge", "B").Set(1, nil)
+	c := r.Counter("a_total", "A")
+	c.Inc(Labels{"model": "y", "kind": "prompt"})
+	c.Inc(Labels{"kind": "prompt", "model": "x"})
+
+	snap := r.Snapshot()
+	require.Len(t, snap, 2)
+	assert.Equal(t, "a_total", snap[0].Name)
+	assert.Equal(t, TypeCounter, snap[0].Type)
+	require.Len(t, snap[0].Samples, 2)
+	assert.Equal(t, Labels{"kind": "prompt", "model": "x"}, snap[0].Samples[0].Labels)
+	assert.Equal(t, "b_gauge", snap[1].Name)
+}
+
+func TestCounter_Concurrent(t *testing.T) {
+	c := NewRegistry().Counter("n", "")
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.Inc(nil)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 50.0, c.Value(nil))
+}
+
+func TestHistogram(t *testing.T) {
+	r := NewRegistry()
+	h := r.Histogram("latency_seconds", "Latency", []float64{0.1, 1})
+	h.Observe(0.05, Labels{"route": "/a"})
+	h.Observe(0.5, Labels{"route": "/a"})
+	h.Observe(3, Labels{"route": "/a"})
+
+	assert.Equal(t, uint64(3), h.Count(Labels{"route": "/a"}))
+	assert.Zero(t, h.Count(Labels{"route": "/b"}))
+
+	snap := r.Snapshot()
+	require.Len(t, snap, 1)
+	assert.Equal(t, TypeHistogram, snap[0].Type)
+	assert.Equal(t, []float64{0.1, 1}, snap[0].Bounds)
+	s := snap[0].Samples[0]
+	assert.Equal(t, []uint64{1, 2}, s.Buckets, "buckets are cumulative")
+	assert.Equal(t, uint64(3), s.Count)
+	assert.InDelta(t, 3.55, s.Value, 1e-9)
+
+	// Snapshots don't share bucket storage with the live series
+	h.Observe(0.01, Labels{"route": "/a"})
+	assert.Equal(t, []uint64{1, 2}, s.Buckets)
+
+	assert.Panics(t, func() { r.Histogram("unsorted", "", []float64{1, 0.1}) })
+}
+
+func TestExponentialBuckets(t *testing.T) {
+	assert.Equal(t, []float64{256, 1024, 4096}, ExponentialBuckets(256, 4, 3))
+}
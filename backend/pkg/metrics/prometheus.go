@@ -0,0 +1,88 @@
+package metrics
+
+import (
+	"bufio"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// PrometheusContentType is the media type of the text exposition format
+const PrometheusContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+// WritePrometheus writes the registry in the Prometheus text exposition format
+func (r *Registry) WritePrometheus(w io.Writer) error {
+	return WritePrometheus(w, r.Snapshot())
+}
+
+// WritePrometheus writes families in the Prometheus text exposition format.
+// Histogram buckets are cumulative and end with le="+Inf", followed by the
+// _sum and _count series.
+func WritePrometheus(w io.Writer, families []Family) error {
+	bw := bufio.NewWriter(w)
+	for _, f := range families {
+		bw.WriteString("# HELP " + f.Name + " " + helpEscaper.Replace(f.Help) + "\n")
+		bw.WriteString("# TYPE " + f.Name + " " + string(f.Type) + "\n")
+		for _, s := range f.Samples {
+			if f.Type != TypeHistogram {
+				writeSeries(bw, f.Name, s.Labels, "", "", s.Value)
+				continue
+			}
+			for i, bound := range f.Bounds {
+				writeSeries(bw, f.Name+"_bucket", s.Labels, "le", formatFloat(bound), float64(s.Buckets[i]))
+			}
+			writeSeries(bw, f.Name+"_bucket", s.Labels, "le", "+Inf", float64(s.Count))
+			writeSeries(bw, f.Name+"_sum", s.Labels, "", "", s.Value)
+			writeSeries(bw, f.Name+"_count", s.Labels, "", "", float64(s.Count))
+		}
+	}
+	return bw.Flush()
+}
+
+// writeSeries writes one line, adding the extra label when its name is set
+func writeSeries(w *bufio.Writer, name string, labels Labels, extraName, extraValue string, v float64) {
+	w.WriteString(name)
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	if extraName != "" {
+		keys = append(keys, extraName)
+	}
+	for i, k := range keys {
+		if i == 0 {
+			w.WriteByte('{')
+		} else {
+			w.WriteByte(',')
+		}
+		value := labels[k]
+		if k == extraName {
+			value = extraValue
+		}
+		w.WriteString(k + `="` + labelEscaper.Replace(value) + `"`)
+	}
+	if len(keys) > 0 {
+		w.WriteByte('}')
+	}
+	w.WriteString(" " + formatFloat(v) + "\n")
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,39 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWritePrometheus(t *testing.T) {
+	r := NewRegistry()
+	r.Counter("requests_total", "Requests served,\nby route").Inc(Labels{"route": "/api", "status": "200"})
+	r.Gauge("in_flight", "Requests in flight").Set(2, nil)
+	r.Counter("errors_total", "Errors").Inc(Labels{"msg": `say "hi"\now`})
+	h := r.Histogram("latency_seconds", "Latency", []float64{0.1, 1})
+	h.Observe(0.05, Labels{"route": "/api"})
+	h.Observe(2, Labels{"route": "/api"})
+
+	var out strings.Builder
+	require.NoError(t, r.WritePrometheus(&out))
+	assert.Equal(t, `# HELP errors_total Errors
+# TYPE errors_total counter
+errors_total{msg="say \"hi\"\\now"} 1
+# HELP in_flight Requests in flight
+# TYPE in_flight gauge
+in_flight 2
+# HELP latency_seconds Latency
+# TYPE latency_seconds histogram
+latency_seconds_bucket{route="/api",le="0.1"} 1
+latency_seconds_bucket{route="/api",le="1"} 1
+latency_seconds_bucket{route="/api",le="+Inf"} 2
+latency_seconds_sum{route="/api"} 2.05
+latency_seconds_count{route="/api"} 2
+# HELP requests_total Requests served,\nby route
+# TYPE requests_total counter
+requests_total{route="/api",status="200"} 1
+`, out.String())
+}
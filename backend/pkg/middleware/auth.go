@@ -0,0 +1,23 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// RequireToken admits requests carrying "Authorization: Bearer <token>" and
+// rejects all others with 401. An empty token rejects every request, so
+// endpoints behind it stay closed until one is configured.
+func RequireToken(token string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		auth := c.Get(fiber.HeaderAuthorization)
+		given, ok := strings.CutPrefix(auth, "Bearer ")
+		if ok && token != "" && subtle.ConstantTimeCompare([]byte(given), []byte(token)) == 1 {
+			return c.Next()
+		}
+		c.Set(fiber.HeaderWWWAuthenticate, `Bearer realm="admin"`)
+		return NewAPIError(fiber.StatusUnauthorized, "unauthorized", "a valid admin token is required")
+	}
+}
@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequireToken(t *testing.T) {
+	newApp := func(token string) *fiber.App {
+		app := fiber.New()
+		app.Use(ErrorMiddleware())
+		app.Get("/admin", RequireToken(token), func(c *fiber.Ctx) error {
+			return c.SendString("ok")
+		})
+		return app
+	}
+
+	tests := []struct {
+		name   string
+		token  string
+		auth   string
+		status int
+	}{
+		{"valid token", "s3cret", "Bearer s3cret", fiber.StatusOK},
+		{"missing header", "s3cret", "", fiber.StatusUnauthorized},
+		{"wrong token", "s3cret", "Bearer guess", fiber.StatusUnauthorized},
+		{"other scheme", "s3cret", "Basic s3cret", fiber.StatusUnauthorized},
+		{"no token configured", "", "Bearer ", fiber.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/admin", nil)
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+			resp, err := newApp(tt.token).Test(req)
+			require.NoError(t, err)
+			assert.Equal(t, tt.status, resp.StatusCode)
+			if tt.status == fiber.StatusUnauthorized {
+				assert.Equal(t, `Bearer realm="admin"`, resp.Header.Get("WWW-Authenticate"))
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package middleware
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// originPattern is one allowed origin. A "*" in the host's first label
+// matches within that label; a first label of just "*" matches any number of
+// subdomains. A port of "*" matches any port.
+type originPattern struct {
+	scheme         string
+	prefix, suffix string // host around the wildcard; suffix is the whole host without one
+	wildcard       bool
+	port           string
+}
+
+// NewOriginMatcher parses allowed CORS origins such as https://example.com,
+// https://*.example.com, https://web-tldr-*.vercel.app, http://localhost:*
+// and chrome-extension://<id>, or "*" for any origin. The returned function
+// reports whether a request's Origin header is allowed.
+func NewOriginMatcher(origins []string) (func(origin string) bool, error) {
+	var patterns []originPattern
+	for _, origin := range origins {
+		if origin == "*" {
+			return func(string) bool { return true }, nil
+		}
+		p, err := parseOriginPattern(origin)
+		if err != nil {
+			return nil, fmt.Errorf("CORS origin %q: %w", origin, err)
+		}
+		patterns = append(patterns, p)
+	}
+
+	return func(origin string) bool {
+		u, err := url.Parse(strings.ToLower(origin))
+		if err != nil || u.Host == "" || (u.Path != "" && u.Path != "/") {
+			return false
+		}
+		for _, p := range patterns {
+			if p.match(u) {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
+
+func parseOriginPattern(origin string) (originPattern, error) {
+	scheme, hostport, ok := strings.Cut(strings.ToLower(strings.TrimSuffix(origin, "/")), "://")
+	if !ok || scheme == "" || hostport == "" {
+		return originPattern{}, fmt.Errorf("want scheme://host[:port]")
+	}
+	if strings.ContainsAny(hostport, "/?#@") {
+		return originPattern{}, fmt.Errorf("an origin has no path, query or user info")
+	}
+
+	host, port := hostport, ""
+	if i := strings.LastIndex(hostport, ":"); i != -1 && !strings.HasSuffix(hostport, "]") {
+		host, port = hostport[:i], hostport[i+1:]
+		if port == "" {
+			return originPattern{}, fmt.Errorf("empty port")
+		}
+	}
+	host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+	p := originPattern{scheme: scheme, suffix: host, port: port}
+
+	switch strings.Count(host, "*") {
+	case 0:
+	case 1:
+		label, rest, _ := strings.Cut(host, ".")
+		if !strings.Contains(label, "*") || !strings.Contains(rest, ".") {
+			return originPattern{}, fmt.Errorf("the wildcard must be in the first label of a host with at least two more")
+		}
+		p.wildcard = true
+		p.prefix, p.suffix, _ = strings.Cut(host, "*")
+	default:
+		return originPattern{}, fmt.Errorf("at most one wildcard is allowed")
+	}
+	return p, nil
+}
+
+func (p originPattern) match(u *url.URL) bool {
+	if u.Scheme != p.scheme || (p.port != "*" && u.Port() != p.port) {
+		return false
+	}
+	host := u.Hostname()
+	if !p.wildcard {
+		return host == p.suffix
+	}
+	if len(host) <= len(p.prefix)+len(p.suffix) || !strings.HasPrefix(host, p.prefix) || !strings.HasSuffix(host, p.suffix) {
+		return false
+	}
+	middle := host[len(p.prefix) : len(host)-len(p.suffix)]
+
+	// A bare "*" label stands for whole subdomains, anything else for part of one
+	multiLabel := p.prefix == "" && strings.HasPrefix(p.suffix, ".")
+	for _, label := range strings.Split(middle, ".") {
+		if label == "" || strings.Trim(label, "abcdefghijklmnopqrstuvwxyz0123456789-") != "" {
+			return false
+		}
+	}
+	return multiLabel || !strings.Contains(middle, ".")
+}
@@ -0,0 +1,91 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/cors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewOriginMatcher(t *testing.T) {
+	allow, err := NewOriginMatcher([]string{
+		"https://web-tldr.vercel.app",
+		"https://web-tldr-*.vercel.app",
+		"https://*.tldr.dev",
+		"http://localhost:*",
+		"chrome-extension://abcdefghijklmnop",
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		origin string
+		want   bool
+	}{
+		{"https://web-tldr.vercel.app", true},
+		{"https://WEB-TLDR.vercel.app", true},
+		{"http://web-tldr.vercel.app", false},
+		{"https://web-tldr-git-main-team.vercel.app", true},
+		{"https://web-tldr-.vercel.app", false},
+		{"https://web-tldr-a.b.vercel.app", false},
+		{"https://evil.vercel.app", false},
+		{"https://app.tldr.dev", true},
+		{"https://preview.app.tldr.dev", true},
+		{"https://tldr.dev", false},
+		{"https://eviltldr.dev", false},
+		{"https://app.tldr.dev.evil.com", false},
+		{"http://localhost:3000", true},
+		{"http://localhost", true},
+		{"http://localhost.evil.com:3000", false},
+		{"chrome-extension://abcdefghijklmnop", true},
+		{"chrome-extension://other", false},
+		{"null", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, allow(tt.origin), tt.origin)
+	}
+
+	t.Run("star allows any origin", func(t *testing.T) {
+		allow, err := NewOriginMatcher([]string{"*"})
+		require.NoError(t, err)
+		assert.True(t, allow("https://anything.example"))
+	})
+
+	t.Run("rejects bad patterns", func(t *testing.T) {
+		for _, origin := range []string{
+			"web-tldr.vercel.app",
+			"https://*",
+			"https://*.com",
+			"https://app.*.tldr.dev",
+			"https://*.*.tldr.dev",
+			"https://tldr.dev/path",
+			"http://localhost:",
+		} {
+			_, err := NewOriginMatcher([]string{origin})
+			assert.Error(t, err, origin)
+		}
+	})
+}
+
+func TestNewOriginMatcher_CORSMiddleware(t *testing.T) {
+	allow, err := NewOriginMatcher([]string{"https://*.tldr.dev"})
+	require.NoError(t, err)
+
+	app := fiber.New()
+	app.Use(cors.New(cors.Config{AllowOriginsFunc: allow}))
+	app.Get("/test", func(c *fiber.Ctx) error { return c.SendString("ok") })
+
+	for origin, want := range map[string]string{
+		"https://app.tldr.dev": "https://app.tldr.dev",
+		"https://evil.example": "",
+	} {
+		req := httptest.NewRequest("GET", "/test", nil)
+		req.Header.Set("Origin", origin)
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		assert.Equal(t, want, resp.Header.Get("Access-Control-Allow-Origin"), origin)
+	}
+}
@@ -1,9 +1,28 @@
 package middleware
 
 import (
+	"errors"
+
 	"github.com/gofiber/fiber/v2"
 )
 
+// APIError is an error carrying an HTTP status and a machine-readable code
+// that clients can branch on without parsing the message
+type APIError struct {
+	Status  int
+	Code    string
+	Message string
+}
+
+// NewAPIError creates an APIError with the given status, code and message
+func NewAPIError(status int, code, message string) *APIError {
+	return &APIError{Status: status, Code: code, Message: message}
+}
+
+func (e *APIError) Error() string {
+	return e.Message
+}
+
 // ErrorMiddleware converts errors to JSON responses with appropriate status codes
 func ErrorMiddleware() fiber.Handler {
 	return func(c *fiber.Ctx) error {
@@ -13,6 +32,15 @@ func ErrorMiddleware() fiber.Handler {
 			return nil
 		}
 
+		// Errors with a code report it alongside the message
+		var apiErr *APIError
+		if errors.As(err, &apiErr) {
+			return c.Status(apiErr.Status).JSON(fiber.Map{
+				"error": apiErr.Message,
+				"code":  apiErr.Code,
+			})
+		}
+
 		// Get fiber's error status code if it exists
 		code := fiber.StatusInternalServerError
 		if e, ok := err.(*fiber.Error); ok {
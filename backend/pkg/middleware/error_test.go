@@ -38,3 +38,21 @@ func TestErrorMiddleware(t *testing.T) {
 	assert.Contains(t, result, "error")
 	assert.Equal(t, fiber.ErrUnprocessableEntity.Error(), result["error"])
 }
+
+func TestErrorMiddleware_APIError(t *testing.T) {
+	app := fiber.New()
+	app.Use(ErrorMiddleware())
+
+	app.Get("/test", func(c *fiber.Ctx) error {
+		return NewAPIError(fiber.StatusUnprocessableEntity, "paywall", "article is behind a paywall")
+	})
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	resp, err := app.Test(req)
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusUnprocessableEntity, resp.StatusCode)
+
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"error":"article is behind a paywall","code":"paywall"}`, string(body))
+}
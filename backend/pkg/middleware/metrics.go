@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/matthewmolinar/tldr/pkg/metrics"
+)
+
+// unmatchedRoute labels requests no route handled, so scans of random paths
+// don't create a series each
+const unmatchedRoute = "unmatched"
+
+// Metrics counts requests and times them per route template, method and
+// status, and tracks those in flight. Register it before ErrorMiddleware so
+// it sees the final status.
+func Metrics(reg *metrics.Registry) fiber.Handler {
+	requests := reg.Counter("http_requests_total", "HTTP requests by route, method and status")
+	duration := reg.Histogram("http_request_duration_seconds", "HTTP request latency by route, method and status", metrics.DurationBuckets)
+	inFlight := reg.Gauge("http_requests_in_flight", "HTTP requests being served")
+
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+		own := c.Route()
+		inFlight.Add(1, nil)
+		defer inFlight.Add(-1, nil)
+
+		err := c.Next()
+
+		status, route := responseStatus(c, err), routeOf(c, own)
+		labels := metrics.Labels{"route": route, "method": c.Method(), "status": strconv.Itoa(status)}
+		requests.Inc(labels)
+		duration.Observe(time.Since(start).Seconds(), labels)
+		return err
+	}
+}
+
+// responseStatus is the status the request will be answered with once
+// ErrorMiddleware has turned err into a response
+func responseStatus(c *fiber.Ctx, err error) int {
+	if err == nil {
+		return c.Response().StatusCode()
+	}
+	var fe *fiber.Error
+	var apiErr *APIError
+	if errors.As(err, &fe) {
+		return fe.Code
+	} else if errors.As(err, &apiErr) {
+		return apiErr.Status
+	}
+	return fiber.StatusInternalServerError
+}
+
+// routeOf is the route template that served the request; own is the route
+// the calling middleware was matched on, which no handler replaced when the
+// request matched nothing
+func routeOf(c *fiber.Ctx, own *fiber.Route) string {
+	if c.Route() == own {
+		return unmatchedRoute
+	}
+	return c.Route().Path
+}
@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/matthewmolinar/tldr/pkg/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetrics(t *testing.T) {
+	reg := metrics.NewRegistry()
+	app := fiber.New()
+	app.Use(Metrics(reg))
+	app.Use(ErrorMiddleware())
+	app.Get("/items/:id", func(c *fiber.Ctx) error {
+		assert.Equal(t, 1.0, reg.Gauge("http_requests_in_flight", "").Value(nil))
+		return c.SendString("ok")
+	})
+	app.Post("/fail", func(c *fiber.Ctx) error {
+		return NewAPIError(fiber.StatusUnprocessableEntity, "paywall", "article is behind a paywall")
+	})
+
+	for _, r := range []struct{ method, path string }{
+		{"GET", "/items/1"}, {"GET", "/items/2"}, {"POST", "/fail"}, {"GET", "/wp-admin"},
+	} {
+		_, err := app.Test(httptest.NewRequest(r.method, r.path, nil))
+		require.NoError(t, err)
+	}
+
+	requests := reg.Counter("http_requests_total", "")
+	assert.Equal(t, 2.0, requests.Value(metrics.Labels{"route": "/items/:id", "method": "GET", "status": "200"}))
+	assert.Equal(t, 1.0, requests.Value(metrics.Labels{"route": "/fail", "method": "POST", "status": "422"}))
+	assert.Equal(t, 1.0, requests.Value(metrics.Labels{"route": "unmatched", "method": "GET", "status": "404"}))
+
+	duration := reg.Histogram("http_request_duration_seconds", "", metrics.DurationBuckets)
+	assert.Equal(t, uint64(2), duration.Count(metrics.Labels{"route": "/items/:id", "method": "GET", "status": "200"}))
+	assert.Zero(t, reg.Gauge("http_requests_in_flight", "").Value(nil))
+}
@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/matthewmolinar/tldr/pkg/config"
+)
+
+// SecurityHeaders sets the browser security headers on every response. HTML
+// responses, such as share pages, get the page Content-Security-Policy; all
+// others the API one, which forbids loading or framing anything.
+func SecurityHeaders(cfg config.Security) fiber.Handler {
+	hsts := ""
+	if secs := int(cfg.HSTSMaxAge.Seconds()); secs > 0 {
+		hsts = "max-age=" + strconv.Itoa(secs) + "; includeSubDomains"
+	}
+
+	return func(c *fiber.Ctx) error {
+		err := c.Next()
+
+		// Set after the handler, and the error middleware, chose the content type
+		h := &c.Response().Header
+		csp := cfg.ContentSecurityPolicy
+		if strings.HasPrefix(string(h.ContentType()), fiber.MIMETextHTML) {
+			csp = cfg.PageContentSecurityPolicy
+		}
+		if csp != "" {
+			h.Set(fiber.HeaderContentSecurityPolicy, csp)
+		}
+		if hsts != "" {
+			h.Set(fiber.HeaderStrictTransportSecurity, hsts)
+		}
+		if cfg.ReferrerPolicy != "" {
+			h.Set(fiber.HeaderReferrerPolicy, cfg.ReferrerPolicy)
+		}
+		h.Set(fiber.HeaderXContentTypeOptions, "nosniff")
+		h.Set(fiber.HeaderXFrameOptions, "DENY")
+		return err
+	}
+}
@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/matthewmolinar/tldr/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSecurityHeaders(t *testing.T) {
+	cfg := config.Default().Security
+	app := fiber.New()
+	app.Use(SecurityHeaders(cfg))
+	app.Use(ErrorMiddleware())
+	app.Get("/api", func(c *fiber.Ctx) error { return c.JSON(fiber.Map{"ok": true}) })
+	app.Get("/page", func(c *fiber.Ctx) error {
+		c.Type("html")
+		return c.SendString("<h1>Shared summary</h1>")
+	})
+	app.Get("/fail", func(c *fiber.Ctx) error {
+		return NewAPIError(fiber.StatusBadRequest, "bad", "bad request")
+	})
+
+	for path, csp := range map[string]string{
+		"/api":  cfg.ContentSecurityPolicy,
+		"/page": cfg.PageContentSecurityPolicy,
+		"/fail": cfg.ContentSecurityPolicy,
+	} {
+		resp, err := app.Test(httptest.NewRequest("GET", path, nil))
+		require.NoError(t, err)
+		assert.Equal(t, csp, resp.Header.Get("Content-Security-Policy"), path)
+		assert.Equal(t, "max-age=15552000; includeSubDomains", resp.Header.Get("Strict-Transport-Security"), path)
+		assert.Equal(t, "nosniff", resp.Header.Get("X-Content-Type-Options"), path)
+		assert.Equal(t, "strict-origin-when-cross-origin", resp.Header.Get("Referrer-Policy"), path)
+		assert.Equal(t, "DENY", resp.Header.Get("X-Frame-Options"), path)
+	}
+
+	t.Run("empty settings omit their headers", func(t *testing.T) {
+		app := fiber.New()
+		app.Use(SecurityHeaders(config.Security{}))
+		app.Get("/api", func(c *fiber.Ctx) error { return c.SendString("ok") })
+
+		resp, err := app.Test(httptest.NewRequest("GET", "/api", nil))
+		require.NoError(t, err)
+		assert.Empty(t, resp.Header.Get("Content-Security-Policy"))
+		assert.Empty(t, resp.Header.Get("Strict-Transport-Security"))
+		assert.Empty(t, resp.Header.Get("Referrer-Policy"))
+		assert.Equal(t, "nosniff", resp.Header.Get("X-Content-Type-Options"))
+	})
+}
@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+	"github.com/matthewmolinar/tldr/pkg/trace"
+)
+
+// Tracing starts a server span for every request, continuing the caller's
+// trace when it sends a W3C traceparent header, and puts the span in the
+// request's user context for the handlers. The trace ID is returned in
+// X-Trace-ID. Register it before ErrorMiddleware so it sees the final status.
+func Tracing(t *trace.Tracer) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		own := c.Route()
+		ctx := c.UserContext()
+		if parent, err := trace.ParseTraceparent(c.Get("traceparent")); err == nil {
+			ctx = trace.ContextWithRemoteParent(ctx, parent)
+		}
+		ctx, span := t.Start(ctx, c.Method(), trace.KindServer)
+		defer span.End()
+		c.SetUserContext(ctx)
+		c.Set("X-Trace-ID", span.Context().TraceID.String())
+
+		err := c.Next()
+
+		status, route := responseStatus(c, err), routeOf(c, own)
+		span.SetName(c.Method() + " " + route)
+		span.SetAttr("http.request.method", c.Method())
+		span.SetAttr("http.route", route)
+		span.SetAttr("http.response.status_code", status)
+		if err == nil && status >= fiber.StatusInternalServerError {
+			span.RecordError(errors.New(utils.StatusMessage(status)))
+		} else if status >= fiber.StatusInternalServerError {
+			span.RecordError(err)
+		}
+		return err
+	}
+}
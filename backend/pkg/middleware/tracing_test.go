@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/matthewmolinar/tldr/pkg/trace"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type spanRecorder struct {
+	mu    sync.Mutex
+	spans []trace.SpanData
+}
+
+func (r *spanRecorder) Export(s trace.SpanData) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.spans = append(r.spans, s)
+}
+
+func TestTracing(t *testing.T) {
+	rec := &spanRecorder{}
+	tracer := trace.NewTracer(rec, 1)
+	app := fiber.New()
+	app.Use(Tracing(tracer))
+	app.Use(ErrorMiddleware())
+	app.Get("/items/:id", func(c *fiber.Ctx) error {
+		_, span := tracer.Start(c.UserContext(), "lookup", trace.KindInternal)
+		span.End()
+		return c.SendString("ok")
+	})
+	app.Post("/fail", func(c *fiber.Ctx) error {
+		return fiber.NewError(fiber.StatusBadGateway, "upstream failed")
+	})
+
+	// A caller's traceparent continues its trace
+	req := httptest.NewRequest("GET", "/items/1", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", resp.Header.Get("X-Trace-ID"))
+
+	require.Len(t, rec.spans, 2)
+	inner, server := rec.spans[0], rec.spans[1]
+	assert.Equal(t, "GET /items/:id", server.Name)
+	assert.Equal(t, trace.KindServer, server.Kind)
+	assert.Equal(t, "00f067aa0ba902b7", server.Parent.String())
+	assert.Equal(t, server.Context.SpanID, inner.Parent, "handlers trace under the server span")
+	assert.Contains(t, server.Attrs, trace.Attr{Key: "http.route", Value: "/items/:id"})
+	assert.Contains(t, server.Attrs, trace.Attr{Key: "http.response.status_code", Value: 200})
+
+	// Errors mark the span failed; a malformed traceparent starts a new trace
+	req = httptest.NewRequest("POST", "/fail", nil)
+	req.Header.Set("traceparent", "garbage")
+	resp, err = app.Test(req)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusBadGateway, resp.StatusCode)
+	assert.Len(t, resp.Header.Get("X-Trace-ID"), 32)
+
+	require.Len(t, rec.spans, 3)
+	failed := rec.spans[2]
+	assert.False(t, failed.Parent.IsValid())
+	assert.True(t, failed.Failed)
+	assert.Equal(t, "Bad Gateway", failed.Error)
+
+	_, err = app.Test(httptest.NewRequest("GET", "/wp-admin", nil))
+	require.NoError(t, err)
+	require.Len(t, rec.spans, 4)
+	assert.Equal(t, "GET unmatched", rec.spans[3].Name)
+}
@@ -0,0 +1,133 @@
+// Package nlp provides the small text-processing primitives shared by
+// summary evaluation, verification and extractive summarization
+package nlp
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Tokens splits text into lowercase word tokens. Letters and digits form
+// words; Han, kana and Thai characters, which aren't space-delimited, are
+// emitted one rune per token so overlap metrics still work for them.
+func Tokens(text string) []string {
+	var tokens []string
+	var word strings.Builder
+	flush := func() {
+		if word.Len() > 0 {
+			tokens = append(tokens, word.String())
+			word.Reset()
+		}
+	}
+
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Thai):
+			flush()
+			tokens = append(tokens, string(r))
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r):
+			word.WriteRune(r)
+		case (r == '.' || r == ',') && word.Len() > 0 && isDigits(word.String()):
+			// keep decimals and thousands separators inside numbers: 3.5, 40,000
+			word.WriteRune(r)
+		default:
+			flush()
+		}
+	}
+	flush()
+
+	for i, t := range tokens {
+		tokens[i] = strings.TrimRight(t, ".,")
+	}
+	return tokens
+}
+
+// ContentTokens returns Tokens with English stopwords removed
+func ContentTokens(text string) []string {
+	var out []string
+	for _, t := range Tokens(text) {
+		if !stopwords[t] {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// Sentences splits text into trimmed sentences on terminal punctuation
+// followed by whitespace, on CJK full stops, and on line breaks
+func Sentences(text string) []string {
+	var sentences []string
+	var cur strings.Builder
+	flush := func() {
+		if s := strings.TrimSpace(cur.String()); s != "" {
+			sentences = append(sentences, strings.Join(strings.Fields(s), " "))
+		}
+		cur.Reset()
+	}
+
+	runes := []rune(text)
+	for i, r := range runes {
+		if r == '\n' {
+			flush()
+			continue
+		}
+		cur.WriteRune(r)
+		switch r {
+		case '。', '！', '？':
+			flush()
+		case '.', '!', '?':
+			if i+1 == len(runes) || unicode.IsSpace(runes[i+1]) {
+				flush()
+			}
+		}
+	}
+	flush()
+	return sentences
+}
+
+// Numbers returns the numeric tokens in text, normalized without
+// thousands separators so "40,000" and "40000" compare equal
+func Numbers(text string) []string {
+	var out []string
+	for _, t := range Tokens(text) {
+		if t != "" && unicode.IsDigit([]rune(t)[0]) {
+			out = append(out, strings.ReplaceAll(t, ",", ""))
+		}
+	}
+	return out
+}
+
+// HasStopwords reports whether ContentTokens knows the stopwords of the
+// ISO 639-1 language, so overlap scores mean something for its text
+func HasStopwords(language string) bool {
+	return language == "en"
+}
+
+// IsStopword reports whether a lowercase token is an English stopword
+func IsStopword(token string) bool {
+	return stopwords[token]
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if !unicode.IsDigit(r) && r != '.' && r != ',' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+var stopwords = map[string]bool{
+	"a": true, "about": true, "after": true, "all": true, "also": true, "an": true, "and": true,
+	"any": true, "are": true, "as": true, "at": true, "be": true, "been": true, "before": true,
+	"but": true, "by": true, "can": true, "could": true, "did": true, "do": true, "does": true,
+	"for": true, "from": true, "had": true, "has": true, "have": true, "he": true, "her": true,
+	"his": true, "how": true, "i": true, "if": true, "in": true, "into": true, "is": true,
+	"it": true, "its": true, "more": true, "most": true, "new": true, "no": true, "not": true,
+	"of": true, "on": true, "one": true, "or": true, "our": true, "out": true, "over": true,
+	"said": true, "says": true, "she": true, "so": true, "some": true, "than": true, "that": true,
+	"the": true, "their": true, "them": true, "then": true, "there": true, "these": true,
+	"they": true, "this": true, "to": true, "up": true, "was": true, "we": true, "were": true,
+	"what": true, "when": true, "which": true, "who": true, "will": true, "with": true,
+	"would": true, "you": true,
+}
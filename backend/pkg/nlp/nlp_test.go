@@ -0,0 +1,45 @@
+package nlp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokens(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{name: "words are lowercased", text: "The Quick, brown fox!", want: []string{"the", "quick", "brown", "fox"}},
+		{name: "numbers keep separators", text: "Costs rose 3.5% to €40,000.", want: []string{"costs", "rose", "3.5", "to", "40,000"}},
+		{name: "accented letters stay in words", text: "Café crème", want: []string{"café", "crème"}},
+		{name: "han characters are split", text: "新しい図書館", want: []string{"新", "し", "い", "図", "書", "館"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Tokens(tt.text))
+		})
+	}
+}
+
+func TestContentTokens(t *testing.T) {
+	assert.Equal(t, []string{"council", "approved", "budget"}, ContentTokens("The council approved the budget"))
+}
+
+func TestSentences(t *testing.T) {
+	text := "The port reopened on Monday. Traffic rose 3.5 percent!\nOfficials were pleased? Yes.新しい図書館が開館。市民が訪れた"
+	assert.Equal(t, []string{
+		"The port reopened on Monday.",
+		"Traffic rose 3.5 percent!",
+		"Officials were pleased?",
+		"Yes.新しい図書館が開館。",
+		"市民が訪れた",
+	}, Sentences(text))
+}
+
+func TestNumbers(t *testing.T) {
+	assert.Equal(t, []string{"2024", "40000", "3.5"}, Numbers("In 2024 the café spent €40,000, up 3.5%."))
+}
@@ -0,0 +1,154 @@
+// Package redact masks personal data and secrets in article text before it
+// is sent to an LLM provider
+package redact
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Kind names a category of redacted data
+type Kind string
+
+const (
+	Email      Kind = "email"
+	Phone      Kind = "phone"
+	CreditCard Kind = "credit_card"
+	SSN        Kind = "ssn"
+	JWT        Kind = "jwt"
+	APIKey     Kind = "api_key"
+)
+
+// Mask returns the placeholder that replaces data of this kind, e.g. [EMAIL]
+func (k Kind) Mask() string {
+	return "[" + strings.ToUpper(string(k)) + "]"
+}
+
+// rule finds one kind of data. valid, when set, filters out regex matches
+// that aren't really that kind, like card-length numbers failing the Luhn
+// check. It is given the whole text and the match's bounds.
+type rule struct {
+	kind  Kind
+	re    *regexp.Regexp
+	valid func(s string, start, end int) bool
+}
+
+// digitGroupAfter and digitGroupBefore spot a match that continues into
+// neighbouring digit groups
+var (
+	digitGroupAfter  = regexp.MustCompile(`^[ .-]?\d`)
+	digitGroupBefore = regexp.MustCompile(`\d[ .-]?$`)
+)
+
+// rules run in order, so the specific token formats go before the generic
+// digit patterns that would otherwise claim pieces of them
+var rules = []rule{
+	{kind: JWT, re: regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{8,}\.eyJ[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]{8,}`)},
+	{kind: APIKey, re: regexp.MustCompile(`\b(?:sk-(?:proj-|ant-)?[A-Za-z0-9_-]{20,}|AKIA[0-9A-Z]{16}|gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{40,}|xox[abprs]-[A-Za-z0-9-]{10,}|AIza[0-9A-Za-z_-]{35}|(?:sk|pk|rk)_(?:live|test)_[0-9A-Za-z]{16,})`)},
+	{kind: APIKey, re: regexp.MustCompile(`(?i)\b(?:api[_-]?key|secret|token|password|passwd)\b["']?\s*[:=]\s*(?:"[A-Za-z0-9_\-./+=]{12,}"?|'[A-Za-z0-9_\-./+=]{12,}'?|[A-Za-z0-9_\-./+=]{12,})`)},
+	{kind: Email, re: regexp.MustCompile(`\b[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}\b`)},
+	{kind: CreditCard, re: regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`), valid: func(s string, start, end int) bool { return luhn(s[start:end]) }},
+	{kind: SSN, re: regexp.MustCompile(`\b\d{3}-\d{2}-\d{4}\b`)},
+	{kind: Phone, re: regexp.MustCompile(`(?:\+\d{1,3}[ .-]?)?(?:\(\d{2,4}\)[ .-]?|\b\d{2,4}[ .-])\d{3,4}[ .-]?\d{3,4}\b`), valid: phoneNumber},
+}
+
+// Result is redacted text with the number of masks applied per kind
+type Result struct {
+	Text   string
+	Counts map[Kind]int
+}
+
+// Total is the number of masks applied across all kinds
+func (r Result) Total() int {
+	n := 0
+	for _, c := range r.Counts {
+		n += c
+	}
+	return n
+}
+
+// Kinds lists the kinds that were redacted, sorted by name
+func (r Result) Kinds() []string {
+	var out []string
+	for k := range r.Counts {
+		out = append(out, string(k))
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Text masks emails, phone numbers, card numbers, US social security numbers,
+// JWTs and API keys in s
+func Text(s string) Result {
+	res := Result{Counts: make(map[Kind]int)}
+	for _, r := range rules {
+		var b strings.Builder
+		last := 0
+		for _, loc := range r.re.FindAllStringIndex(s, -1) {
+			if r.valid != nil && !r.valid(s, loc[0], loc[1]) {
+				continue
+			}
+			b.WriteString(s[last:loc[0]])
+			b.WriteString(r.kind.Mask())
+			last = loc[1]
+			res.Counts[r.kind]++
+		}
+		b.WriteString(s[last:])
+		s = b.String()
+	}
+	res.Text = s
+	return res
+}
+
+// Masked reports whether s contains a placeholder Text puts in place of
+// redacted data
+func Masked(s string) bool {
+	for _, r := range rules {
+		if strings.Contains(s, r.kind.Mask()) {
+			return true
+		}
+	}
+	return false
+}
+
+// luhn reports whether the digits in s pass the card number checksum
+func luhn(s string) bool {
+	digits := onlyDigits(s)
+	if len(digits) < 13 || len(digits) > 19 {
+		return false
+	}
+	sum := 0
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := int(digits[i] - '0')
+		if (len(digits)-i)%2 == 0 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	return sum%10 == 0
+}
+
+// phoneNumber rejects number runs too short for a phone number, which keeps
+// dates, years and ranges like 2019-2020 out of the phone rule, and matches
+// that are only part of a longer run of digit groups
+func phoneNumber(s string, start, end int) bool {
+	n := len(onlyDigits(s[start:end]))
+	if n < 10 || n > 15 {
+		return false
+	}
+	return !digitGroupAfter.MatchString(s[end:]) && !digitGroupBefore.MatchString(s[:start])
+}
+
+func onlyDigits(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsDigit(r) {
+			return r
+		}
+		return -1
+	}, s)
+}
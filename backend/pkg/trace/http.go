@@ -0,0 +1,94 @@
+package trace
+
+import (
+	"io"
+	"net/http"
+	"sync"
+)
+
+// transport records a client span for every request sent through it
+type transport struct {
+	tracer *Tracer // nil for Default
+	base   http.RoundTripper
+}
+
+// Client returns a copy of hc whose requests are traced by t as children of
+// the span in the request context. Requests made outside a trace, like
+// background health probes, are not traced. The traceparent header is not
+// sent, so third-party origins learn nothing of our traces.
+func (t *Tracer) Client(hc *http.Client) *http.Client {
+	return tracedClient(t, hc)
+}
+
+// Client traces hc's requests on the Default tracer as it is when each
+// request is sent, so it can be called before Default is configured
+func Client(hc *http.Client) *http.Client {
+	return tracedClient(nil, hc)
+}
+
+func tracedClient(t *Tracer, hc *http.Client) *http.Client {
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	base := hc.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	traced := *hc
+	traced.Transport = transport{t, base}
+	return &traced
+}
+
+func (t transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if SpanFromContext(req.Context()) == nil {
+		return t.base.RoundTrip(req)
+	}
+	tracer := t.tracer
+	if tracer == nil {
+		tracer = Default
+	}
+	_, span := tracer.Start(req.Context(), "HTTP "+req.Method, KindClient)
+	span.SetAttr("http.request.method", req.Method)
+	span.SetAttr("server.address", req.URL.Hostname())
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		span.RecordError(err)
+		span.End()
+		return nil, err
+	}
+	span.SetAttr("http.response.status_code", resp.StatusCode)
+	if resp.StatusCode >= 500 {
+		span.RecordError(errStatus(resp.Status))
+	}
+	// The download lasts until the body is closed
+	resp.Body = &spanBody{ReadCloser: resp.Body, span: span}
+	return resp, nil
+}
+
+type errStatus string
+
+func (e errStatus) Error() string { return string(e) }
+
+// spanBody ends its span with the bytes read when closed
+type spanBody struct {
+	io.ReadCloser
+	span *Span
+	n    int64
+	once sync.Once
+}
+
+func (b *spanBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.n += int64(n)
+	return n, err
+}
+
+func (b *spanBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(func() {
+		b.span.SetAttr("http.response.body.size", b.n)
+		b.span.End()
+	})
+	return err
+}
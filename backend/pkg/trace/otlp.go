@@ -0,0 +1,270 @@
+package trace
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	otlpQueueSize     = 2048
+	otlpBatchSize     = 256
+	otlpFlushInterval = 5 * time.Second
+	otlpTimeout       = 10 * time.Second
+
+	// instrumentationScope names this package in exported spans
+	instrumentationScope = "github.com/matthewmolinar/tldr/pkg/trace"
+)
+
+// OTLPExporter batches spans and sends them to an OpenTelemetry collector
+// over OTLP/HTTP with JSON encoding. Spans are dropped, not queued without
+// bound, when the collector can't keep up.
+type OTLPExporter struct {
+	url     string
+	service string
+	client  *http.Client
+
+	queue   chan SpanData
+	flushes chan chan struct{}
+	stop    chan struct{}
+	stopped sync.WaitGroup
+	closing sync.Once
+
+	dropped atomic.Int64
+}
+
+// NewOTLPExporter starts an exporter sending to the collector at endpoint,
+// the base URL such as http://localhost:4318, under the given service name.
+// client must not be traced itself; nil uses http.DefaultClient.
+func NewOTLPExporter(endpoint, service string, client *http.Client) *OTLPExporter {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	e := &OTLPExporter{
+		url:     strings.TrimSuffix(endpoint, "/") + "/v1/traces",
+		service: service,
+		client:  client,
+		queue:   make(chan SpanData, otlpQueueSize),
+		flushes: make(chan chan struct{}),
+		stop:    make(chan struct{}),
+	}
+	e.stopped.Add(1)
+	go e.run()
+	return e
+}
+
+// Export queues a span for the next batch
+func (e *OTLPExporter) Export(s SpanData) {
+	select {
+	case e.queue <- s:
+	default:
+		e.dropped.Add(1)
+	}
+}
+
+// Dropped is the number of spans lost to a full queue
+func (e *OTLPExporter) Dropped() int64 {
+	return e.dropped.Load()
+}
+
+// Flush sends the queued spans now, waiting until they are sent or ctx ends
+func (e *OTLPExporter) Flush(ctx context.Context) error {
+	done := make(chan struct{})
+	select {
+	case e.flushes <- done:
+	case <-e.stop:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Shutdown sends the queued spans and stops the exporter. Spans exported
+// afterwards are dropped.
+func (e *OTLPExporter) Shutdown(ctx context.Context) error {
+	err := e.Flush(ctx)
+	e.closing.Do(func() { close(e.stop) })
+	e.stopped.Wait()
+	return err
+}
+
+func (e *OTLPExporter) run() {
+	defer e.stopped.Done()
+	ticker := time.NewTicker(otlpFlushInterval)
+	defer ticker.Stop()
+
+	var batch []SpanData
+	send := func() {
+		if len(batch) > 0 {
+			if err := e.send(batch); err != nil {
+				log.Printf("Failed to export %d spans: %v", len(batch), err)
+			}
+			batch = nil
+		}
+	}
+	// drain moves everything queued so far into the batch, sending full ones
+	drain := func() {
+		for {
+			select {
+			case s := <-e.queue:
+				if batch = append(batch, s); len(batch) >= otlpBatchSize {
+					send()
+				}
+			default:
+				return
+			}
+		}
+	}
+
+	for {
+		select {
+		case s := <-e.queue:
+			if batch = append(batch, s); len(batch) >= otlpBatchSize {
+				send()
+			}
+		case <-ticker.C:
+			send()
+		case done := <-e.flushes:
+			drain()
+			send()
+			close(done)
+		case <-e.stop:
+			return
+		}
+	}
+}
+
+func (e *OTLPExporter) send(batch []SpanData) error {
+	body, err := json.Marshal(e.request(batch))
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), otlpTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("collector returned %s", resp.Status)
+	}
+	return nil
+}
+
+// OTLP/JSON request shapes. IDs are hex and 64-bit integers are strings, as
+// the OTLP JSON mapping requires.
+type (
+	otlpRequest struct {
+		ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+	}
+	otlpResourceSpans struct {
+		Resource   otlpResource     `json:"resource"`
+		ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+	}
+	otlpResource struct {
+		Attributes []otlpKeyValue `json:"attributes"`
+	}
+	otlpScopeSpans struct {
+		Scope otlpScope  `json:"scope"`
+		Spans []otlpSpan `json:"spans"`
+	}
+	otlpScope struct {
+		Name string `json:"name"`
+	}
+	otlpSpan struct {
+		TraceID           string         `json:"traceId"`
+		SpanID            string         `json:"spanId"`
+		ParentSpanID      string         `json:"parentSpanId,omitempty"`
+		Name              string         `json:"name"`
+		Kind              Kind           `json:"kind"`
+		StartTimeUnixNano string         `json:"startTimeUnixNano"`
+		EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+		Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+		Status            otlpStatus     `json:"status"`
+	}
+	otlpStatus struct {
+		Code    int    `json:"code"` // 0 unset, 2 error
+		Message string `json:"message,omitempty"`
+	}
+	otlpKeyValue struct {
+		Key   string         `json:"key"`
+		Value map[string]any `json:"value"`
+	}
+)
+
+func (e *OTLPExporter) request(batch []SpanData) otlpRequest {
+	spans := make([]otlpSpan, 0, len(batch))
+	for _, s := range batch {
+		span := otlpSpan{
+			TraceID:           s.Context.TraceID.String(),
+			SpanID:            s.Context.SpanID.String(),
+			Name:              s.Name,
+			Kind:              s.Kind,
+			StartTimeUnixNano: strconv.FormatInt(s.Start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(s.End.UnixNano(), 10),
+			Attributes:        otlpAttributes(s.Attrs),
+		}
+		if s.Parent.IsValid() {
+			span.ParentSpanID = s.Parent.String()
+		}
+		if s.Failed {
+			span.Status = otlpStatus{Code: 2, Message: s.Error}
+		}
+		spans = append(spans, span)
+	}
+	return otlpRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource:   otlpResource{Attributes: otlpAttributes([]Attr{{"service.name", e.service}})},
+		ScopeSpans: []otlpScopeSpans{{Scope: otlpScope{Name: instrumentationScope}, Spans: spans}},
+	}}}
+}
+
+func otlpAttributes(attrs []Attr) []otlpKeyValue {
+	out := make([]otlpKeyValue, 0, len(attrs))
+	for _, a := range attrs {
+		var v map[string]any
+		switch x := a.Value.(type) {
+		case string:
+			v = map[string]any{"stringValue": x}
+		case bool:
+			v = map[string]any{"boolValue": x}
+		case int:
+			v = map[string]any{"intValue": strconv.Itoa(x)}
+		case int64:
+			v = map[string]any{"intValue": strconv.FormatInt(x, 10)}
+		case float64:
+			if math.IsNaN(x) || math.IsInf(x, 0) {
+				v = map[string]any{"stringValue": strconv.FormatFloat(x, 'g', -1, 64)}
+			} else {
+				v = map[string]any{"doubleValue": x}
+			}
+		default:
+			v = map[string]any{"stringValue": fmt.Sprint(x)}
+		}
+		out = append(out, otlpKeyValue{Key: a.Key, Value: v})
+	}
+	return out
+}
@@ -0,0 +1,173 @@
+package trace
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// collector is a fake OTLP/HTTP endpoint keeping the requests it receives
+type collector struct {
+	*httptest.Server
+	mu       sync.Mutex
+	requests []map[string]any
+}
+
+func newCollector(t *testing.T) *collector {
+	c := &collector{}
+	c.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/traces", r.URL.Path)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		var body map[string]any
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		c.mu.Lock()
+		c.requests = append(c.requests, body)
+		c.mu.Unlock()
+	}))
+	t.Cleanup(c.Close)
+	return c
+}
+
+func TestOTLPExporter(t *testing.T) {
+	col := newCollector(t)
+	exp := NewOTLPExporter(col.URL+"/", "tldr-api", nil)
+	tr := NewTracer(exp, 1)
+
+	ctx, root := tr.Start(context.Background(), "POST /api/summarize", KindServer)
+	_, llm := tr.Start(ctx, "llm", KindInternal)
+	llm.SetAttr("gen_ai.request.model", "gpt-4o-mini")
+	llm.SetAttr("gen_ai.usage.input_tokens", 1200)
+	llm.SetAttr("cost", 0.25)
+	llm.SetAttr("regenerated", false)
+	llm.RecordError(errors.New("timeout"))
+	llm.End()
+	root.End()
+
+	require.NoError(t, exp.Shutdown(context.Background()))
+	require.Len(t, col.requests, 1)
+
+	// Decode the parts of the OTLP/JSON payload the collector relies on
+	raw, err := json.Marshal(col.requests[0])
+	require.NoError(t, err)
+	var req struct {
+		ResourceSpans []struct {
+			Resource struct {
+				Attributes []struct {
+					Key   string
+					Value map[string]any
+				}
+			}
+			ScopeSpans []struct {
+				Spans []struct {
+					TraceID, SpanID, ParentSpanID, Name string
+					Kind                                int
+					StartTimeUnixNano, EndTimeUnixNano  string
+					Attributes                          []struct {
+						Key   string
+						Value map[string]any
+					}
+					Status struct {
+						Code    int
+						Message string
+					}
+				}
+			}
+		}
+	}
+	require.NoError(t, json.Unmarshal(raw, &req))
+	require.Len(t, req.ResourceSpans, 1)
+	rs := req.ResourceSpans[0]
+	assert.Equal(t, "service.name", rs.Resource.Attributes[0].Key)
+	assert.Equal(t, map[string]any{"stringValue": "tldr-api"}, rs.Resource.Attributes[0].Value)
+
+	spans := rs.ScopeSpans[0].Spans
+	require.Len(t, spans, 2)
+	child, parent := spans[0], spans[1]
+	assert.Equal(t, "llm", child.Name)
+	assert.Equal(t, 1, child.Kind)
+	assert.Equal(t, 2, parent.Kind)
+	assert.Equal(t, root.Context().TraceID.String(), child.TraceID)
+	assert.Equal(t, parent.SpanID, child.ParentSpanID)
+	assert.Empty(t, parent.ParentSpanID)
+	assert.Len(t, child.TraceID, 32)
+	assert.Len(t, child.SpanID, 16)
+	assert.NotEmpty(t, child.StartTimeUnixNano)
+	assert.Equal(t, 2, child.Status.Code)
+	assert.Equal(t, "timeout", child.Status.Message)
+	assert.Zero(t, parent.Status.Code)
+
+	values := map[string]map[string]any{}
+	for _, a := range child.Attributes {
+		values[a.Key] = a.Value
+	}
+	assert.Equal(t, map[string]any{"stringValue": "gpt-4o-mini"}, values["gen_ai.request.model"])
+	assert.Equal(t, map[string]any{"intValue": "1200"}, values["gen_ai.usage.input_tokens"])
+	assert.Equal(t, map[string]any{"doubleValue": 0.25}, values["cost"])
+	assert.Equal(t, map[string]any{"boolValue": false}, values["regenerated"])
+
+	// Spans ended after shutdown go nowhere
+	_, late := tr.Start(context.Background(), "late", KindServer)
+	late.End()
+	assert.NoError(t, exp.Flush(context.Background()))
+}
+
+func TestOTLPExporter_CollectorDown(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	exp := NewOTLPExporter(ts.URL, "tldr-api", nil)
+	tr := NewTracer(exp, 1)
+	_, span := tr.Start(context.Background(), "request", KindServer)
+	span.End()
+	assert.NoError(t, exp.Shutdown(context.Background()), "export failures are logged, not returned")
+}
+
+func TestTransport(t *testing.T) {
+	page := strings.Repeat("x", 3000)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Empty(t, r.Header.Get("traceparent"), "origins are not told about our traces")
+		io.WriteString(w, page)
+	}))
+	defer ts.Close()
+
+	rec := &recorder{}
+	tr := NewTracer(rec, 1)
+	client := tr.Client(nil)
+
+	get := func(ctx context.Context) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL, nil)
+		require.NoError(t, err)
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		io.ReadAll(resp.Body)
+		resp.Body.Close()
+	}
+
+	get(context.Background())
+	assert.Empty(t, rec.spans, "requests outside a trace are not traced")
+
+	ctx, root := tr.Start(context.Background(), "request", KindServer)
+	get(ctx)
+	root.End()
+
+	require.Len(t, rec.spans, 2)
+	span := rec.spans[0]
+	assert.Equal(t, "HTTP GET", span.Name)
+	assert.Equal(t, KindClient, span.Kind)
+	assert.Equal(t, root.Context().SpanID, span.Parent)
+	assert.Contains(t, span.Attrs, Attr{"server.address", "127.0.0.1"})
+	assert.Contains(t, span.Attrs, Attr{"http.response.status_code", 200})
+	assert.Contains(t, span.Attrs, Attr{"http.response.body.size", int64(len(page))})
+}
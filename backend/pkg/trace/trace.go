@@ -0,0 +1,286 @@
+// Package trace records spans of work across the summarize pipeline and
+// exports them to an OpenTelemetry collector. It implements the small part of
+// OpenTelemetry the service needs: W3C traceparent propagation, parent-based
+// ratio sampling, and OTLP/HTTP export in JSON.
+package trace
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TraceID identifies a trace
+type TraceID [16]byte
+
+// SpanID identifies a span within a trace
+type SpanID [8]byte
+
+func (id TraceID) String() string { return hex.EncodeToString(id[:]) }
+func (id SpanID) String() string  { return hex.EncodeToString(id[:]) }
+
+// IsValid reports whether the ID is set; all-zero IDs are invalid
+func (id TraceID) IsValid() bool { return id != TraceID{} }
+
+// IsValid reports whether the ID is set; all-zero IDs are invalid
+func (id SpanID) IsValid() bool { return id != SpanID{} }
+
+// SpanContext is the part of a span that crosses process boundaries
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	Sampled bool
+}
+
+// IsValid reports whether both IDs are set
+func (c SpanContext) IsValid() bool {
+	return c.TraceID.IsValid() && c.SpanID.IsValid()
+}
+
+// Traceparent formats the context as a W3C traceparent header value
+func (c SpanContext) Traceparent() string {
+	flags := "00"
+	if c.Sampled {
+		flags = "01"
+	}
+	return "00-" + c.TraceID.String() + "-" + c.SpanID.String() + "-" + flags
+}
+
+// ErrInvalidTraceparent is returned for malformed traceparent headers
+var ErrInvalidTraceparent = errors.New("invalid traceparent")
+
+// ParseTraceparent parses a W3C traceparent header value. Versions above 00
+// are accepted as long as they start with the version 00 fields.
+func ParseTraceparent(h string) (SpanContext, error) {
+	parts := strings.Split(strings.TrimSpace(h), "-")
+	if len(parts) < 4 || parts[0] == "ff" || (parts[0] == "00" && len(parts) != 4) {
+		return SpanContext{}, ErrInvalidTraceparent
+	}
+	var c SpanContext
+	var version, flags [1]byte
+	if !decodeHex(version[:], parts[0]) || !decodeHex(c.TraceID[:], parts[1]) ||
+		!decodeHex(c.SpanID[:], parts[2]) || !decodeHex(flags[:], parts[3]) {
+		return SpanContext{}, ErrInvalidTraceparent
+	}
+	if !c.IsValid() {
+		return SpanContext{}, ErrInvalidTraceparent
+	}
+	c.Sampled = flags[0]&1 == 1
+	return c, nil
+}
+
+// decodeHex decodes lowercase hex s into dst, which it must fill exactly
+func decodeHex(dst []byte, s string) bool {
+	if len(s) != 2*len(dst) || strings.ToLower(s) != s {
+		return false
+	}
+	_, err := hex.Decode(dst, []byte(s))
+	return err == nil
+}
+
+// Kind is the role of a span, numbered as in OTLP
+type Kind int
+
+const (
+	KindInternal Kind = 1
+	KindServer   Kind = 2
+	KindClient   Kind = 3
+)
+
+// Attr is a span attribute. Values are strings, bools, ints or floats.
+type Attr struct {
+	Key   string
+	Value any
+}
+
+// SpanData is a finished span as handed to an Exporter
+type SpanData struct {
+	Name       string
+	Kind       Kind
+	Context    SpanContext
+	Parent     SpanID // zero for root spans
+	Start, End time.Time
+	Attrs      []Attr
+	Error      string // set when the span failed
+	Failed     bool
+}
+
+// Exporter receives finished, sampled spans. Export must not block.
+type Exporter interface {
+	Export(SpanData)
+}
+
+// Span is an operation being traced. A nil *Span is valid and records
+// nothing, so callers needn't check whether tracing is on.
+type Span struct {
+	tracer *Tracer
+
+	mu    sync.Mutex
+	data  SpanData
+	ended bool
+}
+
+// Context returns the span's IDs, for propagation and logs
+func (s *Span) Context() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.data.Context
+}
+
+// SetName renames the span, e.g. once the route that served it is known
+func (s *Span) SetName(name string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Name = name
+}
+
+// SetAttr sets an attribute, replacing any with the same key
+func (s *Span) SetAttr(key string, value any) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.data.Attrs {
+		if s.data.Attrs[i].Key == key {
+			s.data.Attrs[i].Value = value
+			return
+		}
+	}
+	s.data.Attrs = append(s.data.Attrs, Attr{key, value})
+}
+
+// RecordError marks the span failed; nil errors are ignored
+func (s *Span) RecordError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Failed, s.data.Error = true, err.Error()
+}
+
+// End finishes the span and exports it if sampled. Later calls do nothing.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.End = s.tracer.now()
+	data := s.data
+	data.Attrs = append([]Attr(nil), s.data.Attrs...)
+	s.mu.Unlock()
+
+	if data.Context.Sampled && s.tracer.exporter != nil {
+		s.tracer.exporter.Export(data)
+	}
+}
+
+// Tracer starts spans and hands the sampled ones to its exporter
+type Tracer struct {
+	exporter Exporter
+	ratio    float64 // share of new traces sampled
+
+	now func() time.Time
+}
+
+// NewTracer creates a tracer exporting to exp. Traces started here are
+// sampled at ratio (0 to 1); traces continued from a caller keep the
+// caller's decision. A nil exporter samples nothing.
+func NewTracer(exp Exporter, ratio float64) *Tracer {
+	return &Tracer{exporter: exp, ratio: ratio, now: time.Now}
+}
+
+// Default is the tracer the server reports to; it exports nothing until
+// replaced in main
+var Default = NewTracer(nil, 0)
+
+type spanKey struct{}
+type remoteKey struct{}
+
+// ContextWithRemoteParent returns ctx carrying a parent received from
+// another process, for the next span started from it
+func ContextWithRemoteParent(ctx context.Context, parent SpanContext) context.Context {
+	return context.WithValue(ctx, remoteKey{}, parent)
+}
+
+// SpanFromContext returns the span ctx carries, or nil
+func SpanFromContext(ctx context.Context) *Span {
+	s, _ := ctx.Value(spanKey{}).(*Span)
+	return s
+}
+
+// Start begins a span as a child of the one ctx carries, or of its remote
+// parent, and returns ctx carrying the new span. End the span when done.
+func (t *Tracer) Start(ctx context.Context, name string, kind Kind) (context.Context, *Span) {
+	var parent SpanContext
+	if s := SpanFromContext(ctx); s != nil {
+		parent = s.Context()
+	} else if remote, ok := ctx.Value(remoteKey{}).(SpanContext); ok {
+		parent = remote
+	}
+
+	sc := SpanContext{SpanID: newSpanID()}
+	if parent.IsValid() {
+		sc.TraceID, sc.Sampled = parent.TraceID, parent.Sampled
+	} else {
+		sc.TraceID = newTraceID()
+		sc.Sampled = t.exporter != nil && sampleTrace(sc.TraceID, t.ratio)
+	}
+
+	s := &Span{tracer: t, data: SpanData{
+		Name:    name,
+		Kind:    kind,
+		Context: sc,
+		Parent:  parent.SpanID,
+		Start:   t.now(),
+	}}
+	return context.WithValue(ctx, spanKey{}, s), s
+}
+
+// Start begins a span on the Default tracer
+func Start(ctx context.Context, name string, kind Kind) (context.Context, *Span) {
+	return Default.Start(ctx, name, kind)
+}
+
+// sampleTrace decides from the trace ID's random low bytes, so every
+// service sampling at the same ratio keeps the same traces
+func sampleTrace(id TraceID, ratio float64) bool {
+	if ratio >= 1 {
+		return true
+	}
+	if ratio <= 0 {
+		return false
+	}
+	return float64(binary.BigEndian.Uint64(id[8:])>>1) < ratio*float64(uint64(1)<<63)
+}
+
+func newTraceID() TraceID {
+	var id TraceID
+	for !id.IsValid() {
+		rand.Read(id[:])
+	}
+	return id
+}
+
+func newSpanID() SpanID {
+	var id SpanID
+	for !id.IsValid() {
+		rand.Read(id[:])
+	}
+	return id
+}
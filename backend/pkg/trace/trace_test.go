@@ -0,0 +1,123 @@
+package trace
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recorder collects exported spans
+type recorder struct {
+	mu    sync.Mutex
+	spans []SpanData
+}
+
+func (r *recorder) Export(s SpanData) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.spans = append(r.spans, s)
+}
+
+func TestParseTraceparent(t *testing.T) {
+	const h = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	sc, err := ParseTraceparent(h)
+	require.NoError(t, err)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", sc.TraceID.String())
+	assert.Equal(t, "00f067aa0ba902b7", sc.SpanID.String())
+	assert.True(t, sc.Sampled)
+	assert.Equal(t, h, sc.Traceparent())
+
+	sc, err = ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+	require.NoError(t, err)
+	assert.False(t, sc.Sampled)
+
+	_, err = ParseTraceparent("01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra")
+	assert.NoError(t, err, "later versions may append fields")
+
+	for _, bad := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e47-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-zz",
+	} {
+		_, err := ParseTraceparent(bad)
+		assert.ErrorIs(t, err, ErrInvalidTraceparent, bad)
+	}
+}
+
+func TestTracer_Start(t *testing.T) {
+	rec := &recorder{}
+	tr := NewTracer(rec, 1)
+
+	ctx, root := tr.Start(context.Background(), "request", KindServer)
+	_, child := tr.Start(ctx, "fetch", KindClient)
+	child.SetAttr("server.address", "example.com")
+	child.SetAttr("server.address", "example.org")
+	child.RecordError(errors.New("connection reset"))
+	child.End()
+	child.End()
+	root.End()
+
+	require.Len(t, rec.spans, 2, "spans are exported once, when they end")
+	c, r := rec.spans[0], rec.spans[1]
+	assert.Equal(t, r.Context.TraceID, c.Context.TraceID)
+	assert.Equal(t, r.Context.SpanID, c.Parent)
+	assert.False(t, r.Parent.IsValid())
+	assert.Equal(t, []Attr{{"server.address", "example.org"}}, c.Attrs)
+	assert.True(t, c.Failed)
+	assert.Equal(t, "connection reset", c.Error)
+	assert.False(t, c.End.Before(c.Start))
+	assert.Equal(t, KindClient, c.Kind)
+	assert.Equal(t, root, SpanFromContext(ctx))
+}
+
+func TestTracer_RemoteParent(t *testing.T) {
+	rec := &recorder{}
+	tr := NewTracer(rec, 0)
+
+	parent, err := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	require.NoError(t, err)
+	_, span := tr.Start(ContextWithRemoteParent(context.Background(), parent), "request", KindServer)
+	span.End()
+
+	require.Len(t, rec.spans, 1, "the caller's sampling decision wins over the ratio")
+	assert.Equal(t, parent.TraceID, rec.spans[0].Context.TraceID)
+	assert.Equal(t, parent.SpanID, rec.spans[0].Parent)
+
+	parent.Sampled = false
+	_, span = tr.Start(ContextWithRemoteParent(context.Background(), parent), "request", KindServer)
+	span.End()
+	assert.Len(t, rec.spans, 1)
+}
+
+func TestTracer_Sampling(t *testing.T) {
+	rec := &recorder{}
+	tr := NewTracer(rec, 0.25)
+	for i := 0; i < 4000; i++ {
+		_, span := tr.Start(context.Background(), "request", KindServer)
+		span.End()
+	}
+	assert.InDelta(t, 1000, len(rec.spans), 200)
+
+	_, span := NewTracer(nil, 1).Start(context.Background(), "request", KindServer)
+	assert.False(t, span.Context().Sampled, "nothing is sampled without an exporter")
+	span.End()
+}
+
+func TestSpan_Nil(t *testing.T) {
+	var s *Span
+	s.SetName("x")
+	s.SetAttr("k", 1)
+	s.RecordError(errors.New("boom"))
+	s.End()
+	assert.False(t, s.Context().IsValid())
+}
@@ -2,37 +2,41 @@
 package validate
 
 import (
-	"crypto/tls"
+	"context"
 	"fmt"
 	"log"
 	"net/http"
 	"net/url"
-	"os"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/matthewmolinar/tldr/pkg/config"
 )
 
-const maxContentLengthBytes = 10 * 1024 * 1024 // 10 MB
+// Validator checks URLs against the configured fetch limits
+type Validator struct {
+	client           *http.Client
+	maxContentLength int64
+}
 
-// ValidateURL checks if the given URL is valid according to service requirements:
-// - Must use HTTPS scheme
-// - Content must not exceed 20 KB (checked via HEAD request)
-func ValidateURL(s string, client *http.Client) error {
+// New creates a validator with the limits of cfg. client makes the HEAD
+// requests; nil uses http.DefaultClient.
+func New(cfg config.Fetch, client *http.Client) *Validator {
 	if client == nil {
-		// Create a custom client with TLS certificate verification disabled
-		// only in production environments to handle containerized deployments
-		_, inProduction := os.LookupEnv("FLY_APP_NAME")
-		if inProduction {
-			log.Printf("Running in production environment, disabling TLS verification")
-			client = &http.Client{
-				Transport: &http.Transport{
-					TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-				},
-			}
-		} else {
-			client = http.DefaultClient
-		}
+		client = http.DefaultClient
 	}
+	return &Validator{client: client, maxContentLength: cfg.MaxContentLength}
+}
+
+// ValidateURL is Validator.ValidateURL with the default limits
+func ValidateURL(s string, client *http.Client) error {
+	return New(config.Default().Fetch, client).ValidateURL(context.Background(), s)
+}
+
+// ValidateURL checks if the given URL is valid according to service requirements:
+// - Must use HTTPS scheme
+// - Content must not exceed the maximum content length (checked via HEAD request)
+// ctx bounds the HEAD request.
+func (v *Validator) ValidateURL(ctx context.Context, s string) error {
 	// Parse and normalize URL
 	log.Printf("Validating URL: %q", s)
 	u, err := url.Parse(s)
@@ -55,7 +59,7 @@ func ValidateURL(s string, client *http.Client) error {
 
 	// Check content size via HEAD request
 	log.Printf("Making HEAD request to: %s", u.String())
-	req, err := http.NewRequest(http.MethodHead, u.String(), nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, u.String(), nil)
 	if err != nil {
 		log.Printf("Failed to create request: %v", err)
 		return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("failed to create request: %v", err))
@@ -65,7 +69,7 @@ func ValidateURL(s string, client *http.Client) error {
 	req.Header.Set("User-Agent", "Mozilla/5.0 TL;DR-App/1.0")
 	
 	log.Printf("Sending HEAD request")
-	resp, err := client.Do(req)
+	resp, err := v.client.Do(req)
 	if err != nil {
 		log.Printf("Failed to fetch URL: %v", err)
 		return fiber.NewError(fiber.StatusUnprocessableEntity, fmt.Sprintf("failed to fetch URL: %v", err))
@@ -76,11 +80,11 @@ func ValidateURL(s string, client *http.Client) error {
 	
 	// Check content length if provided
 	contentLength := resp.ContentLength
-	log.Printf("Content length: %d bytes (max %d bytes)", contentLength, maxContentLengthBytes)
+	log.Printf("Content length: %d bytes (max %d bytes)", contentLength, v.maxContentLength)
 	
-	if contentLength > maxContentLengthBytes {
+	if contentLength > v.maxContentLength {
 		return fiber.NewError(fiber.StatusUnprocessableEntity, fmt.Sprintf("content too large: %d bytes (max %d bytes)", 
-			contentLength, maxContentLengthBytes),
+			contentLength, v.maxContentLength),
 		)
 	}
 
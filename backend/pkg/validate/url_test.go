@@ -7,6 +7,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/matthewmolinar/tldr/pkg/config"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -56,7 +57,7 @@ func TestValidateURL(t *testing.T) {
 		{
 			name:         "content too large",
 			url:          "https://example.com",
-			responseSize: maxContentLengthBytes + 1,
+			responseSize: config.Default().Fetch.MaxContentLength + 1,
 			wantErr:      true,
 			errContains:  "content too large",
 		},
@@ -0,0 +1,168 @@
+// Package verify checks generated bullets against the article they summarize
+// to flag names, numbers and claims the LLM may have invented
+package verify
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/matthewmolinar/tldr/pkg/nlp"
+)
+
+// SupportThreshold is the score below which a bullet counts as unsupported
+const SupportThreshold = 0.5
+
+// BulletSupport describes how well one bullet is backed by the article
+type BulletSupport struct {
+	Score     float64  `json:"score"`             // 0 (invented) to 1 (fully supported)
+	Source    string   `json:"source,omitempty"`  // best-matching article sentence
+	Missing   []string `json:"missing,omitempty"` // names and numbers not found in the article
+	Supported bool     `json:"supported"`
+}
+
+// Checker scores bullets against one article. Build it once per article and
+// reuse it for every bullet and regeneration attempt.
+type Checker struct {
+	sentences   []string
+	sentenceSet []map[string]bool
+	tokens      map[string]bool
+	numbers     map[string]bool
+}
+
+// NewChecker indexes the article text
+func NewChecker(source string) *Checker {
+	c := &Checker{
+		sentences: nlp.Sentences(source),
+		tokens:    make(map[string]bool),
+		numbers:   make(map[string]bool),
+	}
+	for _, t := range nlp.Tokens(source) {
+		c.tokens[t] = true
+	}
+	for _, n := range nlp.Numbers(source) {
+		c.numbers[n] = true
+	}
+	for _, s := range c.sentences {
+		set := make(map[string]bool)
+		for _, t := range nlp.ContentTokens(s) {
+			set[t] = true
+		}
+		c.sentenceSet = append(c.sentenceSet, set)
+	}
+	return c
+}
+
+// Check scores each bullet. The score blends how many of the bullet's content
+// words appear anywhere in the article with how many appear in its single
+// best-matching sentence, then is scaled down by the share of names and
+// numbers that the article never mentions.
+func (c *Checker) Check(bullets []string) []BulletSupport {
+	out := make([]BulletSupport, len(bullets))
+	for i, b := range bullets {
+		out[i] = c.checkBullet(b)
+	}
+	return out
+}
+
+// Check is a convenience wrapper for a single use of NewChecker
+func Check(source string, bullets []string) []BulletSupport {
+	return NewChecker(source).Check(bullets)
+}
+
+func (c *Checker) checkBullet(bullet string) BulletSupport {
+	tokens := nlp.ContentTokens(bullet)
+	if len(tokens) == 0 {
+		return BulletSupport{Score: 1, Supported: true}
+	}
+
+	inDoc := 0
+	for _, t := range tokens {
+		if c.tokens[t] {
+			inDoc++
+		}
+	}
+
+	best, bestHits := -1, 0
+	for i, set := range c.sentenceSet {
+		hits := 0
+		for _, t := range tokens {
+			if set[t] {
+				hits++
+			}
+		}
+		if hits > bestHits {
+			best, bestHits = i, hits
+		}
+	}
+
+	docCoverage := float64(inDoc) / float64(len(tokens))
+	sentenceCoverage := float64(bestHits) / float64(len(tokens))
+	score := 0.6*docCoverage + 0.4*sentenceCoverage
+
+	facts := 0
+	var missing []string
+	for _, n := range nlp.Numbers(bullet) {
+		facts++
+		if !c.numbers[n] {
+			missing = append(missing, n)
+		}
+	}
+	for _, name := range names(bullet) {
+		facts++
+		if !c.tokens[strings.ToLower(name)] {
+			missing = append(missing, name)
+		}
+	}
+	if facts > 0 {
+		score *= 1 - float64(len(missing))/float64(facts)
+	}
+
+	res := BulletSupport{Score: score, Missing: missing, Supported: score >= SupportThreshold}
+	if best >= 0 {
+		res.Source = c.sentences[best]
+	}
+	return res
+}
+
+// names returns capitalized words that don't start the bullet, a cheap
+// stand-in for named entities
+func names(bullet string) []string {
+	var out []string
+	words := strings.Fields(bullet)
+	for i, w := range words {
+		w = strings.TrimFunc(w, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) })
+		if i == 0 || w == "" {
+			continue
+		}
+		first := []rune(w)[0]
+		if unicode.IsUpper(first) && !nlp.IsStopword(strings.ToLower(w)) {
+			// Possessives and hyphenated names are checked by their first part
+			w = strings.SplitN(w, "'", 2)[0]
+			w = strings.SplitN(w, "-", 2)[0]
+			out = append(out, w)
+		}
+	}
+	return out
+}
+
+// AllSupported reports whether every bullet met the support threshold
+func AllSupported(checks []BulletSupport) bool {
+	for _, c := range checks {
+		if !c.Supported {
+			return false
+		}
+	}
+	return true
+}
+
+// MeanScore averages the support scores, treating no bullets as fully supported
+func MeanScore(checks []BulletSupport) float64 {
+	if len(checks) == 0 {
+		return 1
+	}
+	sum := 0.0
+	for _, c := range checks {
+		sum += c.Score
+	}
+	return sum / float64(len(checks))
+}
@@ -0,0 +1,73 @@
+package verify
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const harborArticle = `The Port of Westhaven board voted 6 to 1 on Tuesday to approve a $1.2 billion expansion of its container terminal, the largest investment in the harbor's 140-year history.
+The project will deepen the main shipping channel to 16 meters so that the newest generation of container ships can dock fully loaded.
+Port director Amara Okafor said the expansion would double annual capacity to 4 million containers by 2031 and create about 2,300 permanent jobs.
+Environmental groups criticized the decision, warning that dredging could disturb seagrass beds that shelter juvenile fish.`
+
+func TestCheck(t *testing.T) {
+	source := harborArticle
+
+	tests := []struct {
+		name          string
+		bullet        string
+		wantSupported bool
+		wantMissing   []string
+		wantSource    string
+	}{
+		{
+			name:          "paraphrase of one sentence",
+			bullet:        "Capacity will double to 4 million containers by 2031",
+			wantSupported: true,
+			wantSource:    "Port director Amara Okafor said the expansion would double annual capacity",
+		},
+		{
+			name:          "invented number",
+			bullet:        "Capacity will double to 9 million containers by 2031",
+			wantSupported: false,
+			wantMissing:   []string{"9"},
+		},
+		{
+			name:          "invented person",
+			bullet:        "Mayor Jenkins celebrated the harbor vote",
+			wantSupported: false,
+			wantMissing:   []string{"Jenkins"},
+		},
+		{
+			name:          "claim absent from the article",
+			bullet:        "Tourism revenue expected to soar after cruise terminal opens",
+			wantSupported: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checks := Check(source, []string{tt.bullet})
+			require.Len(t, checks, 1)
+			got := checks[0]
+
+			assert.Equal(t, tt.wantSupported, got.Supported, "score %.2f", got.Score)
+			for _, m := range tt.wantMissing {
+				assert.Contains(t, got.Missing, m)
+			}
+			if tt.wantSource != "" {
+				assert.Contains(t, got.Source, tt.wantSource)
+			}
+		})
+	}
+}
+
+func TestAggregates(t *testing.T) {
+	checks := []BulletSupport{{Score: 1, Supported: true}, {Score: 0.2}}
+	assert.False(t, AllSupported(checks))
+	assert.InDelta(t, 0.6, MeanScore(checks), 1e-9)
+	assert.True(t, AllSupported(nil))
+	assert.Equal(t, 1.0, MeanScore(nil))
+}